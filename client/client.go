@@ -0,0 +1,344 @@
+// Package fwork_client calls GoQLite resources over HTTP: the queries are
+// sent in the format of QueryPayloadFromRequest and the list / problem
+// envelopes are decoded back into the core types.
+package fwork_client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"iter"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	fwork_server_orm "github.com/joabssilveira/GoQLite/core"
+)
+
+// Client calls the handlers of one resource: list and create on BaseURL,
+// get / update / delete on BaseURL/{id}.
+//
+//	orders := NewClient[Order]("https://api.example.com/orders")
+//	page, err := orders.List(ctx, fwork_server_orm.QueryPayload{Where: filter})
+type Client[T any] struct {
+	BaseURL string
+
+	// HTTPClient sends the requests. Nil uses http.DefaultClient.
+	HTTPClient *http.Client
+
+	// Header is added to every request (authorization, tenant...).
+	Header http.Header
+}
+
+func NewClient[T any](baseURL string) *Client[T] {
+	return &Client[T]{BaseURL: strings.TrimRight(baseURL, "/"), Header: http.Header{}}
+}
+
+// Error is a non-2xx response. Problem is set when the body is
+// application/problem+json; Body keeps the raw body otherwise.
+type Error struct {
+	StatusCode int
+	Problem    *fwork_server_orm.ProblemDetails
+	Body       []byte
+}
+
+func (e *Error) Error() string {
+	if e.Problem != nil {
+		if e.Problem.Detail != "" {
+			return fmt.Sprintf("%d %s: %s", e.StatusCode, e.Problem.Title, e.Problem.Detail)
+		}
+		return fmt.Sprintf("%d %s", e.StatusCode, e.Problem.Title)
+	}
+
+	body := strings.TrimSpace(string(e.Body))
+	if body == "" {
+		return fmt.Sprintf("%d %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("%d %s", e.StatusCode, body)
+}
+
+// Kind is the core error kind of the status code (ErrorInternal for
+// unknown codes).
+func (e *Error) Kind() fwork_server_orm.ErrorKind {
+	switch e.StatusCode {
+	case http.StatusBadRequest:
+		return fwork_server_orm.ErrorBadQuery
+	case http.StatusNotFound:
+		return fwork_server_orm.ErrorNotFound
+	case http.StatusConflict:
+		return fwork_server_orm.ErrorConflict
+	case http.StatusUnprocessableEntity:
+		return fwork_server_orm.ErrorValidation
+	case http.StatusForbidden:
+		return fwork_server_orm.ErrorForbidden
+	case http.StatusGatewayTimeout:
+		return fwork_server_orm.ErrorTimeout
+	case http.StatusServiceUnavailable:
+		return fwork_server_orm.ErrorUnavailable
+	default:
+		return fwork_server_orm.ErrorInternal
+	}
+}
+
+// EncodeQuery builds the query params of a payload, as parsed by
+// QueryPayloadFromRequest: where / sort / select as JSON, nested as is.
+func EncodeQuery(payload fwork_server_orm.QueryPayload) (url.Values, error) {
+	values := url.Values{}
+
+	if !payload.Where.IsEmpty() {
+		raw, err := json.Marshal(payload.Where)
+		if err != nil {
+			return nil, fmt.Errorf("invalid where: %w", err)
+		}
+		values.Set("where", string(raw))
+	}
+
+	if len(payload.Select) > 0 {
+		raw, err := json.Marshal(payload.Select)
+		if err != nil {
+			return nil, err
+		}
+		values.Set("select", string(raw))
+	}
+
+	if len(payload.Order) > 0 {
+		raw, err := json.Marshal(payload.Order)
+		if err != nil {
+			return nil, err
+		}
+		values.Set("sort", string(raw))
+	}
+
+	if payload.Limit != nil {
+		values.Set("limit", strconv.Itoa(*payload.Limit))
+	}
+	if payload.Offset != nil {
+		values.Set("skip", strconv.Itoa(*payload.Offset))
+	}
+	if payload.Page != nil {
+		values.Set("page", strconv.Itoa(*payload.Page))
+	}
+
+	if payload.Nested != "" {
+		values.Set("nested", payload.Nested)
+	}
+
+	if payload.WithDeleted {
+		values.Set("withDeleted", "true")
+	}
+	if payload.OnlyDeleted {
+		values.Set("onlyDeleted", "true")
+	}
+
+	if payload.Count != "" {
+		values.Set("count", string(payload.Count))
+	}
+
+	return values, nil
+}
+
+func (c *Client[T]) List(ctx context.Context, payload fwork_server_orm.QueryPayload) (fwork_server_orm.GetListData[T], error) {
+	var result fwork_server_orm.GetListData[T]
+
+	query, err := EncodeQuery(payload)
+	if err != nil {
+		return result, err
+	}
+
+	err = c.do(ctx, http.MethodGet, c.BaseURL, query, nil, &result)
+	return result, err
+}
+
+// Get loads one record. The optional payload sends select / nested / where.
+func (c *Client[T]) Get(ctx context.Context, id any, payload ...fwork_server_orm.QueryPayload) (*T, error) {
+	var query url.Values
+	if len(payload) > 0 {
+		var err error
+		if query, err = EncodeQuery(payload[0]); err != nil {
+			return nil, err
+		}
+	}
+
+	var item T
+	if err := c.do(ctx, http.MethodGet, c.itemURL(id), query, nil, &item); err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+func (c *Client[T]) Create(ctx context.Context, item T) (*T, error) {
+	var created T
+	if err := c.do(ctx, http.MethodPost, c.BaseURL, nil, item, &created); err != nil {
+		return nil, err
+	}
+	return &created, nil
+}
+
+func (c *Client[T]) Update(ctx context.Context, id any, item T) (*T, error) {
+	var updated T
+	if err := c.do(ctx, http.MethodPut, c.itemURL(id), nil, item, &updated); err != nil {
+		return nil, err
+	}
+	return &updated, nil
+}
+
+func (c *Client[T]) Delete(ctx context.Context, id any) error {
+	return c.do(ctx, http.MethodDelete, c.itemURL(id), nil, nil, nil)
+}
+
+// DefaultPageSize is the limit of Pages / All when the payload has none.
+var DefaultPageSize = 100
+
+// Pages walks the list page by page, starting at the skip / page of the
+// payload (limit 100 when it has none). It stops at hasNextPage false
+// (count=hasMore), at the exact count, or at a short page; an error ends the
+// iteration.
+//
+//	for page, err := range orders.Pages(ctx, payload) { ... }
+func (c *Client[T]) Pages(ctx context.Context, payload fwork_server_orm.QueryPayload) iter.Seq2[fwork_server_orm.GetListData[T], error] {
+	return func(yield func(fwork_server_orm.GetListData[T], error) bool) {
+		if payload.Limit == nil {
+			limit := DefaultPageSize
+			payload.Limit = &limit
+		}
+		fwork_server_orm.ApplyPagination(&payload)
+		payload.Page = nil
+
+		skip := 0
+		if payload.Offset != nil {
+			skip = *payload.Offset
+		}
+
+		for {
+			payload.Offset = &skip
+
+			page, err := c.List(ctx, payload)
+			if err != nil {
+				yield(page, err)
+				return
+			}
+			if !yield(page, nil) {
+				return
+			}
+
+			skip += len(page.Payload)
+			if !hasNext(page, skip, *payload.Limit) {
+				return
+			}
+		}
+	}
+}
+
+// All is Pages item by item.
+func (c *Client[T]) All(ctx context.Context, payload fwork_server_orm.QueryPayload) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for page, err := range c.Pages(ctx, payload) {
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+			for _, item := range page.Payload {
+				if !yield(item, nil) {
+					return
+				}
+			}
+		}
+	}
+}
+
+func hasNext[T any](page fwork_server_orm.GetListData[T], skip, limit int) bool {
+	if len(page.Payload) == 0 {
+		return false
+	}
+
+	if meta := page.Pagination; meta != nil {
+		if meta.HasNextPage != nil {
+			return *meta.HasNextPage
+		}
+		// estimativa não serve para parar
+		exact := meta.CountMode == "" || meta.CountMode == fwork_server_orm.CountExact
+		if exact && meta.Count != nil {
+			return skip < *meta.Count
+		}
+	}
+
+	return len(page.Payload) >= limit
+}
+
+func (c *Client[T]) itemURL(id any) string {
+	return c.BaseURL + "/" + url.PathEscape(fmt.Sprint(id))
+}
+
+func (c *Client[T]) do(ctx context.Context, method, target string, query url.Values, body any, out any) error {
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(raw)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return err
+	}
+
+	for name, values := range c.Header {
+		for _, v := range values {
+			req.Header.Add(name, v)
+		}
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return responseError(resp)
+	}
+
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil && err != io.EOF {
+		return fmt.Errorf("invalid response: %w", err)
+	}
+	return nil
+}
+
+func responseError(resp *http.Response) error {
+	raw, _ := io.ReadAll(resp.Body)
+	e := &Error{StatusCode: resp.StatusCode, Body: raw}
+
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType == "application/problem+json" {
+		var problem fwork_server_orm.ProblemDetails
+		if json.Unmarshal(raw, &problem) == nil {
+			e.Problem = &problem
+		}
+	}
+
+	return e
+}
@@ -0,0 +1,203 @@
+package fwork_client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"testing"
+
+	fwork_server_orm "github.com/joabssilveira/GoQLite/core"
+	fwork_server_gorm "github.com/joabssilveira/GoQLite/gorm"
+)
+
+type testItem struct {
+	ID int `json:"id"`
+}
+
+func TestEncodeQueryRoundTrips(t *testing.T) {
+	limit, skip, page := 20, 40, 3
+
+	var where fwork_server_orm.Filter
+	if err := json.Unmarshal([]byte(`{"total": {"$gte": 100}, "$or": [{"status": "paid"}, {"customer.name": {"$ilike": "a%"}}]}`), &where); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, payload := range map[string]fwork_server_orm.QueryPayload{
+		"empty": {},
+		"full": {
+			Where:       where,
+			Order:       []fwork_server_orm.Order{{Field: "createdAt", Dir: "desc"}, {Field: "id"}},
+			Select:      []string{"id", "total"},
+			Nested:      `{items{}}`,
+			Limit:       &limit,
+			Offset:      &skip,
+			Page:        &page,
+			WithDeleted: true,
+			OnlyDeleted: true,
+			Count:       fwork_server_orm.CountHasMore,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			values, err := EncodeQuery(payload)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			r := httptest.NewRequest(http.MethodGet, "/orders?"+values.Encode(), nil)
+			got, err := fwork_server_gorm.QueryPayloadFromRequest(r)
+			if err != nil {
+				t.Fatalf("QueryPayloadFromRequest: %v", err)
+			}
+
+			// Filter guarda mapas: compara pelo JSON enviado
+			gotJSON, _ := json.Marshal(got)
+			wantJSON, _ := json.Marshal(payload)
+			if string(gotJSON) != string(wantJSON) {
+				t.Errorf("payload = %s\nwant      %s", gotJSON, wantJSON)
+			}
+		})
+	}
+}
+
+func TestProblemResponseDecodesIntoError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/orders/1":
+			err := (&fwork_server_orm.ValidationError{}).Add("total", "min", "must be at least 0")
+			fwork_server_orm.DefaultErrorResponder.RespondError(w, r, err)
+		default:
+			http.Error(w, "gateway down", http.StatusBadGateway)
+		}
+	}))
+	defer server.Close()
+
+	orders := NewClient[testItem](server.URL + "/orders")
+
+	_, err := orders.Update(context.Background(), 1, testItem{ID: 1})
+	var apiErr *Error
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Update err = %v, want an *Error", err)
+	}
+	if apiErr.StatusCode != http.StatusUnprocessableEntity || apiErr.Kind() != fwork_server_orm.ErrorValidation {
+		t.Errorf("error = %d %s, want 422 %s", apiErr.StatusCode, apiErr.Kind(), fwork_server_orm.ErrorValidation)
+	}
+	if apiErr.Problem == nil || apiErr.Problem.Status != http.StatusUnprocessableEntity ||
+		len(apiErr.Problem.Errors) != 1 || apiErr.Problem.Errors[0].Field != "total" {
+		t.Errorf("problem = %+v, want the field error on total", apiErr.Problem)
+	}
+
+	// corpo que não é problem+json fica em Body
+	_, err = orders.Get(context.Background(), 2)
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("Get err = %v, want an *Error", err)
+	}
+	if apiErr.Problem != nil || apiErr.Kind() != fwork_server_orm.ErrorInternal || apiErr.Error() != "502 gateway down" {
+		t.Errorf("error = %q (problem %+v), want the raw body", apiErr.Error(), apiErr.Problem)
+	}
+}
+
+// pageServer serves total items, answering each list with the pagination
+// built by meta, and records the skip of every request.
+func pageServer(t *testing.T, total int, meta func(skip, limit, n int) *fwork_server_orm.PaginationMeta) (*Client[testItem], *[]int) {
+	var skips []int
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		skip, _ := strconv.Atoi(r.URL.Query().Get("skip"))
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		skips = append(skips, skip)
+
+		var items []testItem
+		for id := skip + 1; id <= total && id <= skip+limit; id++ {
+			items = append(items, testItem{ID: id})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(fwork_server_orm.GetListData[testItem]{
+			Payload:    items,
+			Pagination: meta(skip, limit, len(items)),
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	return NewClient[testItem](server.URL), &skips
+}
+
+func TestPagesStops(t *testing.T) {
+	ptr := func(v int) *int { return &v }
+
+	for name, tc := range map[string]struct {
+		total int
+		meta  func(skip, limit, n int) *fwork_server_orm.PaginationMeta
+		skips []int
+		ids   int
+	}{
+		"hasNextPage": {
+			// a página cheia não basta: o servidor diz que acabou
+			6,
+			func(skip, limit, n int) *fwork_server_orm.PaginationMeta {
+				next := skip+n < 4
+				return &fwork_server_orm.PaginationMeta{CountMode: fwork_server_orm.CountHasMore, HasNextPage: &next}
+			},
+			[]int{0, 2}, 4,
+		},
+		"exact count": {
+			4,
+			func(skip, limit, n int) *fwork_server_orm.PaginationMeta {
+				return &fwork_server_orm.PaginationMeta{Count: ptr(4), CountMode: fwork_server_orm.CountExact}
+			},
+			[]int{0, 2}, 4,
+		},
+		"estimated count": {
+			// estimativa baixa não encerra a iteração
+			5,
+			func(skip, limit, n int) *fwork_server_orm.PaginationMeta {
+				return &fwork_server_orm.PaginationMeta{Count: ptr(2), CountMode: fwork_server_orm.CountEstimated}
+			},
+			[]int{0, 2, 4}, 5,
+		},
+		"short page": {
+			5,
+			func(skip, limit, n int) *fwork_server_orm.PaginationMeta { return nil },
+			[]int{0, 2, 4}, 5,
+		},
+		"empty page": {
+			4,
+			func(skip, limit, n int) *fwork_server_orm.PaginationMeta { return nil },
+			[]int{0, 2, 4}, 4,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			orders, skips := pageServer(t, tc.total, tc.meta)
+
+			var ids []int
+			for item, err := range orders.All(context.Background(), fwork_server_orm.QueryPayload{Limit: ptr(2)}) {
+				if err != nil {
+					t.Fatal(err)
+				}
+				ids = append(ids, item.ID)
+			}
+
+			if !reflect.DeepEqual(*skips, tc.skips) {
+				t.Errorf("skips = %v, want %v", *skips, tc.skips)
+			}
+			if len(ids) != tc.ids || (len(ids) > 0 && ids[len(ids)-1] != tc.ids) {
+				t.Errorf("ids = %v, want 1..%d", ids, tc.ids)
+			}
+		})
+	}
+
+	// page vira skip (page 2 de 1 item começa no 1)
+	orders, skips := pageServer(t, 3, func(skip, limit, n int) *fwork_server_orm.PaginationMeta { return nil })
+	for _, err := range orders.Pages(context.Background(), fwork_server_orm.QueryPayload{Page: ptr(2), Limit: ptr(1)}) {
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if !reflect.DeepEqual(*skips, []int{1, 2, 3}) {
+		t.Errorf("skips from page 2 = %v, want [1 2 3]", *skips)
+	}
+}
@@ -0,0 +1,268 @@
+// Command goqlite-fields generates the Field[T] constants of model structs,
+// for refactor-safe FilterBuilder[T] filters:
+//
+//	//go:generate go run github.com/joabssilveira/GoQLite/cmd/goqlite-fields -type Order,Customer
+//
+// Each field of the struct gets a constant named after the struct and the
+// field, with the JSON name as value (OrderTotal = "total"). Relations, up to
+// -depth levels, and the sub-paths of JSONB fields holding a struct of the
+// package go in a <Struct>Paths value: OrderPaths.Customer.Name is
+// "customer.name", OrderPaths.Meta.Source is "meta.source" (the document
+// itself is OrderMeta, or Self inside a relation). The direct to-one relations
+// (belongs-to / has-one) are Rel[T, R] constants (OrderCustomer = "customer")
+// for Rel.Field and Some.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"go/format"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/joabssilveira/GoQLite/internal/modelparse"
+)
+
+const corePackage = "fwork_server_orm"
+
+func main() {
+	var (
+		typeNames = flag.String("type", "", "comma separated struct names (default: every exported struct)")
+		output    = flag.String("output", "fields_gen.go", "output file, relative to -dir")
+		dir       = flag.String("dir", ".", "package directory")
+		depth     = flag.Int("depth", 2, "relation levels to follow")
+	)
+	flag.Parse()
+
+	if err := run(*dir, *output, *typeNames, *depth); err != nil {
+		fmt.Fprintln(os.Stderr, "goqlite-fields:", err)
+		os.Exit(1)
+	}
+}
+
+func run(dir, output, typeNames string, depth int) error {
+	pkg, err := modelparse.Load(dir, filepath.Base(output))
+	if err != nil {
+		return err
+	}
+
+	var only []string
+	if typeNames != "" {
+		only = strings.Split(typeNames, ",")
+	}
+
+	names, err := pkg.Names(only)
+	if err != nil {
+		return err
+	}
+
+	src, err := generate(pkg, names, depth)
+	if err != nil {
+		return err
+	}
+
+	return os.WriteFile(filepath.Join(dir, output), src, 0o644)
+}
+
+// node is a field (Path set) or a group of paths (relation / document).
+type node struct {
+	Name     string
+	Path     string
+	Children []*node
+}
+
+func (n *node) add(child *node) {
+	if child.Path != "" || len(child.Children) > 0 {
+		n.Children = append(n.Children, child)
+	}
+}
+
+func generate(pkg *modelparse.Package, names []string, depth int) ([]byte, error) {
+	// o próprio core não se importa
+	qualifier := corePackage + "."
+	if pkg.Name == corePackage {
+		qualifier = ""
+	}
+	fieldType := qualifier + "Field"
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by goqlite-fields. DO NOT EDIT.\n\npackage %s\n\n", pkg.Name)
+	if pkg.Name != corePackage {
+		fmt.Fprintf(&buf, "import %s \"github.com/joabssilveira/GoQLite/core\"\n\n", corePackage)
+	}
+
+	for _, name := range names {
+		fieldOf := fieldType + "[" + name + "]"
+		root := collect(pkg, pkg.Structs[name], "", depth, map[string]bool{name: true})
+
+		var fields, paths []*node
+		for _, child := range root.Children {
+			if child.Path != "" {
+				fields = append(fields, child)
+			}
+			if len(child.Children) > 0 {
+				paths = append(paths, child)
+			}
+		}
+
+		if len(fields) > 0 {
+			fmt.Fprintf(&buf, "// %s fields.\nconst (\n", name)
+			for _, f := range fields {
+				fmt.Fprintf(&buf, "\t%s%s %s = %q\n", name, f.Name, fieldOf, f.Path)
+			}
+			buf.WriteString(")\n\n")
+		}
+
+		var relations []*modelparse.Field
+		for _, f := range pkg.Structs[name].Fields {
+			// has-many / many2many: o join repetiria as linhas do modelo
+			if f.Kind == modelparse.Relation && !f.Many {
+				relations = append(relations, f)
+			}
+		}
+
+		if len(relations) > 0 {
+			fmt.Fprintf(&buf, "// %s relations.\nconst (\n", name)
+			for _, f := range relations {
+				fmt.Fprintf(&buf, "\t%s%s %sRel[%s, %s] = %q\n", name, f.GoName, qualifier, name, f.Target, modelparse.RelationPath(f.GoName))
+			}
+			buf.WriteString(")\n\n")
+		}
+
+		if len(paths) > 0 {
+			group := &node{Children: paths}
+			if err := checkNames(group, name+"Paths"); err != nil {
+				return nil, err
+			}
+
+			fmt.Fprintf(&buf, "// %sPaths has the relation and JSON document paths of %s.\n", name, name)
+			fmt.Fprintf(&buf, "var %sPaths = %s\n\n", name, valueExpr(group, fieldOf))
+		}
+	}
+
+	return format.Source(buf.Bytes())
+}
+
+// collect builds the paths of s. Relations are followed while depth > 0 and
+// not back into a struct of the current path; documents hold their own path
+// in Self.
+func collect(pkg *modelparse.Package, s *modelparse.Struct, path string, depth int, visiting map[string]bool) *node {
+	group := &node{}
+
+	for _, f := range s.Fields {
+		if f.JSONName == "" {
+			continue
+		}
+
+		switch f.Kind {
+		case modelparse.Scalar:
+			if f.Column {
+				group.add(&node{Name: f.GoName, Path: path + f.JSONName})
+			}
+
+		case modelparse.JSON:
+			if !f.Column {
+				continue
+			}
+
+			// caminhos dentro do documento (sem índices de array)
+			field := &node{Name: f.GoName, Path: path + f.JSONName}
+			if f.Target != "" && !f.Many {
+				doc := document(pkg, pkg.Structs[f.Target], field.Path+".", map[string]bool{f.Target: true})
+				field.Children = doc.Children
+			}
+			group.add(field)
+
+		case modelparse.Relation:
+			if depth <= 0 || visiting[f.Target] {
+				continue
+			}
+
+			visiting[f.Target] = true
+			rel := collect(pkg, pkg.Structs[f.Target], path+modelparse.RelationPath(f.GoName)+".", depth-1, visiting)
+			delete(visiting, f.Target)
+
+			rel.Name = f.GoName
+			group.add(rel)
+		}
+
+		// Object (gorm:"embedded"): colunas com prefixo, fora dos filtros
+	}
+
+	return group
+}
+
+// document builds the paths of the fields of a JSON document.
+func document(pkg *modelparse.Package, s *modelparse.Struct, path string, visiting map[string]bool) *node {
+	group := &node{}
+
+	for _, f := range s.Fields {
+		if f.JSONName == "" {
+			continue
+		}
+
+		field := &node{Name: f.GoName, Path: path + f.JSONName}
+		if f.Target != "" && !f.Many && !visiting[f.Target] {
+			visiting[f.Target] = true
+			field.Children = document(pkg, pkg.Structs[f.Target], field.Path+".", visiting).Children
+			delete(visiting, f.Target)
+		}
+		group.add(field)
+	}
+
+	return group
+}
+
+// checkNames refuses groups where Self (the path of a document) clashes with
+// a field.
+func checkNames(n *node, where string) error {
+	for _, child := range n.Children {
+		if len(child.Children) > 0 && child.Path != "" {
+			for _, c := range child.Children {
+				if c.Name == "Self" {
+					return fmt.Errorf("%s.%s: field Self clashes with the document path", where, child.Name)
+				}
+			}
+		}
+		if err := checkNames(child, where+"."+child.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func typeExpr(n *node, fieldOf string) string {
+	if len(n.Children) == 0 {
+		return fieldOf
+	}
+
+	var b strings.Builder
+	b.WriteString("struct {\n")
+	if n.Path != "" {
+		fmt.Fprintf(&b, "Self %s\n", fieldOf)
+	}
+	for _, child := range n.Children {
+		fmt.Fprintf(&b, "%s %s\n", child.Name, typeExpr(child, fieldOf))
+	}
+	b.WriteString("}")
+	return b.String()
+}
+
+func valueExpr(n *node, fieldOf string) string {
+	if len(n.Children) == 0 {
+		return fmt.Sprintf("%q", n.Path)
+	}
+
+	var b strings.Builder
+	b.WriteString(typeExpr(n, fieldOf) + "{\n")
+	if n.Path != "" {
+		fmt.Fprintf(&b, "Self: %q,\n", n.Path)
+	}
+	for _, child := range n.Children {
+		fmt.Fprintf(&b, "%s: %s,\n", child.Name, valueExpr(child, fieldOf))
+	}
+	b.WriteString("}")
+	return b.String()
+}
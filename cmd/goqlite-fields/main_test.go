@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/joabssilveira/GoQLite/internal/modelparse"
+)
+
+const fixtureDir = "../testdata/models"
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+func generateFixture(t *testing.T, depth int) string {
+	t.Helper()
+
+	pkg, err := modelparse.Load(fixtureDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names, err := pkg.Names(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src, err := generate(pkg, names, depth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(src)
+}
+
+func TestRelOnlyForToOneRelations(t *testing.T) {
+	src := generateFixture(t, 2)
+
+	if !regexp.MustCompile(`OrderCustomer\s+fwork_server_orm\.Rel\[Order, Customer\] = "customer"`).MatchString(src) {
+		t.Errorf("no Rel for the belongs-to Order.Customer:\n%s", src)
+	}
+
+	// has-many / many2many: Some / Rel.Field would repeat the rows of the model
+	for _, rel := range []string{`Rel\[Order, Item\]`, `Rel\[Order, Tag\]`, `Rel\[Customer, Order\]`} {
+		if regexp.MustCompile(rel).MatchString(src) {
+			t.Errorf("Rel generated for a to-many relation (%s):\n%s", rel, src)
+		}
+	}
+}
+
+// TestGenerateGolden compares the output for the fixture models with
+// testdata/fields_gen.golden (go test -update rewrites it).
+func TestGenerateGolden(t *testing.T) {
+	got := []byte(generateFixture(t, 2))
+	golden := filepath.Join("testdata", "fields_gen.golden")
+
+	if *update {
+		if err := os.MkdirAll("testdata", 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(golden, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("%v (run go test -update to create it)", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("output differs from %s (go test -update rewrites it):\n%s", golden, got)
+	}
+}
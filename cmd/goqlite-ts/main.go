@@ -0,0 +1,492 @@
+// Command goqlite-ts generates the TypeScript of model structs for frontends:
+//
+//	//go:generate go run github.com/joabssilveira/GoQLite/cmd/goqlite-ts -type Order,Customer -output ../web/src/api.ts
+//
+// The output has an interface per struct (JSON names and types), the typed
+// filter / sort / select of each model (<Model>Filter, <Model>SortField,
+// <Model>SelectField, <Model>Query) mirroring the core Filter, FieldExpr and
+// Order, the GetListData / PaginationMeta envelopes and a fetch client that
+// sends the queries as the list handler reads them.
+package main
+
+import (
+	"bytes"
+	_ "embed"
+	"flag"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/types"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/joabssilveira/GoQLite/internal/modelparse"
+)
+
+//go:embed runtime.ts
+var runtime string
+
+func main() {
+	var (
+		typeNames = flag.String("type", "", "comma separated struct names (default: every exported struct)")
+		output    = flag.String("output", "goqlite.ts", "output file, relative to -dir")
+		dir       = flag.String("dir", ".", "package directory")
+		depth     = flag.Int("depth", 2, "relation levels to follow in filters")
+	)
+	flag.Parse()
+
+	if err := run(*dir, *output, *typeNames, *depth); err != nil {
+		fmt.Fprintln(os.Stderr, "goqlite-ts:", err)
+		os.Exit(1)
+	}
+}
+
+func run(dir, output, typeNames string, depth int) error {
+	pkg, err := modelparse.Load(dir)
+	if err != nil {
+		return err
+	}
+
+	var only []string
+	if typeNames != "" {
+		only = strings.Split(typeNames, ",")
+	}
+
+	names, err := pkg.Names(only)
+	if err != nil {
+		return err
+	}
+
+	path := filepath.Join(dir, output)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(path, generate(pkg, names, depth), 0o644)
+}
+
+type generator struct {
+	pkg *modelparse.Package
+
+	// structs / named types of the package used by the emitted types
+	structs map[string]bool
+	aliases map[string]bool
+}
+
+func generate(pkg *modelparse.Package, names []string, depth int) []byte {
+	g := &generator{pkg: pkg, structs: map[string]bool{}, aliases: map[string]bool{}}
+
+	var buf bytes.Buffer
+	buf.WriteString("// Code generated by goqlite-ts. DO NOT EDIT.\n\n")
+	buf.WriteString(runtime)
+
+	// filtros primeiro: marcam os tipos usados
+	var queries bytes.Buffer
+	for _, name := range names {
+		g.writeQueries(&queries, pkg.Structs[name], depth)
+	}
+
+	buf.WriteString("\n// Models.\n\n")
+
+	var order []string
+	for _, s := range pkg.SortedStructs(names) {
+		order = append(order, s.Name)
+	}
+
+	written := map[string]bool{}
+	for len(order) > 0 {
+		for _, name := range order {
+			if !written[name] {
+				written[name] = true
+				g.writeInterface(&buf, pkg.Structs[name])
+			}
+		}
+
+		// structs só alcançados por mapas, aliases...
+		order = nil
+		for name := range g.structs {
+			if !written[name] {
+				order = append(order, name)
+			}
+		}
+		sort.Strings(order)
+	}
+
+	for _, name := range g.aliasNames() {
+		expr, err := parser.ParseExpr(pkg.Types[name])
+		ts := "unknown"
+		if err == nil {
+			ts = g.tsType(expr)
+		}
+		fmt.Fprintf(&buf, "export type %s = %s;\n\n", name, ts)
+	}
+
+	buf.Write(queries.Bytes())
+
+	return append(bytes.TrimRight(buf.Bytes(), "\n"), '\n')
+}
+
+// aliasNames returns the named types used, including the ones used by
+// other aliases.
+func (g *generator) aliasNames() []string {
+	done := map[string]bool{}
+	for {
+		pending := false
+		for name := range g.aliases {
+			if done[name] {
+				continue
+			}
+			done[name] = true
+			pending = true
+			if expr, err := parser.ParseExpr(g.pkg.Types[name]); err == nil {
+				g.tsType(expr)
+			}
+		}
+		if !pending {
+			break
+		}
+	}
+
+	names := make([]string, 0, len(done))
+	for name := range done {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (g *generator) writeInterface(buf *bytes.Buffer, s *modelparse.Struct) {
+	writeDoc(buf, s.Doc)
+	fmt.Fprintf(buf, "export interface %s {\n", s.Name)
+
+	for _, f := range s.Fields {
+		if f.JSONName == "" {
+			continue
+		}
+
+		// relações por ponteiro / slice só vêm com nested
+		optional := ""
+		if f.OmitEmpty || f.Kind == modelparse.Relation && (f.Pointer || f.Many) {
+			optional = "?"
+		}
+
+		ts := "unknown"
+		if expr, err := parser.ParseExpr(f.Type); err == nil {
+			ts = g.tsType(expr)
+		}
+		fmt.Fprintf(buf, "  %s%s: %s;\n", propName(f.JSONName), optional, ts)
+	}
+
+	buf.WriteString("}\n\n")
+}
+
+func (g *generator) writeQueries(buf *bytes.Buffer, s *modelparse.Struct, depth int) {
+	name := s.Name
+	fmt.Fprintf(buf, "// %s queries.\n\n", name)
+
+	fmt.Fprintf(buf, "export interface %sFilter {\n", name)
+	for _, f := range g.filterFields(s, "", depth, map[string]bool{name: true}) {
+		if f.pattern {
+			fmt.Fprintf(buf, "  [path: `%s${string}`]: %s | %s | undefined;\n", f.path, f.expr, f.value)
+			continue
+		}
+		fmt.Fprintf(buf, "  %s?: %s | %s;\n", propName(f.path), f.expr, f.value)
+	}
+	fmt.Fprintf(buf, "  $and?: %[1]sFilter[];\n  $or?: %[1]sFilter[];\n  $not?: %[1]sFilter;\n}\n\n", name)
+
+	var sortable, selectable []string
+	for _, f := range s.Fields {
+		if f.JSONName == "" || !f.Column {
+			continue
+		}
+		switch f.Kind {
+		case modelparse.Scalar:
+			selectable = append(selectable, fmt.Sprintf("%q", f.JSONName))
+			if f.Elem != "[]byte" {
+				sortable = append(sortable, fmt.Sprintf("%q", f.JSONName))
+			}
+		case modelparse.JSON:
+			selectable = append(selectable, fmt.Sprintf("%q", f.JSONName))
+		}
+	}
+
+	fmt.Fprintf(buf, "export type %sSortField = %s;\n", name, union(sortable))
+	fmt.Fprintf(buf, "export type %sSelectField = %s;\n", name, union(selectable))
+	fmt.Fprintf(buf, "export type %[1]sQuery = Query<%[1]sFilter, %[1]sSortField, %[1]sSelectField>;\n\n", name)
+
+	fmt.Fprintf(buf, "export type %[1]sClient = ResourceClient<%[1]s, %[1]sQuery>;\n\n", name)
+	fmt.Fprintf(buf, "export function new%[1]sClient(baseURL: string, options?: ClientOptions): %[1]sClient {\n", name)
+	fmt.Fprintf(buf, "  return new ResourceClient(baseURL, options);\n}\n\n")
+
+	g.structs[name] = true
+}
+
+// filterField is a path of the filter with its operators (expr) and plain
+// value ($eq) types. Pattern fields match every sub-path of a document.
+type filterField struct {
+	path    string
+	expr    string
+	value   string
+	pattern bool
+}
+
+// filterFields follows the paths of goqlite-fields: own columns, relations up
+// to depth, and the sub-paths of JSONB documents.
+func (g *generator) filterFields(s *modelparse.Struct, path string, depth int, visiting map[string]bool) []filterField {
+	var fields []filterField
+
+	for _, f := range s.Fields {
+		if f.JSONName == "" {
+			continue
+		}
+
+		switch f.Kind {
+		case modelparse.Scalar:
+			if f.Column {
+				expr, value := g.fieldExpr(f)
+				fields = append(fields, filterField{path: path + f.JSONName, expr: expr, value: value})
+			}
+
+		case modelparse.JSON:
+			if !f.Column {
+				continue
+			}
+			fields = append(fields, filterField{path: path + f.JSONName, expr: "JSONExpr", value: "JSONValue"})
+			fields = append(fields, g.documentFields(f, path+f.JSONName+".", map[string]bool{})...)
+
+		case modelparse.Relation:
+			if depth <= 0 || visiting[f.Target] {
+				continue
+			}
+
+			visiting[f.Target] = true
+			fields = append(fields, g.filterFields(g.pkg.Structs[f.Target], path+modelparse.RelationPath(f.GoName)+".", depth-1, visiting)...)
+			delete(visiting, f.Target)
+		}
+	}
+
+	return fields
+}
+
+// documentFields are the sub-paths of a JSON document: the fields of a struct
+// of the package, or a pattern for maps, arrays and unknown types.
+func (g *generator) documentFields(f *modelparse.Field, path string, visiting map[string]bool) []filterField {
+	if f.Target == "" || f.Many || visiting[f.Target] {
+		return []filterField{{path: path, expr: "JSONExpr", value: "JSONValue", pattern: true}}
+	}
+
+	visiting[f.Target] = true
+	defer delete(visiting, f.Target)
+
+	var fields []filterField
+	for _, sub := range g.pkg.Structs[f.Target].Fields {
+		if sub.JSONName == "" {
+			continue
+		}
+
+		if sub.Target != "" || sub.Many || sub.Kind == modelparse.JSON {
+			fields = append(fields, filterField{path: path + sub.JSONName, expr: "JSONExpr", value: "JSONValue"})
+			fields = append(fields, g.documentFields(sub, path+sub.JSONName+".", visiting)...)
+			continue
+		}
+
+		expr, value := g.fieldExpr(sub)
+		fields = append(fields, filterField{path: path + sub.JSONName, expr: expr, value: value})
+	}
+	return fields
+}
+
+// fieldExpr returns the operators of a scalar field (as core OperatorsFor)
+// and its value type.
+func (g *generator) fieldExpr(f *modelparse.Field) (string, string) {
+	if f.Many {
+		return "FieldExpr", "JSONValue"
+	}
+
+	expr, err := parser.ParseExpr(f.Elem)
+	if err != nil {
+		return "FieldExpr", "JSONValue"
+	}
+	if ix, ok := expr.(*ast.IndexExpr); ok && types.ExprString(ix.X) == "sql.Null" {
+		inner := *f
+		inner.Elem = types.ExprString(ix.Index)
+		return g.fieldExpr(&inner)
+	}
+	value := g.tsType(expr)
+
+	switch g.underlying(f.Elem) {
+	case "string":
+		return "StringExpr<" + value + ">", value
+	case "bool":
+		return "EqualityExpr<" + value + ">", value
+	case "time.Time", "gorm.DeletedAt":
+		return "OrderedExpr<string>", "string"
+	case "[]byte":
+		return "EqualityExpr<string>", "string"
+	}
+
+	// sql.Null*: o filtro compara a coluna, então recebe o valor e não o
+	// struct { String, Valid } do JSON do modelo
+	if null, ok := sqlNullTypes[g.underlying(f.Elem)]; ok {
+		return null.expr, null.ts
+	}
+
+	if isNumber(g.underlying(f.Elem)) {
+		return "OrderedExpr<" + value + ">", value
+	}
+	return "FieldExpr", "JSONValue"
+}
+
+// underlying resolves the named types of the package ("Status" -> "string").
+func (g *generator) underlying(typ string) string {
+	seen := map[string]bool{}
+	for {
+		base, ok := g.pkg.Types[typ]
+		if !ok || seen[typ] {
+			return typ
+		}
+		seen[typ] = true
+		typ = base
+	}
+}
+
+// sqlNullTypes are the database/sql null types: the field holding the value,
+// its TypeScript and the filter expression of the column.
+var sqlNullTypes = map[string]struct{ field, ts, expr string }{
+	"sql.NullString":  {"String", "string", "StringExpr<string>"},
+	"sql.NullTime":    {"Time", "string", "OrderedExpr<string>"},
+	"sql.NullInt64":   {"Int64", "number", "OrderedExpr<number>"},
+	"sql.NullInt32":   {"Int32", "number", "OrderedExpr<number>"},
+	"sql.NullInt16":   {"Int16", "number", "OrderedExpr<number>"},
+	"sql.NullByte":    {"Byte", "number", "OrderedExpr<number>"},
+	"sql.NullFloat64": {"Float64", "number", "OrderedExpr<number>"},
+	"sql.NullBool":    {"Bool", "boolean", "EqualityExpr<boolean>"},
+}
+
+var basicTypes = map[string]string{
+	"string": "string",
+	"bool":   "boolean",
+	"any":    "unknown",
+	"error":  "unknown",
+}
+
+func isNumber(typ string) bool {
+	switch typ {
+	case "int", "int8", "int16", "int32", "int64",
+		"uint", "uint8", "uint16", "uint32", "uint64", "uintptr",
+		"float32", "float64", "byte", "rune":
+		return true
+	}
+	return false
+}
+
+// tsType is the TypeScript of a Go type, as encoding/json writes it.
+func (g *generator) tsType(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.StarExpr:
+		return nullable(g.tsType(e.X))
+
+	case *ast.ArrayType:
+		if types.ExprString(e.Elt) == "byte" {
+			return "string" // base64
+		}
+		return arrayOf(g.tsType(e.Elt))
+
+	case *ast.MapType:
+		return "Record<string, " + g.tsType(e.Value) + ">"
+
+	case *ast.IndexExpr:
+		if typeName(e.X) == "JSONB" {
+			return g.tsType(e.Index)
+		}
+		if types.ExprString(e.X) == "sql.Null" {
+			return "{ V: " + g.tsType(e.Index) + "; Valid: boolean }"
+		}
+		return "unknown"
+
+	case *ast.SelectorExpr:
+		name := types.ExprString(e)
+		switch name {
+		case "time.Time":
+			return "string"
+		case "gorm.DeletedAt":
+			return "string | null" // MarshalJSON: null quando inválido
+		}
+		// sql.Null* não têm MarshalJSON: vão como struct
+		if null, ok := sqlNullTypes[name]; ok {
+			return "{ " + null.field + ": " + null.ts + "; Valid: boolean }"
+		}
+		return "unknown"
+
+	case *ast.Ident:
+		if ts, ok := basicTypes[e.Name]; ok {
+			return ts
+		}
+		if isNumber(e.Name) {
+			return "number"
+		}
+		if _, ok := g.pkg.Structs[e.Name]; ok {
+			g.structs[e.Name] = true
+			return e.Name
+		}
+		if _, ok := g.pkg.Types[e.Name]; ok {
+			g.aliases[e.Name] = true
+			return e.Name
+		}
+	}
+
+	// interface{}, structs anônimas, tipos de outros pacotes
+	return "unknown"
+}
+
+func typeName(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return e.Name
+	case *ast.SelectorExpr:
+		return e.Sel.Name
+	}
+	return ""
+}
+
+func nullable(ts string) string {
+	if ts == "unknown" || strings.HasSuffix(ts, "| null") {
+		return ts
+	}
+	return ts + " | null"
+}
+
+func arrayOf(ts string) string {
+	if strings.Contains(ts, " ") {
+		return "(" + ts + ")[]"
+	}
+	return ts + "[]"
+}
+
+func union(values []string) string {
+	if len(values) == 0 {
+		return "never"
+	}
+	return strings.Join(values, " | ")
+}
+
+var identifier = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$]*$`)
+
+func propName(name string) string {
+	if identifier.MatchString(name) {
+		return name
+	}
+	return fmt.Sprintf("%q", name)
+}
+
+func writeDoc(buf *bytes.Buffer, doc string) {
+	if doc == "" {
+		return
+	}
+	for _, line := range strings.Split(doc, "\n") {
+		fmt.Fprintf(buf, "// %s\n", line)
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/joabssilveira/GoQLite/internal/modelparse"
+)
+
+const fixtureDir = "../testdata/models"
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// generateFixture returns the output for the fixture models without the
+// runtime prefix, which is copied as is.
+func generateFixture(t *testing.T, depth int) string {
+	t.Helper()
+
+	pkg, err := modelparse.Load(fixtureDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	names, err := pkg.Names(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src, ok := strings.CutPrefix(string(generate(pkg, names, depth)), "// Code generated by goqlite-ts. DO NOT EDIT.\n\n"+runtime)
+	if !ok {
+		t.Fatal("output does not start with the header and the runtime")
+	}
+	return src
+}
+
+// TestGenerateGolden compares the models, filters and clients generated for
+// the fixture with testdata/goqlite.ts.golden (go test -update rewrites it).
+func TestGenerateGolden(t *testing.T) {
+	got := []byte(generateFixture(t, 2))
+	golden := filepath.Join("testdata", "goqlite.ts.golden")
+
+	if *update {
+		if err := os.MkdirAll("testdata", 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(golden, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("%v (run go test -update to create it)", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("output differs from %s (go test -update rewrites it):\n%s", golden, got)
+	}
+}
+
+func TestSQLNullTypes(t *testing.T) {
+	src := generateFixture(t, 2)
+
+	// o JSON é o struct de database/sql; o filtro recebe o valor
+	for _, want := range []string{
+		"note: { String: string; Valid: boolean };",
+		"quantity: { Int64: number; Valid: boolean };",
+		"gift: { Bool: boolean; Valid: boolean };",
+		"shippedAt: { Time: string; Valid: boolean };",
+		"discount: { V: number; Valid: boolean };",
+		"weight: { Float64: number; Valid: boolean } | null;",
+		"note?: StringExpr<string> | string;",
+		"quantity?: OrderedExpr<number> | number;",
+		"gift?: EqualityExpr<boolean> | boolean;",
+		"shippedAt?: OrderedExpr<string> | string;",
+		"discount?: OrderedExpr<number> | number;",
+		"weight?: OrderedExpr<number> | number;",
+	} {
+		if !strings.Contains(src, want) {
+			t.Errorf("output has no %q", want)
+		}
+	}
+}
@@ -0,0 +1,258 @@
+// Query DSL (core Filter / FieldExpr / Order).
+
+export type CountMode = "exact" | "none" | "hasMore" | "estimated";
+
+export type JSONValue = string | number | boolean | null;
+
+export interface EqualityExpr<V> {
+  $eq?: V;
+  $ne?: V;
+  $in?: V[];
+  $nin?: V[];
+  $null?: boolean;
+  $exists?: boolean;
+}
+
+export interface OrderedExpr<V> extends EqualityExpr<V> {
+  $gt?: V;
+  $gte?: V;
+  $lt?: V;
+  $lte?: V;
+  $between?: [V, V];
+}
+
+export interface StringExpr<V extends string = string> extends OrderedExpr<V> {
+  $like?: string;
+  $ilike?: string;
+}
+
+export interface FieldExprOp {
+  op: string;
+  value?: unknown;
+}
+
+// FieldExpr has every operator; JSON documents also accept $op.
+export interface FieldExpr<V = unknown> extends OrderedExpr<V> {
+  $like?: string;
+  $ilike?: string;
+  $op?: FieldExprOp;
+}
+
+export type JSONExpr = FieldExpr<JSONValue>;
+
+// Filter is the untyped filter: field paths to operators or plain values
+// ($eq).
+export interface Filter {
+  $and?: Filter[];
+  $or?: Filter[];
+  $not?: Filter;
+  [field: string]: FieldExpr | JSONValue | Filter | Filter[] | undefined;
+}
+
+export interface Sort<F extends string = string> {
+  field: F;
+  dir?: "asc" | "desc";
+}
+
+export interface Query<W = Filter, S extends string = string, L extends string = S> {
+  where?: W;
+  sort?: Sort<S>[];
+  select?: L[];
+  nested?: string;
+  limit?: number;
+  skip?: number;
+  page?: number;
+  withDeleted?: boolean;
+  onlyDeleted?: boolean;
+  count?: CountMode;
+}
+
+// Responses.
+
+export interface PaginationMeta {
+  skip?: number;
+  limit?: number;
+  count?: number;
+  pageCount?: number;
+  currentPage?: number;
+  countMode?: CountMode;
+  hasNextPage?: boolean;
+}
+
+export interface GetListData<T> {
+  payload?: T[];
+  pagination?: PaginationMeta;
+}
+
+export interface FieldError {
+  field?: string;
+  code: string;
+  message: string;
+}
+
+export interface ProblemDetails {
+  type: string;
+  title: string;
+  status: number;
+  detail?: string;
+  instance?: string;
+  errors?: FieldError[];
+}
+
+// Client.
+
+// encodeQuery builds the query params read by the list / get handlers:
+// where, sort and select as JSON, nested as is.
+export function encodeQuery(query: Query<object, string, string> = {}): URLSearchParams {
+  const params = new URLSearchParams();
+
+  if (query.where && Object.keys(query.where).length > 0) params.set("where", JSON.stringify(query.where));
+  if (query.select && query.select.length > 0) params.set("select", JSON.stringify(query.select));
+  if (query.sort && query.sort.length > 0) params.set("sort", JSON.stringify(query.sort));
+  if (query.limit != null) params.set("limit", String(query.limit));
+  if (query.skip != null) params.set("skip", String(query.skip));
+  if (query.page != null) params.set("page", String(query.page));
+  if (query.nested) params.set("nested", query.nested);
+  if (query.withDeleted) params.set("withDeleted", "true");
+  if (query.onlyDeleted) params.set("onlyDeleted", "true");
+  if (query.count) params.set("count", query.count);
+
+  return params;
+}
+
+// GoQLiteError is a non-2xx response; problem is set for
+// application/problem+json bodies.
+export class GoQLiteError extends Error {
+  constructor(
+    readonly status: number,
+    readonly problem?: ProblemDetails,
+    readonly body?: string,
+  ) {
+    super(
+      problem
+        ? `${status} ${problem.title}${problem.detail ? ": " + problem.detail : ""}`
+        : `${status} ${body ?? ""}`.trim(),
+    );
+    this.name = "GoQLiteError";
+  }
+}
+
+export interface ClientOptions {
+  fetch?: typeof fetch;
+  // headers of every request (authorization, tenant...)
+  headers?: HeadersInit | (() => HeadersInit | Promise<HeadersInit>);
+  // limit of pages() / all() when the query has none (default 100)
+  pageSize?: number;
+}
+
+// ResourceClient calls the handlers of one resource: list and create on
+// baseURL, get / update / delete on baseURL/{id}.
+export class ResourceClient<T, Q extends Query<object, string, string> = Query> {
+  private readonly baseURL: string;
+
+  constructor(
+    baseURL: string,
+    private readonly options: ClientOptions = {},
+  ) {
+    this.baseURL = baseURL.replace(/\/+$/, "");
+  }
+
+  list(query?: Q): Promise<GetListData<T>> {
+    return this.request("GET", this.baseURL, encodeQuery(query));
+  }
+
+  // get loads one record; the query sends select / nested / where.
+  get(id: string | number, query?: Q): Promise<T> {
+    return this.request("GET", this.itemURL(id), encodeQuery(query));
+  }
+
+  create(item: Partial<T>): Promise<T> {
+    return this.request("POST", this.baseURL, undefined, item);
+  }
+
+  update(id: string | number, item: T): Promise<T> {
+    return this.request("PUT", this.itemURL(id), undefined, item);
+  }
+
+  delete(id: string | number): Promise<void> {
+    return this.request("DELETE", this.itemURL(id));
+  }
+
+  // pages walks the list from the skip / page of the query, stopping at
+  // hasNextPage false (count=hasMore), at the exact count or at a short page.
+  async *pages(query?: Q): AsyncGenerator<GetListData<T>> {
+    const limit = query?.limit ?? this.options.pageSize ?? 100;
+    const first = query?.page;
+    let skip = query?.skip ?? (first != null ? (Math.max(first, 1) - 1) * limit : 0);
+
+    for (;;) {
+      const page = await this.list({ ...(query ?? {}), limit, skip, page: undefined } as Q);
+      yield page;
+
+      const count = page.payload?.length ?? 0;
+      skip += count;
+      if (!hasNext(page, count, skip, limit)) return;
+    }
+  }
+
+  // all is pages() item by item.
+  async *all(query?: Q): AsyncGenerator<T> {
+    for await (const page of this.pages(query)) {
+      yield* page.payload ?? [];
+    }
+  }
+
+  private itemURL(id: string | number): string {
+    return `${this.baseURL}/${encodeURIComponent(String(id))}`;
+  }
+
+  private async request<R>(method: string, url: string, params?: URLSearchParams, body?: unknown): Promise<R> {
+    const query = params?.toString();
+    if (query) url += "?" + query;
+
+    const headers = new Headers(
+      typeof this.options.headers === "function" ? await this.options.headers() : this.options.headers,
+    );
+    headers.set("Accept", "application/json");
+    if (body !== undefined) headers.set("Content-Type", "application/json");
+
+    const doFetch = this.options.fetch ?? fetch;
+    const resp = await doFetch(url, {
+      method,
+      headers,
+      body: body !== undefined ? JSON.stringify(body) : undefined,
+    });
+
+    if (!resp.ok) {
+      const text = await resp.text();
+      const type = resp.headers.get("Content-Type") ?? "";
+      let problem: ProblemDetails | undefined;
+      if (type.startsWith("application/problem+json")) {
+        try {
+          problem = JSON.parse(text);
+        } catch {
+          // corpo inválido: fica só o texto
+        }
+      }
+      throw new GoQLiteError(resp.status, problem, text);
+    }
+
+    if (resp.status === 204) return undefined as R;
+    const text = await resp.text();
+    return (text ? JSON.parse(text) : undefined) as R;
+  }
+}
+
+function hasNext(page: GetListData<unknown>, count: number, skip: number, limit: number): boolean {
+  if (count === 0) return false;
+
+  const meta = page.pagination;
+  if (meta) {
+    if (meta.hasNextPage != null) return meta.hasNextPage;
+    // estimativa não serve para parar
+    const exact = !meta.countMode || meta.countMode === "exact";
+    if (exact && meta.count != null) return skip < meta.count;
+  }
+
+  return count >= limit;
+}
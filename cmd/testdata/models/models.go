@@ -0,0 +1,35 @@
+// Package models is the fixture of the goqlite-fields / goqlite-ts tests.
+package models
+
+import "database/sql"
+
+type Customer struct {
+	ID     uint    `json:"id"`
+	Name   string  `json:"name"`
+	Orders []Order `json:"orders"` // has-many
+}
+
+type Order struct {
+	ID         uint     `json:"id"`
+	CustomerID uint     `json:"customerId"`
+	Customer   Customer `json:"customer"` // belongs-to
+	Items      []Item   `json:"items"`    // has-many
+	Tags       []*Tag   `json:"tags" gorm:"many2many:order_tags"`
+}
+
+type Item struct {
+	ID        uint              `json:"id"`
+	OrderID   uint              `json:"orderId"`
+	Sku       string            `json:"sku"`
+	Note      sql.NullString    `json:"note"`
+	Quantity  sql.NullInt64     `json:"quantity"`
+	Gift      sql.NullBool      `json:"gift"`
+	ShippedAt sql.NullTime      `json:"shippedAt"`
+	Discount  sql.Null[float64] `json:"discount"`
+	Weight    *sql.NullFloat64  `json:"weight"`
+}
+
+type Tag struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name"`
+}
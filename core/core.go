@@ -14,6 +14,30 @@ func isEmptyFilter(f Filter) bool {
 		len(f.Fields) == 0
 }
 
+// IsEmpty reports whether the filter has no condition at all, also looking
+// into $and / $or / $not and ignoring fields without operators.
+func (f Filter) IsEmpty() bool {
+	for _, expr := range f.Fields {
+		if !expr.isEmpty() {
+			return false
+		}
+	}
+
+	for _, sub := range f.And {
+		if !sub.IsEmpty() {
+			return false
+		}
+	}
+
+	for _, sub := range f.Or {
+		if !sub.IsEmpty() {
+			return false
+		}
+	}
+
+	return f.Not == nil || f.Not.IsEmpty()
+}
+
 func (f FieldExpr) isEmpty() bool {
 	return f.Eq == nil &&
 		f.Ne == nil &&
@@ -170,11 +194,40 @@ func ApplyPagination(payload *QueryPayload) {
 
 func ExtractCountPayload(payload QueryPayload) QueryPayload {
 	return QueryPayload{
-		Where: payload.Where,
+		Where:       payload.Where,
+		WithDeleted: payload.WithDeleted,
+		OnlyDeleted: payload.OnlyDeleted,
 		// tudo o resto vazio de propósito
 	}
 }
 
+// RequestsDeleted reports whether the payload, or any nested query, asks for
+// soft-deleted rows.
+func (payload QueryPayload) RequestsDeleted() bool {
+	if payload.WithDeleted || payload.OnlyDeleted {
+		return true
+	}
+
+	if payload.Nested == "" {
+		return false
+	}
+
+	var walk func(nodes []*NestedNode) bool
+	walk = func(nodes []*NestedNode) bool {
+		for _, node := range nodes {
+			if node.Query != nil && node.Query.RequestsDeleted() {
+				return true
+			}
+			if walk(node.Childs) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return walk(ParseNestedTree(payload.Nested))
+}
+
 func BuildPaginationMeta(payload QueryPayload, total int64) *PaginationMeta {
 	if payload.Limit == nil && payload.Offset == nil && payload.Page == nil {
 		return nil
@@ -214,6 +267,46 @@ func BuildPaginationMeta(payload QueryPayload, total int64) *PaginationMeta {
 	return meta
 }
 
+// BuildPaginationMetaFromCount is BuildPaginationMeta for any CountMode:
+// none / hasMore leave Count and PageCount empty, and CountMode tells the
+// client how Count was produced.
+func BuildPaginationMetaFromCount(payload QueryPayload, count ListCount) *PaginationMeta {
+	switch count.Mode {
+	case "", CountExact, CountEstimated:
+		meta := BuildPaginationMeta(payload, count.Total)
+		if meta != nil {
+			meta.CountMode = count.Mode
+		}
+		return meta
+	}
+
+	if payload.Limit == nil && payload.Offset == nil && payload.Page == nil {
+		return nil
+	}
+
+	meta := &PaginationMeta{
+		Skip:        payload.Offset,
+		Limit:       payload.Limit,
+		CountMode:   count.Mode,
+		HasNextPage: count.HasNextPage,
+	}
+
+	if payload.Limit != nil && *payload.Limit > 0 {
+		var currentPage int
+		if payload.Page != nil {
+			currentPage = *payload.Page
+		} else if payload.Offset != nil {
+			currentPage = (*payload.Offset / *payload.Limit) + 1
+		} else {
+			currentPage = 1
+		}
+
+		meta.CurrentPage = &currentPage
+	}
+
+	return meta
+}
+
 func (f *Filter) UnmarshalJSON(data []byte) error {
 	var raw map[string]json.RawMessage
 	if err := json.Unmarshal(data, &raw); err != nil {
@@ -275,6 +368,27 @@ func (f *Filter) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// MarshalJSON writes the filter in the same form UnmarshalJSON reads, so a
+// payload can be stored and sent back (e.g. export jobs).
+func (f Filter) MarshalJSON() ([]byte, error) {
+	raw := make(map[string]interface{}, len(f.Fields)+3)
+
+	for field, expr := range f.Fields {
+		raw[field] = expr
+	}
+	if len(f.And) > 0 {
+		raw["$and"] = f.And
+	}
+	if len(f.Or) > 0 {
+		raw["$or"] = f.Or
+	}
+	if f.Not != nil {
+		raw["$not"] = f.Not
+	}
+
+	return json.Marshal(raw)
+}
+
 func SnakeToCamel(s string) string {
 	parts := strings.Split(s, "_")
 	for i := range parts {
@@ -297,10 +411,9 @@ func ParseNested(input string) []string {
 
 func ApplyFilter(builder QueryBuilder, filter Filter, fieldExprApplier FieldExprApplier) QueryBuilder {
 
-	// Campos
-	for field, expr := range filter.Fields {
-		// builder = applyFieldExpr(builder, field, expr)
-		builder = fieldExprApplier(builder, field, expr)
+	// Campos (ordenados: o mesmo filtro gera sempre o mesmo SQL, como os planos)
+	for _, field := range filter.SortedFieldNames() {
+		builder = fieldExprApplier(builder, field, filter.Fields[field])
 	}
 
 	// AND
@@ -332,13 +445,18 @@ func CastIfJSONB(sqlField string, isJSONB bool, value interface{}) string {
 		return sqlField
 	}
 
+	return sqlField + JSONBCast(value)
+}
+
+// JSONBCast is the cast applied to a JSONB text value compared with value.
+func JSONBCast(value interface{}) string {
 	switch value.(type) {
 	case int, int32, int64, float32, float64:
-		return sqlField + "::numeric"
+		return "::numeric"
 	case bool:
-		return sqlField + "::boolean"
+		return "::boolean"
 	default:
-		return sqlField
+		return ""
 	}
 }
 
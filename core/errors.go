@@ -0,0 +1,135 @@
+package fwork_server_orm
+
+import (
+	"errors"
+	"net/http"
+)
+
+type ErrorKind string
+
+const (
+	ErrorBadQuery   ErrorKind = "bad_query"
+	ErrorNotFound   ErrorKind = "not_found"
+	ErrorConflict   ErrorKind = "conflict"
+	ErrorValidation ErrorKind = "validation"
+	ErrorForbidden  ErrorKind = "forbidden"
+	ErrorInternal   ErrorKind = "internal"
+
+	// ErrorTimeout: the query deadline expired.
+	ErrorTimeout ErrorKind = "timeout"
+	// ErrorUnavailable: the request was cancelled before the query finished.
+	ErrorUnavailable ErrorKind = "unavailable"
+)
+
+func (k ErrorKind) Status() int {
+	switch k {
+	case ErrorBadQuery:
+		return http.StatusBadRequest
+	case ErrorNotFound:
+		return http.StatusNotFound
+	case ErrorConflict:
+		return http.StatusConflict
+	case ErrorValidation:
+		return http.StatusUnprocessableEntity
+	case ErrorForbidden:
+		return http.StatusForbidden
+	case ErrorTimeout:
+		return http.StatusGatewayTimeout
+	case ErrorUnavailable:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func (k ErrorKind) Title() string {
+	switch k {
+	case ErrorBadQuery:
+		return "Bad query"
+	case ErrorNotFound:
+		return "Not found"
+	case ErrorConflict:
+		return "Conflict"
+	case ErrorValidation:
+		return "Validation failed"
+	case ErrorForbidden:
+		return "Forbidden"
+	case ErrorTimeout:
+		return "Query timeout"
+	case ErrorUnavailable:
+		return "Service unavailable"
+	default:
+		return "Internal error"
+	}
+}
+
+// Error is the typed error returned to HTTP clients. Detail is safe to show;
+// Err keeps the cause for logs and errors.Is / errors.As.
+type Error struct {
+	Kind   ErrorKind
+	Detail string
+	Fields []FieldError
+	Err    error
+}
+
+func NewError(kind ErrorKind, detail string, err error) *Error {
+	if detail == "" && err != nil {
+		detail = err.Error()
+	}
+	return &Error{Kind: kind, Detail: detail, Err: err}
+}
+
+func (e *Error) Error() string {
+	if e.Detail != "" {
+		return e.Detail
+	}
+	if e.Err != nil {
+		return e.Err.Error()
+	}
+	return e.Kind.Title()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+func (e *Error) Status() int {
+	return e.Kind.Status()
+}
+
+// AsError converts any error to *Error. A *ValidationError becomes a
+// validation error (or a conflict when every field error is a unique
+// violation); unknown errors use the fallback kind.
+func AsError(err error, fallback ErrorKind) *Error {
+	if err == nil {
+		return nil
+	}
+
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+
+	var verr *ValidationError
+	if errors.As(err, &verr) {
+		kind := ErrorValidation
+
+		conflict := len(verr.Errors) > 0
+		for _, fe := range verr.Errors {
+			if fe.Code != FieldErrorUnique {
+				conflict = false
+			}
+		}
+		if conflict {
+			kind = ErrorConflict
+		}
+
+		return &Error{Kind: kind, Detail: verr.Error(), Fields: verr.Errors, Err: err}
+	}
+
+	if fallback == "" {
+		fallback = ErrorInternal
+	}
+
+	return NewError(fallback, "", err)
+}
@@ -182,12 +182,8 @@ type MySubdocModelExample struct {
 	AnotherProp string `json:"another_prop,omitempty" gorm:"column:realm_uuid;type:uuid;not null"`
 }
 
-const (
-	MyModelExampleUuid        Field[MyModelExample] = "uuid"
-	MyModelExampleSomeProp    Field[MyModelExample] = "some_prop"
-	MyModelExampleAnotherProp Field[MyModelExample] = "another_prop"
-	MyModelExampleSubDoc      Field[MyModelExample] = "subdoc"
-)
+// constantes geradas em ext_fields.go
+//go:generate go run ../cmd/goqlite-fields -type MyModelExample -output ext_fields.go
 
 func example() Filter {
 	filter := NewFilter[MyModelExample]().
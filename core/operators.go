@@ -0,0 +1,65 @@
+package fwork_server_orm
+
+// FieldKind is the type of a field as seen by the query DSL and the API
+// documents, independent of the database.
+type FieldKind string
+
+const (
+	KindString  FieldKind = "string"
+	KindInteger FieldKind = "integer"
+	KindNumber  FieldKind = "number"
+	KindBoolean FieldKind = "boolean"
+	KindTime    FieldKind = "time" // date-time
+	KindDate    FieldKind = "date"
+	KindJSON    FieldKind = "json" // JSONB: paths inside the document are filterable too
+	KindBytes   FieldKind = "bytes"
+)
+
+// Enum is implemented by field types with a fixed set of values, listed in
+// the API documents:
+//
+//	type Status string
+//
+//	func (Status) EnumValues() []any { return []any{"draft", "published"} }
+type Enum interface {
+	EnumValues() []any
+}
+
+// Operators of the where DSL.
+const (
+	OpEq      = "$eq"
+	OpNe      = "$ne"
+	OpGt      = "$gt"
+	OpGte     = "$gte"
+	OpLt      = "$lt"
+	OpLte     = "$lte"
+	OpIn      = "$in"
+	OpNin     = "$nin"
+	OpLike    = "$like"
+	OpILike   = "$ilike"
+	OpBetween = "$between"
+	OpExists  = "$exists"
+	OpNull    = "$null"
+	OpOp      = "$op"
+)
+
+// OperatorsFor lists the operators that make sense for a kind of field.
+func OperatorsFor(kind FieldKind) []string {
+	equality := []string{OpEq, OpNe, OpIn, OpNin, OpNull, OpExists}
+	ordered := []string{OpGt, OpGte, OpLt, OpLte, OpBetween}
+
+	switch kind {
+	case KindString:
+		ops := append(equality, ordered...)
+		return append(ops, OpLike, OpILike)
+	case KindInteger, KindNumber, KindTime, KindDate:
+		return append(equality, ordered...)
+	case KindJSON:
+		ops := append(equality, ordered...)
+		return append(ops, OpLike, OpILike, OpOp)
+	case KindBoolean:
+		return equality
+	default:
+		return []string{OpEq, OpNe, OpNull, OpExists}
+	}
+}
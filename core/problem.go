@@ -0,0 +1,80 @@
+package fwork_server_orm
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// ProblemDetails is the RFC 7807 (application/problem+json) envelope.
+type ProblemDetails struct {
+	Type     string       `json:"type"`
+	Title    string       `json:"title"`
+	Status   int          `json:"status"`
+	Detail   string       `json:"detail,omitempty"`
+	Instance string       `json:"instance,omitempty"`
+	Errors   []FieldError `json:"errors,omitempty"`
+}
+
+// ErrorResponder writes err to the client. Handlers call it for every error,
+// so replacing it changes the error envelope of the whole API.
+type ErrorResponder interface {
+	RespondError(w http.ResponseWriter, r *http.Request, err error)
+}
+
+type ErrorResponderFunc func(w http.ResponseWriter, r *http.Request, err error)
+
+func (f ErrorResponderFunc) RespondError(w http.ResponseWriter, r *http.Request, err error) {
+	f(w, r, err)
+}
+
+var DefaultErrorResponder ErrorResponder = ProblemResponder{}
+
+// ProblemResponder writes errors as application/problem+json.
+type ProblemResponder struct {
+	// TypeBase prefixes the error kind in "type". Default: urn:goqlite:problem:
+	TypeBase string
+
+	// ExposeInternal shows the detail of internal errors (hidden by default).
+	ExposeInternal bool
+
+	// Customize can change the envelope before it is written.
+	Customize func(r *http.Request, problem *ProblemDetails, err *Error)
+}
+
+func (p ProblemResponder) RespondError(w http.ResponseWriter, r *http.Request, err error) {
+	e := AsError(err, ErrorInternal)
+
+	typeBase := p.TypeBase
+	if typeBase == "" {
+		typeBase = "urn:goqlite:problem:"
+	}
+
+	problem := ProblemDetails{
+		Type:   typeBase + strings.ReplaceAll(string(e.Kind), "_", "-"),
+		Title:  e.Kind.Title(),
+		Status: e.Status(),
+		Detail: e.Detail,
+		Errors: e.Fields,
+	}
+
+	if r != nil {
+		problem.Instance = r.URL.Path
+	}
+
+	if e.Kind == ErrorInternal && !p.ExposeInternal {
+		problem.Detail = ""
+	}
+
+	if p.Customize != nil {
+		p.Customize(r, &problem, e)
+	}
+
+	WriteProblem(w, problem)
+}
+
+func WriteProblem(w http.ResponseWriter, problem ProblemDetails) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(problem.Status)
+	json.NewEncoder(w).Encode(problem)
+}
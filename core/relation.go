@@ -0,0 +1,60 @@
+package fwork_server_orm
+
+// Rel is a to-one relation (belongs-to / has-one) of T to R, with its path in
+// filters ("customer", "customer.address"). goqlite-fields generates them for
+// each model:
+//
+//	const OrderCustomer Rel[Order, Customer] = "customer"
+type Rel[T any, R any] string
+
+// Field is the path of a field of R from T (OrderCustomer.Field(CustomerName)
+// is "customer.name").
+func (r Rel[T, R]) Field(f Field[R]) Field[T] {
+	return Field[T](string(r) + "." + string(f))
+}
+
+// Chain joins two relations: Chain(OrderCustomer, CustomerAddress) is the
+// relation "customer.address" of Order.
+func Chain[T any, R any, S any](a Rel[T, R], b Rel[R, S]) Rel[T, S] {
+	return Rel[T, S](string(a) + "." + string(b))
+}
+
+// Some builds the filter of the related R and moves it under the relation, to
+// be added with And / Or:
+//
+//	NewFilter[Order]().And(Some(OrderCustomer, func(f *FilterBuilder[Customer]) {
+//		f.ILike(CustomerName, "ana%").Eq(CustomerActive, true)
+//	}))
+//
+// The conditions go to the LEFT JOIN of the relation. Only to-one relations
+// are supported: a join to a has-many / many2many relation repeats the rows
+// of T (and inflates the count), so goqlite-fields does not generate Rel for
+// them.
+func Some[T any, R any](rel Rel[T, R], build func(f *FilterBuilder[R])) *FilterBuilder[T] {
+	sub := NewFilter[R]()
+	build(sub)
+
+	return &FilterBuilder[T]{filter: prefixFilter(string(rel)+".", sub.Build())}
+}
+
+// prefixFilter puts every field of f (and of its $and / $or / $not) under
+// prefix.
+func prefixFilter(prefix string, f Filter) Filter {
+	out := Filter{Fields: make(map[string]FieldExpr, len(f.Fields))}
+
+	for field, expr := range f.Fields {
+		out.Fields[prefix+field] = expr
+	}
+	for _, sub := range f.And {
+		out.And = append(out.And, prefixFilter(prefix, sub))
+	}
+	for _, sub := range f.Or {
+		out.Or = append(out.Or, prefixFilter(prefix, sub))
+	}
+	if f.Not != nil {
+		not := prefixFilter(prefix, *f.Not)
+		out.Not = &not
+	}
+
+	return out
+}
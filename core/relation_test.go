@@ -0,0 +1,66 @@
+package fwork_server_orm
+
+import "testing"
+
+const (
+	testCustomerName   Field[testCustomer] = "name"
+	testCustomerActive Field[testCustomer] = "active"
+
+	testAddressCity Field[testAddress] = "city"
+
+	testOrderCustomer   Rel[testOrder, testCustomer]   = "customer"
+	testCustomerAddress Rel[testCustomer, testAddress] = "address"
+)
+
+func TestRelationPaths(t *testing.T) {
+	if got := testOrderCustomer.Field(testCustomerName); got != "customer.name" {
+		t.Errorf("Rel.Field = %q, want customer.name", got)
+	}
+
+	chain := Chain(testOrderCustomer, testCustomerAddress)
+	if got := chain.Field(testAddressCity); got != "customer.address.city" {
+		t.Errorf("Chain(...).Field = %q, want customer.address.city", got)
+	}
+
+	city := NewStringField(chain.Field(testAddressCity))
+	assertFilterJSON(t, NewFilter[testOrder]().Where(city.Eq("Recife")).Build(), `{"customer.address.city": {"$eq": "Recife"}}`)
+}
+
+func TestSomeMovesTheSubFilterUnderTheRelation(t *testing.T) {
+	name := NewStringField(testCustomerName)
+	active := NewTypedField[bool](testCustomerActive)
+
+	for label, tc := range map[string]struct {
+		filter *FilterBuilder[testOrder]
+		want   string
+	}{
+		"fields": {
+			NewFilter[testOrder]().And(Some(testOrderCustomer, func(f *FilterBuilder[testCustomer]) {
+				f.Where(name.ILike("ana%"), active.Eq(true))
+			})),
+			`{"$and": [{"customer.name": {"$ilike": "ana%"}, "customer.active": {"$eq": true}}]}`,
+		},
+		"nested and / or / not": {
+			NewFilter[testOrder]().Or(Some(testOrderCustomer, func(f *FilterBuilder[testCustomer]) {
+				f.Or(
+					NewFilter[testCustomer]().Where(name.Eq("Ana")),
+					NewFilter[testCustomer]().And(NewFilter[testCustomer]().Where(name.Eq("Bia"))),
+				).Not(NewFilter[testCustomer]().Where(active.Eq(false)))
+			})),
+			`{"$or": [{
+				"$or": [{"customer.name": {"$eq": "Ana"}}, {"$and": [{"customer.name": {"$eq": "Bia"}}]}],
+				"$not": {"customer.active": {"$eq": false}}
+			}]}`,
+		},
+		"chained relation": {
+			NewFilter[testOrder]().And(Some(Chain(testOrderCustomer, testCustomerAddress), func(f *FilterBuilder[testAddress]) {
+				f.Eq(testAddressCity, "Recife")
+			})),
+			`{"$and": [{"customer.address.city": {"$eq": "Recife"}}]}`,
+		},
+	} {
+		t.Run(label, func(t *testing.T) {
+			assertFilterJSON(t, tc.filter.Build(), tc.want)
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package fwork_server_orm
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Shape is the canonical form of the payload without its values: fields,
+// operators, select, sort, nested and which options are present. Payloads
+// with the same shape produce the same SQL, only the bound values change.
+//
+//	{"where":{"age":{"$gt":18}},"limit":10} -> where(age[gt::numeric]);limit
+//
+// Value kinds are kept only where they change the SQL (JSONB casts).
+func (payload QueryPayload) Shape() string {
+	var sb strings.Builder
+
+	sb.WriteString("where(")
+	writeFilterShape(&sb, payload.Where)
+	sb.WriteString(")")
+
+	if len(payload.Select) > 0 {
+		sb.WriteString(";select(")
+		for i, field := range payload.Select {
+			if i > 0 {
+				sb.WriteString(",")
+			}
+			sb.WriteString(strconv.Quote(field))
+		}
+		sb.WriteString(")")
+	}
+
+	if len(payload.Order) > 0 {
+		sb.WriteString(";sort(")
+		for i, o := range payload.Order {
+			if i > 0 {
+				sb.WriteString(",")
+			}
+			sb.WriteString(strconv.Quote(o.Field) + " " + o.Direction())
+		}
+		sb.WriteString(")")
+	}
+
+	if payload.Nested != "" {
+		sb.WriteString(";nested(" + strconv.Quote(payload.Nested) + ")")
+	}
+
+	if payload.Limit != nil {
+		sb.WriteString(";limit")
+	}
+	if payload.Offset != nil {
+		sb.WriteString(";skip")
+	}
+	if payload.WithDeleted {
+		sb.WriteString(";withDeleted")
+	}
+	if payload.OnlyDeleted {
+		sb.WriteString(";onlyDeleted")
+	}
+
+	return sb.String()
+}
+
+// ShapeHash is a short, stable hash of Shape, usable as a cache key.
+func (payload QueryPayload) ShapeHash() string {
+	return ShapeHash(payload.Shape())
+}
+
+func ShapeHash(shape string) string {
+	sum := sha256.Sum256([]byte(shape))
+	return hex.EncodeToString(sum[:16])
+}
+
+// SortedFieldNames returns the field names of the filter in a stable order.
+func (f Filter) SortedFieldNames() []string {
+	names := make([]string, 0, len(f.Fields))
+	for name := range f.Fields {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func writeFilterShape(sb *strings.Builder, f Filter) {
+	for i, field := range f.SortedFieldNames() {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		sb.WriteString(strconv.Quote(field))
+		sb.WriteString("[")
+		sb.WriteString(strings.Join(f.Fields[field].OperatorShape(), ","))
+		sb.WriteString("]")
+	}
+
+	for _, sub := range f.And {
+		sb.WriteString("$and(")
+		writeFilterShape(sb, sub)
+		sb.WriteString(")")
+	}
+
+	for _, sub := range f.Or {
+		sb.WriteString("$or(")
+		writeFilterShape(sb, sub)
+		sb.WriteString(")")
+	}
+
+	if f.Not != nil {
+		sb.WriteString("$not(")
+		writeFilterShape(sb, *f.Not)
+		sb.WriteString(")")
+	}
+}
+
+// OperatorShape lists the operators set on the expression, in the order the
+// adapters apply them.
+func (f FieldExpr) OperatorShape() []string {
+	ops := make([]string, 0, 2)
+
+	if f.Eq != nil {
+		ops = append(ops, "eq")
+	}
+	if f.Ne != nil {
+		ops = append(ops, "ne")
+	}
+	if f.Gt != nil {
+		ops = append(ops, "gt"+JSONBCast(f.Gt))
+	}
+	if f.Gte != nil {
+		ops = append(ops, "gte"+JSONBCast(f.Gte))
+	}
+	if f.Lt != nil {
+		ops = append(ops, "lt"+JSONBCast(f.Lt))
+	}
+	if f.Lte != nil {
+		ops = append(ops, "lte"+JSONBCast(f.Lte))
+	}
+	if len(f.In) > 0 {
+		ops = append(ops, "in")
+	}
+	if len(f.Nin) > 0 {
+		ops = append(ops, "nin")
+	}
+	if f.Like != "" {
+		ops = append(ops, "like")
+	}
+	if f.ILike != "" {
+		ops = append(ops, "ilike")
+	}
+	if len(f.Between) == 2 {
+		ops = append(ops, "between"+JSONBCast(f.Between[0]))
+	}
+	if f.Exists != nil {
+		ops = append(ops, "exists:"+strconv.FormatBool(*f.Exists))
+	}
+	if f.IsNull != nil {
+		ops = append(ops, "null:"+strconv.FormatBool(*f.IsNull))
+	}
+	if f.Op != nil {
+		ops = append(ops, "op:"+strconv.Quote(f.Op.Op))
+	}
+
+	return ops
+}
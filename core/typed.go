@@ -0,0 +1,145 @@
+package fwork_server_orm
+
+import (
+	"cmp"
+	"time"
+)
+
+// Orderable are the values of the range operators ($gt, $lt, $between...).
+type Orderable interface {
+	cmp.Ordered | time.Time
+}
+
+// Cond is one condition on a field of T, built by the typed fields and added
+// with FilterBuilder.Where.
+type Cond[T any] struct {
+	field Field[T]
+	apply func(*FieldExpr)
+}
+
+func (c Cond[T]) Field() Field[T] {
+	return c.field
+}
+
+// Where adds the conditions to the filter (AND), like the untyped methods.
+func (b *FilterBuilder[T]) Where(conds ...Cond[T]) *FilterBuilder[T] {
+	for _, c := range conds {
+		b.set(c.field, c.apply)
+	}
+	return b
+}
+
+// TypedField is a Field[T] whose values are V: the equality operators only
+// accept V. See OrderedField and StringField for the range and text ones.
+//
+//	status := NewTypedField[Status](OrderStatus)
+//	NewFilter[Order]().Where(status.In(StatusPaid, StatusSent))
+type TypedField[T any, V any] struct {
+	Field[T]
+}
+
+// NewTypedField types f with V (T is inferred: NewTypedField[bool](f)).
+func NewTypedField[V any, T any](f Field[T]) TypedField[T, V] {
+	return TypedField[T, V]{f}
+}
+
+func (f TypedField[T, V]) cond(apply func(e *FieldExpr)) Cond[T] {
+	return Cond[T]{field: f.Field, apply: apply}
+}
+
+func (f TypedField[T, V]) Eq(v V) Cond[T] {
+	return f.cond(func(e *FieldExpr) { e.Eq = v })
+}
+
+func (f TypedField[T, V]) Ne(v V) Cond[T] {
+	return f.cond(func(e *FieldExpr) { e.Ne = v })
+}
+
+func (f TypedField[T, V]) In(v ...V) Cond[T] {
+	return f.cond(func(e *FieldExpr) { e.In = anySlice(v) })
+}
+
+func (f TypedField[T, V]) Nin(v ...V) Cond[T] {
+	return f.cond(func(e *FieldExpr) { e.Nin = anySlice(v) })
+}
+
+func (f TypedField[T, V]) IsNull() Cond[T] {
+	return f.cond(func(e *FieldExpr) { e.IsNull = boolPtr(true) })
+}
+
+func (f TypedField[T, V]) NotNull() Cond[T] {
+	return f.cond(func(e *FieldExpr) { e.IsNull = boolPtr(false) })
+}
+
+func (f TypedField[T, V]) Exists() Cond[T] {
+	return f.cond(func(e *FieldExpr) { e.Exists = boolPtr(true) })
+}
+
+func (f TypedField[T, V]) NotExists() Cond[T] {
+	return f.cond(func(e *FieldExpr) { e.Exists = boolPtr(false) })
+}
+
+// OrderedField adds the range operators to a TypedField of numbers, strings
+// or time.Time.
+type OrderedField[T any, V Orderable] struct {
+	TypedField[T, V]
+}
+
+func NewOrderedField[V Orderable, T any](f Field[T]) OrderedField[T, V] {
+	return OrderedField[T, V]{TypedField[T, V]{f}}
+}
+
+func (f OrderedField[T, V]) Gt(v V) Cond[T] {
+	return f.cond(func(e *FieldExpr) { e.Gt = v })
+}
+
+func (f OrderedField[T, V]) Gte(v V) Cond[T] {
+	return f.cond(func(e *FieldExpr) { e.Gte = v })
+}
+
+func (f OrderedField[T, V]) Lt(v V) Cond[T] {
+	return f.cond(func(e *FieldExpr) { e.Lt = v })
+}
+
+func (f OrderedField[T, V]) Lte(v V) Cond[T] {
+	return f.cond(func(e *FieldExpr) { e.Lte = v })
+}
+
+func (f OrderedField[T, V]) Between(a, c V) Cond[T] {
+	return f.cond(func(e *FieldExpr) { e.Between = []any{a, c} })
+}
+
+// StringField adds $like / $ilike to an OrderedField of strings (or string
+// types, e.g. enums).
+type StringField[T any, V ~string] struct {
+	OrderedField[T, V]
+}
+
+func NewStringField[T any](f Field[T]) StringField[T, string] {
+	return StringField[T, string]{OrderedField[T, string]{TypedField[T, string]{f}}}
+}
+
+// NewStringFieldOf is NewStringField for string types other than string.
+func NewStringFieldOf[V ~string, T any](f Field[T]) StringField[T, V] {
+	return StringField[T, V]{OrderedField[T, V]{TypedField[T, V]{f}}}
+}
+
+func (f StringField[T, V]) Like(pattern string) Cond[T] {
+	return f.cond(func(e *FieldExpr) { e.Like = pattern })
+}
+
+func (f StringField[T, V]) ILike(pattern string) Cond[T] {
+	return f.cond(func(e *FieldExpr) { e.ILike = pattern })
+}
+
+func anySlice[V any](values []V) []any {
+	result := make([]any, len(values))
+	for i, v := range values {
+		result[i] = v
+	}
+	return result
+}
+
+func boolPtr(v bool) *bool {
+	return &v
+}
@@ -0,0 +1,115 @@
+package fwork_server_orm
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+type testStatus string
+
+type testAddress struct {
+	City string `json:"city"`
+}
+
+type testCustomer struct {
+	Name    string      `json:"name"`
+	Active  bool        `json:"active"`
+	Address testAddress `json:"address"`
+}
+
+type testOrder struct {
+	Total    float64      `json:"total"`
+	Status   testStatus   `json:"status"`
+	Note     *string      `json:"note"`
+	PaidAt   time.Time    `json:"paidAt"`
+	Customer testCustomer `json:"customer"`
+}
+
+const (
+	testOrderTotal  Field[testOrder] = "total"
+	testOrderStatus Field[testOrder] = "status"
+	testOrderNote   Field[testOrder] = "note"
+	testOrderPaidAt Field[testOrder] = "paidAt"
+)
+
+// assertFilterJSON compares the JSON of filter (the where sent to the API)
+// with want, ignoring formatting.
+func assertFilterJSON(t *testing.T, filter Filter, want string) {
+	t.Helper()
+
+	got, err := json.Marshal(filter)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wantValue any
+	if err := json.Unmarshal([]byte(want), &wantValue); err != nil {
+		t.Fatalf("invalid want JSON: %v", err)
+	}
+	normalized, _ := json.Marshal(wantValue)
+
+	if string(got) != string(normalized) {
+		t.Errorf("where = %s\nwant    %s", got, normalized)
+	}
+}
+
+func TestTypedFieldsBuildTheUntypedFilter(t *testing.T) {
+	total := NewOrderedField[float64](testOrderTotal)
+	status := NewStringFieldOf[testStatus](testOrderStatus)
+	note := NewStringField(testOrderNote)
+	paidAt := NewOrderedField[time.Time](testOrderPaidAt)
+
+	day := time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)
+
+	for name, tc := range map[string]struct {
+		typed   *FilterBuilder[testOrder]
+		untyped *FilterBuilder[testOrder]
+		want    string
+	}{
+		"eq / ne": {
+			NewFilter[testOrder]().Where(status.Eq("paid"), total.Ne(0)),
+			NewFilter[testOrder]().Eq(testOrderStatus, testStatus("paid")).Ne(testOrderTotal, 0.0),
+			`{"status": {"$eq": "paid"}, "total": {"$ne": 0}}`,
+		},
+		"in / nin": {
+			NewFilter[testOrder]().Where(status.In("paid", "sent"), note.Nin("a", "b")),
+			NewFilter[testOrder]().In(testOrderStatus, testStatus("paid"), testStatus("sent")).Nin(testOrderNote, "a", "b"),
+			`{"status": {"$in": ["paid", "sent"]}, "note": {"$nin": ["a", "b"]}}`,
+		},
+		"range on one field": {
+			NewFilter[testOrder]().Where(total.Gte(10), total.Lt(99.5)),
+			NewFilter[testOrder]().Gte(testOrderTotal, 10.0).Lt(testOrderTotal, 99.5),
+			`{"total": {"$gte": 10, "$lt": 99.5}}`,
+		},
+		"gt / lte / between": {
+			NewFilter[testOrder]().Where(total.Gt(1), paidAt.Lte(day), status.Between("a", "m")),
+			NewFilter[testOrder]().Gt(testOrderTotal, 1.0).Lte(testOrderPaidAt, day).Between(testOrderStatus, testStatus("a"), testStatus("m")),
+			`{"total": {"$gt": 1}, "paidAt": {"$lte": "2024-05-01T00:00:00Z"}, "status": {"$between": ["a", "m"]}}`,
+		},
+		"like / ilike": {
+			NewFilter[testOrder]().Where(note.Like("%x%"), status.ILike("pa%")),
+			NewFilter[testOrder]().Like(testOrderNote, "%x%").ILike(testOrderStatus, "pa%"),
+			`{"note": {"$like": "%x%"}, "status": {"$ilike": "pa%"}}`,
+		},
+		"null / exists": {
+			NewFilter[testOrder]().Where(note.IsNull(), total.NotNull(), status.Exists(), paidAt.NotExists()),
+			NewFilter[testOrder]().IsNull(testOrderNote).NotNull(testOrderTotal).Exists(testOrderStatus).NotExists(testOrderPaidAt),
+			`{"note": {"$null": true}, "total": {"$null": false}, "status": {"$exists": true}, "paidAt": {"$exists": false}}`,
+		},
+		"mixed with untyped": {
+			NewFilter[testOrder]().Where(total.Gt(5)).Lt(testOrderTotal, 9.0),
+			NewFilter[testOrder]().Gt(testOrderTotal, 5.0).Lt(testOrderTotal, 9.0),
+			`{"total": {"$gt": 5, "$lt": 9}}`,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			assertFilterJSON(t, tc.typed.Build(), tc.want)
+			assertFilterJSON(t, tc.untyped.Build(), tc.want)
+		})
+	}
+
+	if got := status.Eq("paid").Field(); got != testOrderStatus {
+		t.Errorf("Cond.Field() = %q, want %q", got, testOrderStatus)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"database/sql/driver"
 	"encoding/json"
 	"fmt"
+	"strings"
 
 	"gorm.io/gorm"
 	"gorm.io/gorm/schema"
@@ -19,6 +20,52 @@ type QueryPayload struct {
 	Limit  *int     `json:"limit,omitempty"`
 	Offset *int     `json:"skip,omitempty"`
 	Page   *int     `json:"page,omitempty"`
+
+	// soft delete: include deleted rows / return only deleted rows
+	WithDeleted bool `json:"withDeleted,omitempty"`
+	OnlyDeleted bool `json:"onlyDeleted,omitempty"`
+
+	// how the total is computed (default: exact)
+	Count CountMode `json:"count,omitempty"`
+}
+
+type CountMode string
+
+const (
+	// CountExact runs COUNT(*) (default).
+	CountExact CountMode = "exact"
+	// CountNone skips the count query.
+	CountNone CountMode = "none"
+	// CountHasMore loads limit+1 rows and only reports hasNextPage.
+	CountHasMore CountMode = "hasMore"
+	// CountEstimated uses the planner estimate when the database has one
+	// (Postgres); otherwise an exact count is used.
+	CountEstimated CountMode = "estimated"
+)
+
+func (m CountMode) IsValid() bool {
+	switch m {
+	case "", CountExact, CountNone, CountHasMore, CountEstimated:
+		return true
+	}
+	return false
+}
+
+// ExportJobPayload starts a background export.
+type ExportJobPayload struct {
+	Query  QueryPayload `json:"query"`
+	Format string       `json:"format"` // default: csv
+}
+
+type UpdateWherePayload struct {
+	Where  Filter         `json:"where"`
+	Set    map[string]any `json:"set"`
+	DryRun bool           `json:"dryRun,omitempty"`
+}
+
+type DeleteWherePayload struct {
+	Where  Filter `json:"where"`
+	DryRun bool   `json:"dryRun,omitempty"`
 }
 
 // ...request
@@ -36,6 +83,62 @@ type PaginationMeta struct {
 	Count       *int `json:"count,omitempty"`
 	PageCount   *int `json:"pageCount,omitempty"`
 	CurrentPage *int `json:"currentPage,omitempty"`
+
+	// mode that produced Count (omitted when no mode was requested)
+	CountMode   CountMode `json:"countMode,omitempty"`
+	HasNextPage *bool     `json:"hasNextPage,omitempty"`
+}
+
+// ListCount is the result of the count step of a list query.
+type ListCount struct {
+	Mode        CountMode
+	Total       int64 // exact / estimated modes
+	HasNextPage *bool // hasMore mode
+}
+
+type BulkItemStatus string
+
+const (
+	BulkItemOk         BulkItemStatus = "ok"
+	BulkItemFailed     BulkItemStatus = "failed"
+	BulkItemRolledBack BulkItemStatus = "rolled_back" // ok, but the transaction was rolled back
+)
+
+type BulkItemResult[T any] struct {
+	Index  int            `json:"index"`
+	Key    any            `json:"key,omitempty"`
+	Status BulkItemStatus `json:"status"`
+	Error  string         `json:"error,omitempty"`
+	Errors []FieldError   `json:"errors,omitempty"`
+	Data   *T             `json:"data,omitempty"`
+}
+
+type BulkResult[T any] struct {
+	Atomic    bool                `json:"atomic"`
+	Succeeded int                 `json:"succeeded"`
+	Failed    int                 `json:"failed"`
+	Items     []BulkItemResult[T] `json:"items"`
+}
+
+// ImportResult reports a file import. Errors lists the failed rows only.
+type ImportResult struct {
+	Atomic    bool             `json:"atomic"`
+	DryRun    bool             `json:"dryRun,omitempty"`
+	Total     int              `json:"total"`
+	Succeeded int              `json:"succeeded"`
+	Failed    int              `json:"failed"`
+	Errors    []ImportRowError `json:"errors,omitempty"`
+}
+
+type ImportRowError struct {
+	Row    int          `json:"row"` // line in the file
+	Error  string       `json:"error"`
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+type WriteResult struct {
+	Affected int64 `json:"affected"`
+	DryRun   bool  `json:"dryRun,omitempty"`
 }
 
 // ...response
@@ -98,6 +201,15 @@ type Order struct {
 	Dir   string `json:"dir"` // asc | desc
 }
 
+// Direction is Dir in upper case, ASC when invalid.
+func (o Order) Direction() string {
+	dir := strings.ToUpper(o.Dir)
+	if dir != "ASC" && dir != "DESC" {
+		return "ASC"
+	}
+	return dir
+}
+
 type FieldExprApplier func(builder QueryBuilder, field string, expr FieldExpr) QueryBuilder
 
 // DB TYPES
@@ -0,0 +1,61 @@
+package fwork_server_orm
+
+import (
+	"strings"
+)
+
+const (
+	FieldErrorRequired   = "required"
+	FieldErrorInvalid    = "invalid"
+	FieldErrorMin        = "min"
+	FieldErrorMax        = "max"
+	FieldErrorLen        = "len"
+	FieldErrorOneOf      = "oneof"
+	FieldErrorEmail      = "email"
+	FieldErrorUnique     = "unique"
+	FieldErrorForeignKey = "foreign_key"
+	FieldErrorNotNull    = "not_null"
+	FieldErrorCheck      = "check"
+)
+
+type FieldError struct {
+	Field   string `json:"field,omitempty"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// ValidationError groups field-level errors. Err keeps the original cause
+// (e.g. the driver error of a unique violation), if any.
+type ValidationError struct {
+	Errors []FieldError `json:"errors"`
+	Err    error        `json:"-"`
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, 0, len(e.Errors))
+	for _, fe := range e.Errors {
+		if fe.Field != "" {
+			msgs = append(msgs, fe.Field+": "+fe.Message)
+		} else {
+			msgs = append(msgs, fe.Message)
+		}
+	}
+	return "validation failed: " + strings.Join(msgs, "; ")
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+func (e *ValidationError) Add(field, code, message string) *ValidationError {
+	e.Errors = append(e.Errors, FieldError{Field: field, Code: code, Message: message})
+	return e
+}
+
+// OrNil returns nil when no error was added, so it can be returned as error.
+func (e *ValidationError) OrNil() error {
+	if e == nil || len(e.Errors) == 0 {
+		return nil
+	}
+	return e
+}
@@ -0,0 +1,384 @@
+package fwork_server_gorm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+
+	fwork_server_orm "github.com/joabssilveira/GoQLite/core"
+	"gorm.io/gorm"
+)
+
+type BulkMode string
+
+const (
+	// BulkAtomic runs every item in a single transaction: all or nothing (default).
+	BulkAtomic BulkMode = ""
+	// BulkBestEffort persists every item it can and reports the others.
+	BulkBestEffort BulkMode = "best_effort"
+)
+
+const defaultBulkBatchSize = 100
+
+type BulkOptions[T any] struct {
+	Mode      BulkMode
+	BatchSize int // default: 100
+
+	// Upsert applies to GormCreateBulk only.
+	Upsert *UpsertOptions
+
+	// Validator overrides DefaultStructValidator.
+	Validator StructValidator
+
+	// Hooks run for every item. With create hooks GormCreateBulk inserts the
+	// items one by one.
+	Hooks *Hooks[T]
+}
+
+var ErrBulkRolledBack = errors.New("bulk operation rolled back: one or more items failed")
+
+func bulkOptions[T any](opts []BulkOptions[T]) BulkOptions[T] {
+	var opt BulkOptions[T]
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+	if opt.BatchSize <= 0 {
+		opt.BatchSize = defaultBulkBatchSize
+	}
+	return opt
+}
+
+// GormCreateBulk inserts items with CreateInBatches. When a batch fails, its
+// items are retried one by one to report the failing ones.
+func GormCreateBulk[T any](
+	ctx context.Context,
+	items []T,
+	db *gorm.DB,
+	opts ...BulkOptions[T],
+) (fwork_server_orm.BulkResult[T], error) {
+
+	return createBulk(ctx, items, db, bulkOptions(opts), make([]error, len(items)))
+}
+
+// createBulk is GormCreateBulk with items that already failed (invalid[i] !=
+// nil, e.g. import parse errors), which are reported and never persisted.
+func createBulk[T any](
+	ctx context.Context,
+	items []T,
+	db *gorm.DB,
+	opt BulkOptions[T],
+	invalid []error,
+) (fwork_server_orm.BulkResult[T], error) {
+
+	db = db.WithContext(ctx)
+
+	if opt.Hooks.hasCreate() {
+		errs, err := runBulk(db, invalid, opt.Mode, opt.BatchSize, nil,
+			func(tx *gorm.DB, i int) error {
+				created, err := GormCreate(ctx, items[i], tx, CreateOptions[T]{Upsert: opt.Upsert, Validator: opt.Validator, Hooks: opt.Hooks})
+				if err != nil {
+					return err
+				}
+
+				items[i] = *created
+				return nil
+			},
+		)
+		if err != nil {
+			return fwork_server_orm.BulkResult[T]{}, err
+		}
+
+		return buildBulkResult(opt.Mode, errs, nil, func(i int) *T { return &items[i] })
+	}
+
+	for i := range items {
+		if invalid[i] != nil {
+			continue
+		}
+
+		// 🔴 sanitiza se o tipo suportar
+		if s, ok := any(&items[i]).(PersistSanitizer); ok {
+			s.SanitizeForPersist()
+		}
+
+		invalid[i] = validateForPersist(&items[i], opt.Validator)
+	}
+
+	errs, err := runBulk(db, invalid, opt.Mode, opt.BatchSize,
+		func(tx *gorm.DB, start, end int) error {
+			return withUpsert[T](tx, opt.Upsert).CreateInBatches(items[start:end], end-start).Error
+		},
+		func(tx *gorm.DB, i int) error {
+			err := withUpsert[T](tx, opt.Upsert).Create(&items[i]).Error
+			return translateDBError[T](tx, err)
+		},
+	)
+	if err != nil {
+		return fwork_server_orm.BulkResult[T]{}, err
+	}
+
+	return buildBulkResult(opt.Mode, errs, nil, func(i int) *T { return &items[i] })
+}
+
+// GormUpdateBulk updates each item by its keyName value (see GormUpdate).
+// Keys that match no row fail their item as not found.
+func GormUpdateBulk[T any](
+	ctx context.Context,
+	items []T,
+	db *gorm.DB,
+	keyName string,
+	opts ...BulkOptions[T],
+) (fwork_server_orm.BulkResult[T], error) {
+
+	db = db.WithContext(ctx)
+
+	opt := bulkOptions(opts)
+
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(new(T)); err != nil {
+		return fwork_server_orm.BulkResult[T]{}, err
+	}
+
+	keyField := stmt.Schema.LookUpField(keyName)
+	if keyField == nil {
+		return fwork_server_orm.BulkResult[T]{}, fmt.Errorf("unknown key field: %s", keyName)
+	}
+
+	keys := make([]any, len(items))
+	for i := range items {
+		if value, zero := keyField.ValueOf(db.Statement.Context, reflect.ValueOf(&items[i]).Elem()); !zero {
+			keys[i] = value
+		}
+	}
+
+	errs, err := runBulk(db, make([]error, len(items)), opt.Mode, opt.BatchSize, nil,
+		func(tx *gorm.DB, i int) error {
+			if keys[i] == nil {
+				return badQuery("missing %s", keyName)
+			}
+
+			// sem Scope nada mais acusa uma chave inexistente
+			updated, err := gormUpdate(ctx, items[i], keys[i], tx, keyName, UpdateOptions[T]{Validator: opt.Validator, Hooks: opt.Hooks}, true)
+			if err != nil {
+				return err
+			}
+
+			items[i] = *updated
+			return nil
+		},
+	)
+	if err != nil {
+		return fwork_server_orm.BulkResult[T]{}, err
+	}
+
+	return buildBulkResult(opt.Mode, errs, keys, func(i int) *T { return &items[i] })
+}
+
+// GormDeleteBulk deletes each key (see GormDelete). Keys decoded from JSON
+// (float64, json.Number) are converted to the type of the key field; strings
+// are kept, as the ids of the routes.
+func GormDeleteBulk[T any](
+	ctx context.Context,
+	keys []any,
+	db *gorm.DB,
+	keyName string,
+	opts ...BulkOptions[T],
+) (fwork_server_orm.BulkResult[T], error) {
+
+	db = db.WithContext(ctx)
+
+	opt := bulkOptions(opts)
+
+	errs := make([]error, len(keys))
+	keys = append([]any(nil), keys...)
+
+	if keyField := lookUpField(schemaOf(db, new(T)), keyName); keyField != nil {
+		for i, key := range keys {
+			if _, ok := key.(string); ok || key == nil {
+				continue
+			}
+
+			typed, err := coerceFieldValue(keyField, key)
+			if err != nil {
+				errs[i] = badQuery("invalid %s: %w", keyName, err)
+				continue
+			}
+			keys[i] = typed
+		}
+	}
+
+	errs, err := runBulk(db, errs, opt.Mode, opt.BatchSize, nil,
+		func(tx *gorm.DB, i int) error {
+			return GormDelete(ctx, keys[i], tx, keyName, DeleteOptions[T]{Hooks: opt.Hooks})
+		},
+	)
+	if err != nil {
+		return fwork_server_orm.BulkResult[T]{}, err
+	}
+
+	return buildBulkResult[T](opt.Mode, errs, keys, nil)
+}
+
+// runBulk executes the items in batches. errs holds one entry per item;
+// items that already failed (e.g. validation) are skipped. batch (optional)
+// persists a whole batch at once; when it fails, item runs for every item of
+// the batch. In atomic mode everything runs in one transaction and savepoints
+// isolate the failed statements, so the remaining items can still be checked.
+func runBulk(
+	db *gorm.DB,
+	errs []error,
+	mode BulkMode,
+	batchSize int,
+	batch func(tx *gorm.DB, start, end int) error,
+	item func(tx *gorm.DB, i int) error,
+) ([]error, error) {
+
+	n := len(errs)
+	atomic := mode != BulkBestEffort
+
+	failed := hasErrors(errs)
+
+	// tudo ou nada: não adianta ir ao banco
+	if atomic && failed {
+		return errs, nil
+	}
+
+	run := func(tx *gorm.DB) {
+		// savepoints also when the caller already opened a transaction
+		// (e.g. statement timeout), otherwise one failure aborts it
+		useSavePoints := atomic || inTransactionAlready(tx)
+
+		savePoint := func(name string) {
+			if useSavePoints {
+				tx.SavePoint(name)
+			}
+		}
+
+		rollbackTo := func(name string) {
+			if useSavePoints {
+				tx.RollbackTo(name)
+			}
+		}
+
+		for start := 0; start < n; start += batchSize {
+			end := min(start+batchSize, n)
+
+			if batch != nil && !hasErrors(errs[start:end]) {
+				savePoint("goqlite_batch")
+				if err := batch(tx, start, end); err == nil {
+					continue
+				}
+				rollbackTo("goqlite_batch")
+			}
+
+			for i := start; i < end; i++ {
+				if errs[i] != nil {
+					continue
+				}
+
+				savePoint("goqlite_item")
+				if err := item(tx, i); err != nil {
+					rollbackTo("goqlite_item")
+					errs[i] = err
+					failed = true
+				}
+			}
+		}
+	}
+
+	if !atomic {
+		run(db)
+		return errs, nil
+	}
+
+	// Transaction usa savepoint quando já existe uma transação aberta
+	err := db.Transaction(func(tx *gorm.DB) error {
+		run(tx)
+		if failed {
+			return ErrBulkRolledBack
+		}
+		return nil
+	})
+	if errors.Is(err, ErrBulkRolledBack) {
+		err = nil
+	}
+
+	return errs, err
+}
+
+func hasErrors(errs []error) bool {
+	for _, err := range errs {
+		if err != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func buildBulkResult[T any](
+	mode BulkMode,
+	errs []error,
+	keys []any,
+	data func(i int) *T,
+) (fwork_server_orm.BulkResult[T], error) {
+
+	result := fwork_server_orm.BulkResult[T]{
+		Atomic: mode != BulkBestEffort,
+		Items:  make([]fwork_server_orm.BulkItemResult[T], len(errs)),
+	}
+
+	for _, err := range errs {
+		if err != nil {
+			result.Failed++
+		}
+	}
+
+	rolledBack := result.Atomic && result.Failed > 0
+
+	for i, err := range errs {
+		item := fwork_server_orm.BulkItemResult[T]{Index: i}
+
+		if keys != nil {
+			item.Key = keys[i]
+		}
+
+		switch {
+		case err != nil:
+			item.Status = fwork_server_orm.BulkItemFailed
+			item.Error = bulkItemError(err)
+
+			var verr *fwork_server_orm.ValidationError
+			if errors.As(err, &verr) {
+				item.Errors = verr.Errors
+			}
+		case rolledBack:
+			item.Status = fwork_server_orm.BulkItemRolledBack
+		default:
+			item.Status = fwork_server_orm.BulkItemOk
+			result.Succeeded++
+			if data != nil {
+				item.Data = data(i)
+			}
+		}
+
+		result.Items[i] = item
+	}
+
+	if rolledBack {
+		return result, ErrBulkRolledBack
+	}
+
+	return result, nil
+}
+
+// bulkItemError is the message of a failed item, classified as HttpError
+// does: typed errors keep their message, any other error (driver, hooks) is
+// an internal error and only its title is reported.
+func bulkItemError(err error) string {
+	e := HttpError(err)
+	if e.Kind == fwork_server_orm.ErrorInternal {
+		return e.Kind.Title()
+	}
+	return e.Error()
+}
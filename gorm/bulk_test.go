@@ -0,0 +1,200 @@
+package fwork_server_gorm
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	fwork_server_orm "github.com/joabssilveira/GoQLite/core"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+type testBulkRow struct {
+	ID   uint   `json:"id"`
+	Name string `json:"name" gorm:"uniqueIndex"`
+}
+
+func TestBulkResultHidesInternalErrors(t *testing.T) {
+	db := sqliteDB(t, &testBulkRow{})
+
+	hooks := &Hooks[testBulkRow]{}
+	hooks.BeforeCreate = append(hooks.BeforeCreate, func(ctx context.Context, tx *gorm.DB, item *testBulkRow) error {
+		if item.Name == "boom" {
+			return errors.New(`pq: relation "secret_table" does not exist`)
+		}
+		if item.Name == "bad" {
+			return fwork_server_orm.NewError(fwork_server_orm.ErrorBadQuery, "bad name", nil)
+		}
+		return nil
+	})
+
+	items := []testBulkRow{{Name: "ok"}, {Name: "boom"}, {Name: "bad"}}
+	result, err := GormCreateBulk(context.Background(), items, db, BulkOptions[testBulkRow]{Mode: BulkBestEffort, Hooks: hooks})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"", "Internal error", "bad name"}
+	for i, item := range result.Items {
+		if item.Error != want[i] {
+			t.Errorf("item %d error = %q, want %q", i, item.Error, want[i])
+		}
+	}
+}
+
+// sqlRecorder keeps the statements run through the session.
+type sqlRecorder struct {
+	logger.Interface
+	mu  sync.Mutex
+	sql []string
+}
+
+func (r *sqlRecorder) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	sql, _ := fc()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.sql = append(r.sql, sql)
+}
+
+func (r *sqlRecorder) count(prefix string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	n := 0
+	for _, sql := range r.sql {
+		if strings.HasPrefix(sql, prefix) {
+			n++
+		}
+	}
+	return n
+}
+
+func bulkStatuses[T any](result fwork_server_orm.BulkResult[T]) []fwork_server_orm.BulkItemStatus {
+	statuses := make([]fwork_server_orm.BulkItemStatus, len(result.Items))
+	for i, item := range result.Items {
+		statuses[i] = item.Status
+	}
+	return statuses
+}
+
+func TestUpdateBulkReportsMissingKeys(t *testing.T) {
+	db := sqliteDB(t, &testBulkRow{})
+	if err := db.Create(&testBulkRow{ID: 1, Name: "one"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	items := []testBulkRow{{ID: 1, Name: "uno"}, {ID: 99, Name: "ghost"}}
+	result, err := GormUpdateBulk(context.Background(), items, db, "id", BulkOptions[testBulkRow]{Mode: BulkBestEffort})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []fwork_server_orm.BulkItemStatus{fwork_server_orm.BulkItemOk, fwork_server_orm.BulkItemFailed}
+	if got := bulkStatuses(result); !reflect.DeepEqual(got, want) {
+		t.Fatalf("statuses = %v, want %v", got, want)
+	}
+	if result.Items[1].Key != uint(99) || result.Items[1].Error != gorm.ErrRecordNotFound.Error() {
+		t.Errorf("missing key item = %+v, want a not found error", result.Items[1])
+	}
+
+	var count int64
+	db.Model(new(testBulkRow)).Count(&count)
+	if count != 1 {
+		t.Errorf("%d rows, want the missing key not created", count)
+	}
+}
+
+func TestBulkAtomicRollsBack(t *testing.T) {
+	db := sqliteDB(t, &testBulkRow{})
+	if err := db.Create(&testBulkRow{ID: 1, Name: "taken"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	for name, run := range map[string]func(db *gorm.DB) (fwork_server_orm.BulkResult[testBulkRow], error){
+		"create": func(db *gorm.DB) (fwork_server_orm.BulkResult[testBulkRow], error) {
+			items := []testBulkRow{{Name: "a"}, {Name: "taken"}, {Name: "b"}}
+			return GormCreateBulk(context.Background(), items, db, BulkOptions[testBulkRow]{BatchSize: 1})
+		},
+		"update": func(db *gorm.DB) (fwork_server_orm.BulkResult[testBulkRow], error) {
+			items := []testBulkRow{{ID: 1, Name: "a"}, {ID: 99, Name: "b"}}
+			return GormUpdateBulk(context.Background(), items, db, "id")
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			result, err := run(db)
+			if !errors.Is(err, ErrBulkRolledBack) {
+				t.Fatalf("err = %v, want ErrBulkRolledBack", err)
+			}
+			if !result.Atomic || result.Succeeded != 0 || result.Failed != 1 {
+				t.Errorf("result = %+v", result)
+			}
+			for _, item := range result.Items {
+				if item.Status == fwork_server_orm.BulkItemOk {
+					t.Errorf("item %d is ok in a rolled back bulk", item.Index)
+				}
+			}
+
+			var rows []testBulkRow
+			db.Find(&rows)
+			if len(rows) != 1 || rows[0].Name != "taken" {
+				t.Errorf("rows = %+v, want only the original row", rows)
+			}
+		})
+	}
+}
+
+func TestBulkBestEffortUsesSavePointsInTransactions(t *testing.T) {
+	for name, outer := range map[string]bool{"own transaction": false, "caller transaction": true} {
+		t.Run(name, func(t *testing.T) {
+			recorder := &sqlRecorder{Interface: logger.Discard}
+			db := sqliteDB(t, &testBulkRow{}).Session(&gorm.Session{Logger: recorder})
+			if err := db.Create(&testBulkRow{Name: "taken"}).Error; err != nil {
+				t.Fatal(err)
+			}
+
+			items := []testBulkRow{{Name: "a"}, {Name: "taken"}, {Name: "b"}}
+			opts := BulkOptions[testBulkRow]{Mode: BulkBestEffort, BatchSize: 10}
+
+			var result fwork_server_orm.BulkResult[testBulkRow]
+			var err error
+			if outer {
+				err = db.Transaction(func(tx *gorm.DB) (err error) {
+					result, err = GormCreateBulk(context.Background(), items, tx, opts)
+					return err
+				})
+			} else {
+				result, err = GormCreateBulk(context.Background(), items, db, opts)
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want := []fwork_server_orm.BulkItemStatus{fwork_server_orm.BulkItemOk, fwork_server_orm.BulkItemFailed, fwork_server_orm.BulkItemOk}
+			if got := bulkStatuses(result); !reflect.DeepEqual(got, want) {
+				t.Errorf("statuses = %v, want %v", got, want)
+			}
+
+			// o lote falha e cada item roda no seu savepoint
+			savePoints := recorder.count("SAVEPOINT goqlite_item")
+			rollbacks := recorder.count("ROLLBACK TO SAVEPOINT goqlite_item")
+			if outer && (savePoints != 3 || rollbacks != 1) {
+				t.Errorf("%d item savepoints and %d rollbacks, want 3 and 1", savePoints, rollbacks)
+			}
+			if !outer && savePoints != 0 {
+				t.Errorf("%d item savepoints without a transaction, want none", savePoints)
+			}
+
+			var count int64
+			db.Model(new(testBulkRow)).Count(&count)
+			if count != 3 {
+				t.Errorf("%d rows, want the 2 valid items kept", count)
+			}
+		})
+	}
+}
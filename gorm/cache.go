@@ -0,0 +1,261 @@
+package fwork_server_gorm
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+
+	fwork_server_orm "github.com/joabssilveira/GoQLite/core"
+	"gorm.io/gorm"
+)
+
+type ETagMode string
+
+const (
+	// ETagNone disables conditional GET (default).
+	ETagNone ETagMode = ""
+	// ETagHash hashes the encoded response. It saves bandwidth, not queries.
+	ETagHash ETagMode = "hash"
+	// ETagUpdatedAt derives the ETag from max(updated_at) + count of the
+	// filtered set, so a 304 is answered before the data query runs.
+	// Falls back to ETagHash when the model has no such column.
+	ETagUpdatedAt ETagMode = "updated_at"
+)
+
+type CacheOptions struct {
+	ETag ETagMode
+
+	// UpdatedAtColumn is the column used by ETagUpdatedAt. Default: updated_at.
+	UpdatedAtColumn string
+
+	// CacheControl, when set, is sent as the Cache-Control header.
+	CacheControl string
+}
+
+func (c CacheOptions) updatedAtColumn() string {
+	if c.UpdatedAtColumn != "" {
+		return c.UpdatedAtColumn
+	}
+	return "updated_at"
+}
+
+type resourceVersion struct {
+	ETag         string
+	LastModified time.Time
+}
+
+// collectionVersion resolves the version of the set matched by payload.Where.
+// Returns nil when the model has no updated_at column. Only the ETag is set:
+// deletes change the set without moving max(updated_at), so a Last-Modified
+// (and If-Modified-Since) would answer stale 304s; the count in the ETag
+// catches them.
+func collectionVersion[T any](db *gorm.DB, payload fwork_server_orm.QueryPayload, column string, rawQuery string) (*resourceVersion, error) {
+	builder := NewGormQueryBuilder(db.Model(new(T)))
+	if builder.Schema == nil || builder.Schema.LookUpField(column) == nil {
+		return nil, nil
+	}
+
+	column = builder.Schema.LookUpField(column).DBName
+
+	builder = ApplyQuery(builder, fwork_server_orm.ExtractCountPayload(payload))
+	if builder.Db.Error != nil {
+		return nil, builder.Db.Error
+	}
+
+	var total int64
+	var maxUpdatedAt dbTime
+
+	err := builder.Db.
+		Select(fmt.Sprintf(
+			"COUNT(*), MAX(%s.%s)",
+			quoteTable(builder.Schema.Table),
+			quoteIdent(column),
+		)).
+		Row().
+		Scan(&total, &maxUpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	var lastModified int64
+	if maxUpdatedAt.Valid {
+		lastModified = maxUpdatedAt.Time.UnixNano()
+	}
+
+	// a query string diferente (filtro, página, select...) gera outra representação
+	return &resourceVersion{
+		ETag: weakETag(fmt.Sprintf("%d|%d|%s", total, lastModified, rawQuery)),
+	}, nil
+}
+
+// recordVersion resolves the version of a single record from its updated_at
+// field. Returns nil when the model has no such field.
+func recordVersion[T any](db *gorm.DB, item *T, column string, rawQuery string) *resourceVersion {
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(item); err != nil || stmt.Schema == nil {
+		return nil
+	}
+
+	field := stmt.Schema.LookUpField(column)
+	if field == nil {
+		return nil
+	}
+
+	value, zero := field.ValueOf(db.Statement.Context, indirect(reflect.ValueOf(item)))
+	if zero {
+		return nil
+	}
+
+	var updatedAt time.Time
+	switch v := value.(type) {
+	case time.Time:
+		updatedAt = v
+	case *time.Time:
+		updatedAt = *v
+	default:
+		return nil
+	}
+
+	return &resourceVersion{
+		ETag:         weakETag(fmt.Sprintf("%d|%s", updatedAt.UnixNano(), rawQuery)),
+		LastModified: updatedAt,
+	}
+}
+
+// dbTime scans aggregated timestamps. Some drivers (e.g. SQLite) return
+// MAX(datetime) as text instead of time.Time.
+type dbTime struct {
+	Time  time.Time
+	Valid bool
+}
+
+func (t *dbTime) Scan(value interface{}) error {
+	var raw string
+
+	switch v := value.(type) {
+	case nil:
+		t.Valid = false
+		return nil
+	case time.Time:
+		t.Time, t.Valid = v, true
+		return nil
+	case []byte:
+		raw = string(v)
+	case string:
+		raw = v
+	default:
+		return fmt.Errorf("dbTime: unsupported Scan type %T", value)
+	}
+
+	for _, layout := range []string{
+		time.RFC3339Nano,
+		"2006-01-02 15:04:05.999999999-07:00",
+		"2006-01-02 15:04:05.999999999",
+	} {
+		if parsed, err := time.Parse(layout, raw); err == nil {
+			t.Time, t.Valid = parsed, true
+			return nil
+		}
+	}
+
+	return fmt.Errorf("dbTime: invalid time %q", raw)
+}
+
+func weakETag(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return `W/"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+func strongETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// checkNotModified writes the validators and answers 304 when the request
+// preconditions match. If-Modified-Since is only considered when
+// If-None-Match is absent (RFC 9110).
+func checkNotModified(w http.ResponseWriter, r *http.Request, v *resourceVersion, cacheControl string) bool {
+	if cacheControl != "" {
+		w.Header().Set("Cache-Control", cacheControl)
+	}
+
+	if v == nil {
+		return false
+	}
+
+	if v.ETag != "" {
+		w.Header().Set("ETag", v.ETag)
+	}
+
+	if !v.LastModified.IsZero() {
+		w.Header().Set("Last-Modified", v.LastModified.UTC().Format(http.TimeFormat))
+	}
+
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		return false
+	}
+
+	if inm := r.Header.Get("If-None-Match"); inm != "" {
+		if etagMatches(inm, v.ETag) {
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+		return false
+	}
+
+	if ims := r.Header.Get("If-Modified-Since"); ims != "" && !v.LastModified.IsZero() {
+		t, err := http.ParseTime(ims)
+		if err == nil && !v.LastModified.Truncate(time.Second).After(t) {
+			w.WriteHeader(http.StatusNotModified)
+			return true
+		}
+	}
+
+	return false
+}
+
+// etagMatches uses the weak comparison, as required for If-None-Match.
+func etagMatches(header string, etag string) bool {
+	if etag == "" {
+		return false
+	}
+
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" {
+			return true
+		}
+		if strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+// writeCachedJSON encodes v and, when hash is set, answers 304 if the body
+// hash matches If-None-Match. Nothing is written when encoding fails.
+func writeCachedJSON(w http.ResponseWriter, r *http.Request, v any, hash bool, cacheControl string) error {
+	var buf bytes.Buffer
+	if err := json.NewEncoder(&buf).Encode(v); err != nil {
+		return err
+	}
+
+	if hash {
+		if checkNotModified(w, r, &resourceVersion{ETag: strongETag(buf.Bytes())}, cacheControl) {
+			return nil
+		}
+	} else if cacheControl != "" {
+		w.Header().Set("Cache-Control", cacheControl)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(buf.Bytes())
+	return nil
+}
@@ -0,0 +1,111 @@
+package fwork_server_gorm
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+
+	"gorm.io/gorm"
+)
+
+// runCountAndFind runs the count and data queries of a list with the given
+// consistency. count may be nil (CountNone / CountHasMore).
+func runCountAndFind(
+	ctx context.Context,
+	db *gorm.DB,
+	consistency ListConsistency,
+	count func(db *gorm.DB) error,
+	find func(db *gorm.DB) error,
+) error {
+
+	// count nil: modo sem contagem
+	if count == nil {
+		return find(db)
+	}
+
+	sequential := func(db *gorm.DB) error {
+		if err := count(db); err != nil {
+			return err
+		}
+		return find(db)
+	}
+
+	switch consistency {
+	case ListSnapshot:
+		return inSnapshot(db, sequential)
+
+	case ListConcurrent:
+		// uma transação usa uma conexão só: não há o que paralelizar
+		if inTransactionAlready(db) {
+			return sequential(db)
+		}
+		return concurrently(ctx, db, count, find)
+	}
+
+	return sequential(db)
+}
+
+// inSnapshot runs fn in a read-only repeatable-read transaction. When db is
+// already in a transaction (e.g. QueryTimeout on Postgres), the isolation of
+// that transaction is changed instead, which Postgres allows before the first
+// query.
+func inSnapshot(db *gorm.DB, fn func(tx *gorm.DB) error) error {
+	if inTransactionAlready(db) {
+		if isPostgres(db) {
+			if err := db.Exec("SET TRANSACTION ISOLATION LEVEL REPEATABLE READ, READ ONLY").Error; err != nil {
+				return err
+			}
+		}
+		return fn(db)
+	}
+
+	return db.Transaction(fn, snapshotTxOptions(db))
+}
+
+func snapshotTxOptions(db *gorm.DB) *sql.TxOptions {
+	// SQLite só tem transações serializáveis
+	if db.Dialector != nil && db.Dialector.Name() == "sqlite" {
+		return nil
+	}
+
+	return &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true}
+}
+
+// concurrently runs the queries on separate connections of the pool. The
+// first error cancels the other query.
+func concurrently(ctx context.Context, db *gorm.DB, queries ...func(db *gorm.DB) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		once     sync.Once
+		firstErr error
+	)
+
+	for _, query := range queries {
+		wg.Add(1)
+		go func(query func(db *gorm.DB) error) {
+			defer wg.Done()
+
+			if err := query(db.WithContext(ctx)); err != nil {
+				once.Do(func() {
+					firstErr = err
+					cancel()
+				})
+			}
+		}(query)
+	}
+
+	wg.Wait()
+	return firstErr
+}
+
+func inTransactionAlready(db *gorm.DB) bool {
+	_, ok := db.Statement.ConnPool.(gorm.TxCommitter)
+	return ok
+}
+
+func isPostgres(db *gorm.DB) bool {
+	return db.Dialector != nil && db.Dialector.Name() == "postgres"
+}
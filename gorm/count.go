@@ -0,0 +1,51 @@
+package fwork_server_gorm
+
+import (
+	"encoding/json"
+
+	"gorm.io/gorm"
+)
+
+// estimateCount returns the row estimate of the Postgres planner for the
+// query (EXPLAIN (FORMAT JSON) → "Plan Rows"). ok is false when the database
+// has no estimate available.
+func estimateCount[T any](query *gorm.DB) (estimate int64, ok bool, err error) {
+	if !isPostgres(query) {
+		return 0, false, nil
+	}
+
+	// gera o SQL (com placeholders) sem executar
+	stmt := query.Session(&gorm.Session{DryRun: true}).Find(&[]T{}).Statement
+	if stmt.Error != nil {
+		return 0, false, stmt.Error
+	}
+
+	var raw string
+	err = query.Session(&gorm.Session{NewDB: true}).
+		Raw("EXPLAIN (FORMAT JSON) "+stmt.SQL.String(), stmt.Vars...).
+		Row().
+		Scan(&raw)
+	if err != nil {
+		return 0, false, err
+	}
+
+	return parsePlanRows(raw)
+}
+
+func parsePlanRows(raw string) (int64, bool, error) {
+	var plans []struct {
+		Plan struct {
+			PlanRows float64 `json:"Plan Rows"`
+		} `json:"Plan"`
+	}
+
+	if err := json.Unmarshal([]byte(raw), &plans); err != nil {
+		return 0, false, err
+	}
+
+	if len(plans) == 0 {
+		return 0, false, nil
+	}
+
+	return int64(plans[0].Plan.PlanRows), true, nil
+}
@@ -0,0 +1,119 @@
+package fwork_server_gorm
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	fwork_server_orm "github.com/joabssilveira/GoQLite/core"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+func countDB(t *testing.T, rows int) (*gorm.DB, *sqlRecorder) {
+	recorder := &sqlRecorder{Interface: logger.Discard}
+	db := sqliteDB(t, &testBulkRow{})
+
+	items := make([]testBulkRow, rows)
+	for i := range items {
+		items[i] = testBulkRow{ID: uint(i + 1), Name: string(rune('a' + i))}
+	}
+	if err := db.Create(&items).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	return db.Session(&gorm.Session{Logger: recorder}), recorder
+}
+
+func TestListHasMoreFetchesOneMoreRow(t *testing.T) {
+	for name, tc := range map[string]struct {
+		skip    int
+		ids     []uint
+		hasNext bool
+	}{
+		"first page": {0, []uint{1, 2}, true},
+		"exact end":  {3, []uint{4, 5}, false},
+		"short page": {4, []uint{5}, false},
+		"past end":   {6, nil, false},
+	} {
+		t.Run(name, func(t *testing.T) {
+			db, recorder := countDB(t, 5)
+
+			limit, skip := 2, tc.skip
+			resp, err := GormGetList[testBulkRow](context.Background(), db, fwork_server_orm.QueryPayload{
+				Order:  []fwork_server_orm.Order{{Field: "id"}},
+				Limit:  &limit,
+				Offset: &skip,
+				Count:  fwork_server_orm.CountHasMore,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var ids []uint
+			for _, row := range resp.Payload {
+				ids = append(ids, row.ID)
+			}
+			if !reflect.DeepEqual(ids, tc.ids) {
+				t.Errorf("ids = %v, want %v", ids, tc.ids)
+			}
+
+			meta := resp.Pagination
+			if meta == nil || meta.HasNextPage == nil || *meta.HasNextPage != tc.hasNext {
+				t.Fatalf("pagination = %+v, want hasNextPage %v", meta, tc.hasNext)
+			}
+			if meta.Count != nil || meta.PageCount != nil || meta.CountMode != fwork_server_orm.CountHasMore {
+				t.Errorf("pagination = %+v, want no count", meta)
+			}
+
+			if len(recorder.sql) != 1 || !strings.Contains(recorder.sql[0], "LIMIT 3") {
+				t.Errorf("queries = %q, want one data query with LIMIT 3 (limit + 1)", recorder.sql)
+			}
+		})
+	}
+}
+
+func TestListEstimatedCountFallsBackToExact(t *testing.T) {
+	db, recorder := countDB(t, 5)
+
+	limit := 2
+	resp, err := GormGetList[testBulkRow](context.Background(), db, fwork_server_orm.QueryPayload{
+		Where: mustFilter(t, `{"id": {"$gt": 1}}`),
+		Limit: &limit,
+		Count: fwork_server_orm.CountEstimated,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// SQLite não tem estimativa: a contagem é exata e o modo diz isso
+	meta := resp.Pagination
+	if meta == nil || meta.Count == nil || *meta.Count != 4 || meta.CountMode != fwork_server_orm.CountExact {
+		t.Errorf("pagination = %+v, want an exact count of 4", meta)
+	}
+	if n := recorder.count("EXPLAIN"); n != 0 {
+		t.Errorf("%d EXPLAIN queries on SQLite, want none", n)
+	}
+}
+
+func TestParsePlanRows(t *testing.T) {
+	for name, tc := range map[string]struct {
+		raw  string
+		want int64
+		ok   bool
+		err  bool
+	}{
+		"plan":     {`[{"Plan": {"Node Type": "Seq Scan", "Plan Rows": 1520, "Total Cost": 12.5}}]`, 1520, true, false},
+		"fraction": {`[{"Plan": {"Plan Rows": 2.7}}]`, 2, true, false},
+		"no plan":  {`[]`, 0, false, false},
+		"invalid":  {`QUERY PLAN`, 0, false, true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			got, ok, err := parsePlanRows(tc.raw)
+			if got != tc.want || ok != tc.ok || (err != nil) != tc.err {
+				t.Errorf("parsePlanRows = %d, %v, %v; want %d, %v, error %v", got, ok, err, tc.want, tc.ok, tc.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,48 @@
+package fwork_server_gorm
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// sqliteDB opens a fresh SQLite database (pure Go driver) with the tables of
+// models, for the tests that run the queries.
+func sqliteDB(tb testing.TB, models ...any) *gorm.DB {
+	tb.Helper()
+
+	db, err := gorm.Open(sqlite.Open(filepath.Join(tb.TempDir(), "test.db")), &gorm.Config{
+		Logger: logger.Discard,
+	})
+	if err != nil {
+		tb.Fatal(err)
+	}
+	if err := db.AutoMigrate(models...); err != nil {
+		tb.Fatal(err)
+	}
+	return db
+}
+
+// serve runs handler on a request and returns the recorder.
+func serve(handler http.Handler, method, target, body string, header ...string) *httptest.ResponseRecorder {
+	var reader io.Reader
+	if body != "" {
+		reader = strings.NewReader(body)
+	}
+
+	req := httptest.NewRequest(method, target, reader)
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+	return w
+}
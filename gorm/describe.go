@@ -0,0 +1,230 @@
+package fwork_server_gorm
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"time"
+
+	fwork_server_orm "github.com/joabssilveira/GoQLite/core"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// ModelDescription is what the API documents (OpenAPI, metadata) know about
+// a model, taken from its GORM schema.
+type ModelDescription struct {
+	Model     string                `json:"model"` // nome do tipo Go
+	Table     string                `json:"table"`
+	Key       string                `json:"key,omitempty"` // coluna da chave das rotas /{id}
+	Fields    []FieldDescription    `json:"fields"`
+	Relations []RelationDescription `json:"relations,omitempty"`
+
+	schema *schema.Schema
+}
+
+// FieldDescription is a column of the model. Name is the JSON name of the
+// payloads; the where / sort / select params accept Name or Column.
+// Write-only fields (gorm:"->:false") are not Filterable, Sortable or
+// Selectable, as the queries refuse them.
+type FieldDescription struct {
+	Name       string                     `json:"name"`
+	Column     string                     `json:"column"`
+	Type       fwork_server_orm.FieldKind `json:"type"`
+	Nullable   bool                       `json:"nullable"`
+	PrimaryKey bool                       `json:"primaryKey,omitempty"`
+	ReadOnly   bool                       `json:"readOnly,omitempty"`
+	Enum       []any                      `json:"enum,omitempty"` // fwork_server_orm.Enum
+	Filterable bool                       `json:"filterable"`
+	Sortable   bool                       `json:"sortable"` // JSON / bytes fields are not
+	Selectable bool                       `json:"selectable"`
+	Operators  []string                   `json:"operators"`
+}
+
+// RelationDescription is a relation of the model. Name is the path used by
+// nested and by relation filters ("course.title"); Field is the JSON name in
+// the payloads ("" when not serialized). Resource is the registered resource
+// of the target model, if any (Registry.Meta).
+type RelationDescription struct {
+	Name     string `json:"name"`
+	Field    string `json:"field,omitempty"`
+	Type     string `json:"type"` // has_one, has_many, belongs_to, many_to_many
+	Model    string `json:"model"`
+	Table    string `json:"table"`
+	Many     bool   `json:"many"`
+	Resource string `json:"resource,omitempty"`
+
+	schema *schema.Schema
+}
+
+// DescribeModel describes T from its GORM schema.
+func DescribeModel[T any](db *gorm.DB) (*ModelDescription, error) {
+	s := schemaOf(db, new(T))
+	if s == nil {
+		return nil, errors.New("describe: cannot parse model")
+	}
+	return describeSchema(s), nil
+}
+
+// Describe describes the model of the resource, with KeyName as the key.
+func (res *GormResource[T]) Describe() (*ModelDescription, error) {
+	desc, err := DescribeModel[T](res.Db)
+	if err != nil {
+		return nil, err
+	}
+
+	if field := lookUpField(desc.schema, res.KeyName); field != nil {
+		desc.Key = field.DBName
+	} else {
+		desc.Key = res.KeyName
+	}
+
+	return desc, nil
+}
+
+func describeSchema(s *schema.Schema) *ModelDescription {
+	desc := &ModelDescription{
+		Model:  s.Name,
+		Table:  s.Table,
+		schema: s,
+	}
+
+	if s.PrioritizedPrimaryField != nil {
+		desc.Key = s.PrioritizedPrimaryField.DBName
+	}
+
+	for _, field := range s.Fields {
+		name := jsonFieldName(field)
+		if field.DBName == "" || name == "" {
+			continue
+		}
+
+		kind := fieldKind(field)
+		queryable := queryableField(field)
+		desc.Fields = append(desc.Fields, FieldDescription{
+			Name:       name,
+			Column:     field.DBName,
+			Type:       kind,
+			Nullable:   fieldNullable(field),
+			PrimaryKey: field.PrimaryKey,
+			ReadOnly:   !field.Creatable && !field.Updatable,
+			Enum:       enumValues(field),
+			Filterable: queryable,
+			Sortable:   queryable && kind != fwork_server_orm.KindJSON && kind != fwork_server_orm.KindBytes,
+			Selectable: queryable,
+			Operators:  fwork_server_orm.OperatorsFor(kind),
+		})
+	}
+
+	// mesma ordem dos campos do struct
+	for _, field := range s.Fields {
+		rel, ok := s.Relationships.Relations[field.Name]
+		if !ok || rel.Field != field {
+			continue
+		}
+
+		desc.Relations = append(desc.Relations, RelationDescription{
+			Name:   relationPathName(rel),
+			Field:  jsonFieldName(field),
+			Type:   string(rel.Type),
+			Model:  rel.FieldSchema.Name,
+			Table:  rel.FieldSchema.Table,
+			Many:   rel.Type == schema.HasMany || rel.Type == schema.Many2Many,
+			schema: rel.FieldSchema,
+		})
+	}
+
+	return desc
+}
+
+// relationPathName is the name of rel in nested / filter paths: snake case,
+// as long as SnakeToCamel gives the relation back.
+func relationPathName(rel *schema.Relationship) string {
+	snake := schema.NamingStrategy{}.ColumnName("", rel.Name)
+	if fwork_server_orm.SnakeToCamel(snake) == rel.Name {
+		return snake
+	}
+	return rel.Name
+}
+
+func fieldKind(field *schema.Field) fwork_server_orm.FieldKind {
+	// serializer:json grava o valor como documento
+	if field.Serializer != nil {
+		return fwork_server_orm.KindJSON
+	}
+
+	switch field.DataType {
+	case schema.Bool:
+		return fwork_server_orm.KindBoolean
+	case schema.Int, schema.Uint:
+		return fwork_server_orm.KindInteger
+	case schema.Float:
+		return fwork_server_orm.KindNumber
+	case schema.String:
+		return fwork_server_orm.KindString
+	case schema.Time:
+		return fwork_server_orm.KindTime
+	case schema.Bytes:
+		return fwork_server_orm.KindBytes
+	}
+
+	// tipos do banco (type:...) e tipos customizados
+	dataType := strings.ToLower(string(field.DataType))
+	switch {
+	case dataType == "date":
+		return fwork_server_orm.KindDate
+	case strings.HasPrefix(dataType, "json"):
+		return fwork_server_orm.KindJSON
+	case strings.HasPrefix(dataType, "timestamp"), strings.HasPrefix(dataType, "datetime"):
+		return fwork_server_orm.KindTime
+	case strings.HasPrefix(dataType, "bool"):
+		return fwork_server_orm.KindBoolean
+	case strings.Contains(dataType, "int"), dataType == "serial", dataType == "bigserial":
+		return fwork_server_orm.KindInteger
+	case strings.HasPrefix(dataType, "numeric"), strings.HasPrefix(dataType, "decimal"),
+		strings.HasPrefix(dataType, "float"), strings.HasPrefix(dataType, "double"), dataType == "real":
+		return fwork_server_orm.KindNumber
+	case dataType == "bytea", strings.HasSuffix(dataType, "blob"):
+		return fwork_server_orm.KindBytes
+	}
+
+	t := field.IndirectFieldType
+	switch t.Kind() {
+	case reflect.Struct:
+		if t.ConvertibleTo(reflect.TypeOf(time.Time{})) {
+			return fwork_server_orm.KindTime
+		}
+		return fwork_server_orm.KindJSON
+	case reflect.Map, reflect.Slice, reflect.Array:
+		return fwork_server_orm.KindJSON
+	}
+
+	return fwork_server_orm.KindString
+}
+
+// enumValues of fields whose type (or pointer) implements fwork_server_orm.Enum.
+func enumValues(field *schema.Field) []any {
+	if enum, ok := reflect.New(field.IndirectFieldType).Interface().(fwork_server_orm.Enum); ok {
+		return enum.EnumValues()
+	}
+	if enum, ok := reflect.New(field.IndirectFieldType).Elem().Interface().(fwork_server_orm.Enum); ok {
+		return enum.EnumValues()
+	}
+	return nil
+}
+
+// fieldNullable: pointers and the sql.Null* / gorm.DeletedAt style structs.
+func fieldNullable(field *schema.Field) bool {
+	t := field.FieldType
+	if t.Kind() == reflect.Ptr {
+		return true
+	}
+
+	if t.Kind() == reflect.Struct {
+		if valid, ok := t.FieldByName("Valid"); ok && valid.Type.Kind() == reflect.Bool {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,25 @@
+package fwork_server_gorm
+
+import "testing"
+
+func TestDescribeModelQueryableFields(t *testing.T) {
+	desc, err := DescribeModel[testStudent](dryRunDB(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fields := map[string]FieldDescription{}
+	for _, f := range desc.Fields {
+		fields[f.Column] = f
+	}
+
+	if f := fields["age"]; !f.Filterable || !f.Sortable || !f.Selectable {
+		t.Errorf("age = %+v, want filterable, sortable and selectable", f)
+	}
+	if f := fields["metadata"]; !f.Filterable || f.Sortable {
+		t.Errorf("metadata = %+v, want filterable and not sortable", f)
+	}
+	if f, ok := fields["password"]; !ok || f.Filterable || f.Sortable || f.Selectable {
+		t.Errorf("password = %+v, want a write-only field", f)
+	}
+}
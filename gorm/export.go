@@ -0,0 +1,440 @@
+package fwork_server_gorm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	fwork_server_orm "github.com/joabssilveira/GoQLite/core"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+const (
+	DefaultExportMaxRows   = 10000
+	defaultExportBatchSize = 500
+)
+
+type ExportOptions[T any] struct {
+	// MaxRows refuses exports with more rows (bad query). Default:
+	// DefaultExportMaxRows; negative means no limit.
+	MaxRows int
+
+	// BatchSize is the number of rows loaded per query. Default: 500.
+	BatchSize int
+
+	// FileName of the Content-Disposition, without extension. Default: the
+	// table name.
+	FileName string
+
+	// Labels replaces the header of columns, by select path / JSON name
+	// ("course.title": "Course").
+	Labels map[string]string
+
+	// Timeout limits the whole export (the handlers do not use QueryTimeout
+	// here, an export is expected to be slow).
+	Timeout time.Duration
+
+	// Progress is called after each batch with the rows written so far.
+	Progress func(rows int)
+
+	Hooks *Hooks[T]
+}
+
+func (o ExportOptions[T]) maxRows() int {
+	if o.MaxRows == 0 {
+		return DefaultExportMaxRows
+	}
+	return o.MaxRows
+}
+
+func (o ExportOptions[T]) batchSize() int {
+	if o.BatchSize <= 0 {
+		return defaultExportBatchSize
+	}
+	return o.BatchSize
+}
+
+// GormExport streams every row matched by payload (pagination is ignored) to
+// out in the given format. Columns follow payload.Select, which can point to
+// relation fields ("course.title") or JSONB paths; without select every
+// column of the model is exported (plus the nested relations).
+//
+// Rows are loaded in batches, in a snapshot transaction, and go through the
+// AfterFind hooks. Returns the number of exported rows.
+func GormExport[T any](
+	ctx context.Context,
+	db *gorm.DB,
+	payload fwork_server_orm.QueryPayload,
+	out io.Writer,
+	format ExportFormat,
+	opts ...ExportOptions[T],
+) (int, error) {
+
+	db = db.WithContext(ctx)
+
+	var opt ExportOptions[T]
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+
+	if err := opt.Hooks.beforeQuery(ctx, &payload); err != nil {
+		return 0, err
+	}
+
+	s := schemaOf(db, new(T))
+	if s == nil {
+		return 0, fmt.Errorf("export: cannot parse model %T", new(T))
+	}
+
+	columns, err := exportColumns(s, payload)
+	if err != nil {
+		return 0, err
+	}
+
+	for i := range columns {
+		columns[i].Label = opt.Labels[columns[i].Name]
+	}
+
+	// sem paginação; o select vale só para as colunas do arquivo
+	query := payload
+	query.Select = nil
+	query.Limit = nil
+	query.Offset = nil
+	query.Page = nil
+
+	writer := format.NewWriter(out)
+	written := 0
+
+	err = inSnapshot(db, func(tx *gorm.DB) error {
+		base := ApplyQuery(NewGormQueryBuilder(tx.Model(new(T))), query).Db
+		if base.Error != nil {
+			return base.Error
+		}
+
+		for _, column := range columns {
+			if column.preload != "" {
+				if _, ok := base.Statement.Preloads[column.preload]; !ok {
+					base = base.Preload(column.preload)
+				}
+			}
+		}
+
+		if limit := opt.maxRows(); limit > 0 {
+			if err := checkExportSize(tx, base, s, limit); err != nil {
+				return err
+			}
+		}
+
+		if err := writer.WriteHeader(columns); err != nil {
+			return err
+		}
+
+		writeBatch := func(batch []T) error {
+			items, err := opt.Hooks.afterFind(ctx, batch)
+			if err != nil {
+				return err
+			}
+
+			for i := range items {
+				if limit := opt.maxRows(); limit > 0 && written >= limit {
+					break
+				}
+
+				values, err := exportValues(items[i], columns)
+				if err != nil {
+					return err
+				}
+				if err := writer.WriteRow(values); err != nil {
+					return err
+				}
+				written++
+			}
+
+			if err := writer.Flush(); err != nil {
+				return err
+			}
+			if f, ok := out.(http.Flusher); ok {
+				f.Flush()
+			}
+			if opt.Progress != nil {
+				opt.Progress(written)
+			}
+			return nil
+		}
+
+		return exportBatches(base, s, payload.Order, opt.batchSize(), writeBatch)
+	})
+	if err != nil {
+		return written, err
+	}
+
+	return written, writer.Close()
+}
+
+// exportBatches loads the rows in batches: by primary key (FindInBatches)
+// when there is no sort, by offset otherwise, with the primary key as tie
+// breaker.
+func exportBatches[T any](base *gorm.DB, s *schema.Schema, order []fwork_server_orm.Order, size int, fn func(batch []T) error) error {
+	if len(order) == 0 {
+		var batch []T
+		return base.FindInBatches(&batch, size, func(tx *gorm.DB, _ int) error {
+			return fn(batch)
+		}).Error
+	}
+
+	if s.PrioritizedPrimaryField != nil {
+		base = base.Order(quoteTable(s.Table) + "." + quoteIdent(s.PrioritizedPrimaryField.DBName))
+	}
+	base = base.Session(&gorm.Session{})
+
+	for offset := 0; ; offset += size {
+		var batch []T
+		if err := base.Limit(size).Offset(offset).Find(&batch).Error; err != nil {
+			return err
+		}
+
+		if len(batch) > 0 {
+			if err := fn(batch); err != nil {
+				return err
+			}
+		}
+
+		if len(batch) < size {
+			return nil
+		}
+	}
+}
+
+// checkExportSize fails when the query matches more than limit rows. Only
+// limit+1 keys are read.
+func checkExportSize(tx *gorm.DB, base *gorm.DB, s *schema.Schema, limit int) error {
+	key := "1"
+	if s.PrioritizedPrimaryField != nil {
+		key = quoteTable(s.Table) + "." + quoteIdent(s.PrioritizedPrimaryField.DBName)
+	}
+
+	sub := base.Session(&gorm.Session{}).Select(key).Limit(limit + 1)
+
+	var total int64
+	err := tx.Session(&gorm.Session{NewDB: true}).
+		Table("(?) AS export_rows", sub).
+		Count(&total).Error
+	if err != nil {
+		return err
+	}
+
+	if total > int64(limit) {
+		return fwork_server_orm.NewError(
+			fwork_server_orm.ErrorBadQuery,
+			fmt.Sprintf("export exceeds %d rows, narrow the filter", limit),
+			nil,
+		)
+	}
+	return nil
+}
+
+// exportColumns resolves the columns of payload.Select (or the default ones)
+// to JSON paths of the encoded row.
+func exportColumns(s *schema.Schema, payload fwork_server_orm.QueryPayload) ([]ExportColumn, error) {
+	if len(payload.Select) == 0 {
+		return defaultExportColumns(s, payload.Nested), nil
+	}
+
+	columns := make([]ExportColumn, 0, len(payload.Select))
+	var unknown []string
+
+	for _, path := range payload.Select {
+		column, ok := resolveExportColumn(s, path)
+		if !ok {
+			unknown = append(unknown, path)
+			continue
+		}
+		columns = append(columns, column)
+	}
+
+	if len(unknown) > 0 {
+		return nil, fwork_server_orm.NewError(
+			fwork_server_orm.ErrorBadQuery,
+			"unknown fields: "+strings.Join(unknown, ", "),
+			nil,
+		)
+	}
+
+	return columns, nil
+}
+
+func resolveExportColumn(s *schema.Schema, path string) (ExportColumn, bool) {
+	column := ExportColumn{Name: path}
+
+	current := s
+	var preload []string
+
+	parts := strings.Split(path, ".")
+	for i, part := range parts {
+		// relação: entra no schema filho
+		if rel, ok := current.Relationships.Relations[fwork_server_orm.SnakeToCamel(part)]; ok {
+			column.keys = append(column.keys, jsonFieldName(rel.Field))
+			preload = append(preload, rel.Name)
+			current = rel.FieldSchema
+			continue
+		}
+
+		// como no select: só colunas que o modelo lê
+		field := lookUpField(current, part)
+		if !queryableField(field) || jsonFieldName(field) == "" {
+			return column, false
+		}
+
+		column.keys = append(column.keys, jsonFieldName(field))
+
+		if i == len(parts)-1 {
+			column.Type = field.FieldType
+			column.DataType = field.DataType
+		} else {
+			// JSONB: o resto é caminho dentro do documento
+			column.keys = append(column.keys, parts[i+1:]...)
+		}
+		break
+	}
+
+	column.preload = strings.Join(preload, ".")
+	return column, true
+}
+
+func defaultExportColumns(s *schema.Schema, nested string) []ExportColumn {
+	var columns []ExportColumn
+
+	for _, field := range s.Fields {
+		name := jsonFieldName(field)
+		if !queryableField(field) || name == "" {
+			continue
+		}
+		columns = append(columns, ExportColumn{
+			Name:     name,
+			Type:     field.FieldType,
+			DataType: field.DataType,
+			keys:     []string{name},
+		})
+	}
+
+	if nested != "" {
+		for _, node := range fwork_server_orm.ParseNestedTree(nested) {
+			rel, ok := s.Relationships.Relations[toGormRelationPath(node.Name)]
+			if !ok {
+				continue
+			}
+			name := jsonFieldName(rel.Field)
+			columns = append(columns, ExportColumn{Name: name, keys: []string{name}})
+		}
+	}
+
+	return columns
+}
+
+// exportValues reads the columns from the JSON of item, so the values match
+// the regular JSON response (custom MarshalJSON, JSONB...).
+func exportValues[T any](item T, columns []ExportColumn) ([]any, error) {
+	raw, err := json.Marshal(item)
+	if err != nil {
+		return nil, err
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+
+	var row any
+	if err := decoder.Decode(&row); err != nil {
+		return nil, err
+	}
+
+	values := make([]any, len(columns))
+	for i, column := range columns {
+		values[i] = jsonPathValue(row, column.keys)
+	}
+	return values, nil
+}
+
+func jsonPathValue(value any, keys []string) any {
+	for _, key := range keys {
+		switch v := value.(type) {
+		case map[string]any:
+			value = v[key]
+		case []any:
+			var index int
+			if _, err := fmt.Sscanf(key, "%d", &index); err != nil || index < 0 || index >= len(v) {
+				return nil
+			}
+			value = v[index]
+		default:
+			return nil
+		}
+	}
+	return value
+}
+
+// exportResponse sets the download headers on the first write, so errors
+// before any row can still be answered as problem+json.
+type exportResponse struct {
+	w       http.ResponseWriter
+	format  ExportFormat
+	file    string
+	started bool
+}
+
+func (e *exportResponse) Write(p []byte) (int, error) {
+	if !e.started {
+		e.started = true
+		e.w.Header().Set("Content-Type", e.format.ContentType)
+		e.w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s.%s"`, e.file, e.format.Extension))
+		e.w.WriteHeader(http.StatusOK)
+	}
+	return e.w.Write(p)
+}
+
+func (e *exportResponse) Flush() {
+	if f, ok := e.w.(http.Flusher); ok && e.started {
+		f.Flush()
+	}
+}
+
+// writeExport answers a list request with a file. Once rows were sent an
+// error can only abort the connection.
+func (res *GormResource[T]) writeExport(w http.ResponseWriter, r *http.Request, payload fwork_server_orm.QueryPayload, format ExportFormat) {
+	opts := res.Export
+	if opts.Hooks == nil {
+		opts.Hooks = res.Hooks
+	}
+	// BeforeQuery já rodou no handler
+	opts.Hooks = opts.Hooks.withoutBeforeQuery()
+
+	file := opts.FileName
+	if file == "" {
+		if s := schemaOf(res.Db, new(T)); s != nil {
+			file = s.Table
+		} else {
+			file = "export"
+		}
+	}
+
+	out := &exportResponse{w: w, format: format, file: file}
+
+	err := withQueryTimeout(r.Context(), res.Db, opts.Timeout, func(ctx context.Context, db *gorm.DB) error {
+		_, err := GormExport(ctx, db, payload, out, format, opts)
+		return err
+	})
+	if err == nil {
+		return
+	}
+
+	if out.started {
+		panic(http.ErrAbortHandler)
+	}
+
+	res.writeError(w, r, err)
+}
@@ -0,0 +1,280 @@
+package fwork_server_gorm
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+
+	"gorm.io/gorm/schema"
+)
+
+// ExportColumn is a column of an export: a field of the model, a relation
+// field ("course.title") or a JSONB path.
+type ExportColumn struct {
+	Name string
+	// Label is the header of the column, when different from Name
+	// (ExportOptions.Labels).
+	Label string
+
+	// Type and DataType come from the GORM schema field. Empty for JSONB
+	// paths and relations.
+	Type     reflect.Type
+	DataType schema.DataType
+
+	keys    []string // caminho no JSON da linha
+	preload string   // relação a carregar ("Course.Group"), vazio na raiz
+}
+
+// Header is the label of the column, or its name.
+func (c ExportColumn) Header() string {
+	if c.Label != "" {
+		return c.Label
+	}
+	return c.Name
+}
+
+// RowWriter writes the rows of an export in one format. Values follow the
+// column order and come from the JSON of each row: string, json.Number,
+// bool, nil, or map / slice for objects.
+type RowWriter interface {
+	WriteHeader(columns []ExportColumn) error
+	WriteRow(values []any) error
+	// Flush sends the buffered rows (called after each batch).
+	Flush() error
+	// Close ends the file.
+	Close() error
+}
+
+type ExportFormat struct {
+	// Name is the value of the format query param.
+	Name        string
+	ContentType string
+	Extension   string
+	NewWriter   func(w io.Writer) RowWriter
+}
+
+var (
+	// ExportCSV prefixes text cells that a spreadsheet would read as a
+	// formula (=, +, -, @, tab, CR) with a quote.
+	ExportCSV = ExportFormat{
+		Name:        "csv",
+		ContentType: "text/csv",
+		Extension:   "csv",
+		NewWriter:   func(w io.Writer) RowWriter { return &csvRowWriter{w: csv.NewWriter(w), escapeFormulas: true} },
+	}
+
+	// ExportCSVRaw keeps the cells as they are. Register it
+	// (RegisterExportFormat) to replace ExportCSV when the files are not
+	// opened in spreadsheets.
+	ExportCSVRaw = ExportFormat{
+		Name:        "csv",
+		ContentType: "text/csv",
+		Extension:   "csv",
+		NewWriter:   func(w io.Writer) RowWriter { return &csvRowWriter{w: csv.NewWriter(w)} },
+	}
+
+	ExportNDJSON = ExportFormat{
+		Name:        "ndjson",
+		ContentType: "application/x-ndjson",
+		Extension:   "ndjson",
+		NewWriter:   func(w io.Writer) RowWriter { return &ndjsonRowWriter{w: bufio.NewWriter(w)} },
+	}
+)
+
+var (
+	exportFormatsMu sync.RWMutex
+	exportFormats   = map[string]ExportFormat{
+		ExportCSV.Name:    ExportCSV,
+		ExportNDJSON.Name: ExportNDJSON,
+		ExportXLSX.Name:   ExportXLSX,
+	}
+)
+
+// RegisterExportFormat makes a format available to the list handlers
+// (?format=<name> or Accept: <content type>).
+func RegisterExportFormat(format ExportFormat) {
+	exportFormatsMu.Lock()
+	defer exportFormatsMu.Unlock()
+
+	exportFormats[format.Name] = format
+}
+
+func LookupExportFormat(name string) (ExportFormat, bool) {
+	exportFormatsMu.RLock()
+	defer exportFormatsMu.RUnlock()
+
+	format, ok := exportFormats[name]
+	return format, ok
+}
+
+// negotiateExportFormat resolves the format of a list request: the format
+// param first, then the Accept header (in the client's order). Nil means the
+// regular JSON response.
+func negotiateExportFormat(r *http.Request) (*ExportFormat, error) {
+	if name := r.URL.Query().Get("format"); name != "" {
+		if name == "json" {
+			return nil, nil
+		}
+
+		format, ok := LookupExportFormat(name)
+		if !ok {
+			return nil, badQuery("unsupported format: %s", name)
+		}
+		return &format, nil
+	}
+
+	for _, accepted := range strings.Split(r.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accepted))
+		if err != nil {
+			continue
+		}
+
+		switch mediaType {
+		case "application/json", "application/*", "*/*":
+			return nil, nil
+		}
+
+		exportFormatsMu.RLock()
+		for _, format := range exportFormats {
+			if format.ContentType == mediaType {
+				exportFormatsMu.RUnlock()
+				return &format, nil
+			}
+		}
+		exportFormatsMu.RUnlock()
+	}
+
+	return nil, nil
+}
+
+// =========================
+// CSV
+// =========================
+
+type csvRowWriter struct {
+	w              *csv.Writer
+	record         []string
+	escapeFormulas bool
+}
+
+func (c *csvRowWriter) WriteHeader(columns []ExportColumn) error {
+	header := make([]string, len(columns))
+	for i, column := range columns {
+		header[i] = c.text(column.Header())
+	}
+	return c.w.Write(header)
+}
+
+func (c *csvRowWriter) WriteRow(values []any) error {
+	c.record = c.record[:0]
+	for _, value := range values {
+		cell := ExportCellString(value)
+		// só texto: números negativos (json.Number) ficam como estão
+		if _, ok := value.(string); ok {
+			cell = c.text(cell)
+		}
+		c.record = append(c.record, cell)
+	}
+	return c.w.Write(c.record)
+}
+
+// text neutralizes CSV injection: a leading quote makes spreadsheets show
+// the cell as text instead of evaluating it.
+func (c *csvRowWriter) text(s string) string {
+	if !c.escapeFormulas || s == "" {
+		return s
+	}
+
+	switch s[0] {
+	case '=', '+', '-', '@', '\t', '\r':
+		return "'" + s
+	}
+	return s
+}
+
+func (c *csvRowWriter) Flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+func (c *csvRowWriter) Close() error {
+	return c.Flush()
+}
+
+// ExportCellString formats an export value as text: objects and arrays as
+// JSON, nil as empty.
+func ExportCellString(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case json.Number:
+		return v.String()
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(raw)
+	}
+}
+
+// =========================
+// NDJSON
+// =========================
+
+// ndjsonRowWriter writes one object per line, keys in column order.
+type ndjsonRowWriter struct {
+	w    *bufio.Writer
+	keys [][]byte
+}
+
+func (n *ndjsonRowWriter) WriteHeader(columns []ExportColumn) error {
+	n.keys = make([][]byte, len(columns))
+	for i, column := range columns {
+		key, err := json.Marshal(column.Name)
+		if err != nil {
+			return err
+		}
+		n.keys[i] = key
+	}
+	return nil
+}
+
+func (n *ndjsonRowWriter) WriteRow(values []any) error {
+	n.w.WriteByte('{')
+	for i, value := range values {
+		if i > 0 {
+			n.w.WriteByte(',')
+		}
+		n.w.Write(n.keys[i])
+		n.w.WriteByte(':')
+
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return err
+		}
+		n.w.Write(raw)
+	}
+	n.w.WriteString("}\n")
+	return nil
+}
+
+func (n *ndjsonRowWriter) Flush() error {
+	return n.w.Flush()
+}
+
+func (n *ndjsonRowWriter) Close() error {
+	return n.Flush()
+}
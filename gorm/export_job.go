@@ -0,0 +1,559 @@
+package fwork_server_gorm
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	fwork_server_orm "github.com/joabssilveira/GoQLite/core"
+	"gorm.io/gorm"
+)
+
+type ExportJobStatus string
+
+const (
+	ExportJobPending ExportJobStatus = "pending"
+	ExportJobRunning ExportJobStatus = "running"
+	ExportJobDone    ExportJobStatus = "done"
+	ExportJobFailed  ExportJobStatus = "failed"
+)
+
+const (
+	defaultExportJobsRunning   = 2
+	defaultExportJobsRetention = 24 * time.Hour
+	defaultExportJobsStale     = time.Hour
+	// intervalo mínimo entre gravações do progresso no store
+	exportJobProgressInterval = time.Second
+	// intervalo máximo entre varreduras do store
+	exportJobSweepInterval = 10 * time.Minute
+)
+
+var ErrJobNotFound = errors.New("export job not found")
+
+// ExportJob is a background export. Rows is updated while the job runs;
+// Total is the row count when it started.
+type ExportJob struct {
+	ID         string                        `json:"id" gorm:"primaryKey;size:32"`
+	Resource   string                        `json:"resource" gorm:"size:255"`
+	Format     string                        `json:"format" gorm:"size:32"`
+	Query      fwork_server_orm.QueryPayload `json:"query" gorm:"serializer:json;type:text"`
+	Status     ExportJobStatus               `json:"status" gorm:"size:16"`
+	Rows       int64                         `json:"rows"`
+	Total      int64                         `json:"total"`
+	Error      string                        `json:"error,omitempty"`
+	File       string                        `json:"-" gorm:"size:255"` // nome no ExportStorage
+	FileName   string                        `json:"fileName" gorm:"size:255"`
+	CreatedAt  time.Time                     `json:"createdAt"`
+	StartedAt  *time.Time                    `json:"startedAt,omitempty"`
+	FinishedAt *time.Time                    `json:"finishedAt,omitempty"`
+	// UpdatedAt is the last write of the job (status, progress).
+	UpdatedAt time.Time `json:"updatedAt" gorm:"index"`
+	// Owner (GormResource.JobOwner) and Scope (JSON of the resource Scope)
+	// of the request that started the job; only the same requester sees it.
+	Owner string `json:"-" gorm:"size:255"`
+	Scope string `json:"-" gorm:"type:text"`
+}
+
+func (ExportJob) TableName() string {
+	return "goqlite_export_jobs"
+}
+
+// JobStore persists the export jobs. Get returns ErrJobNotFound for unknown
+// ids. Create and Update set UpdatedAt; List returns the jobs in one of
+// statuses last written before updatedBefore (ExportJobs.Sweep).
+type JobStore interface {
+	Create(ctx context.Context, job *ExportJob) error
+	Update(ctx context.Context, job *ExportJob) error
+	Get(ctx context.Context, id string) (*ExportJob, error)
+	Delete(ctx context.Context, id string) error
+	List(ctx context.Context, updatedBefore time.Time, statuses ...ExportJobStatus) ([]ExportJob, error)
+}
+
+// =========================
+// MEMORY
+// =========================
+
+// MemoryJobStore keeps the jobs in the process (lost on restart).
+type MemoryJobStore struct {
+	mu   sync.RWMutex
+	jobs map[string]ExportJob
+}
+
+func NewMemoryJobStore() *MemoryJobStore {
+	return &MemoryJobStore{jobs: map[string]ExportJob{}}
+}
+
+func (s *MemoryJobStore) Create(ctx context.Context, job *ExportJob) error {
+	return s.Update(ctx, job)
+}
+
+func (s *MemoryJobStore) Update(ctx context.Context, job *ExportJob) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job.UpdatedAt = time.Now()
+	s.jobs[job.ID] = *job
+	return nil
+}
+
+func (s *MemoryJobStore) Get(ctx context.Context, id string) (*ExportJob, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return nil, ErrJobNotFound
+	}
+	return &job, nil
+}
+
+func (s *MemoryJobStore) Delete(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.jobs, id)
+	return nil
+}
+
+func (s *MemoryJobStore) List(ctx context.Context, updatedBefore time.Time, statuses ...ExportJobStatus) ([]ExportJob, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var jobs []ExportJob
+	for _, job := range s.jobs {
+		if job.UpdatedAt.Before(updatedBefore) && containsStatus(statuses, job.Status) {
+			jobs = append(jobs, job)
+		}
+	}
+	return jobs, nil
+}
+
+func containsStatus(statuses []ExportJobStatus, status ExportJobStatus) bool {
+	for _, s := range statuses {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// =========================
+// GORM
+// =========================
+
+// GormJobStore keeps the jobs in the goqlite_export_jobs table, so the
+// status survives restarts and is shared between instances (the files must
+// then be in a shared ExportStorage).
+type GormJobStore struct {
+	Db *gorm.DB
+}
+
+// NewGormJobStore migrates the jobs table.
+func NewGormJobStore(db *gorm.DB) (*GormJobStore, error) {
+	if err := db.AutoMigrate(&ExportJob{}); err != nil {
+		return nil, err
+	}
+	return &GormJobStore{Db: db}, nil
+}
+
+func (s *GormJobStore) Create(ctx context.Context, job *ExportJob) error {
+	return s.Db.WithContext(ctx).Create(job).Error
+}
+
+func (s *GormJobStore) Update(ctx context.Context, job *ExportJob) error {
+	return s.Db.WithContext(ctx).Save(job).Error
+}
+
+func (s *GormJobStore) Get(ctx context.Context, id string) (*ExportJob, error) {
+	var job ExportJob
+	err := s.Db.WithContext(ctx).Where("id = ?", id).Take(&job).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, ErrJobNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &job, nil
+}
+
+func (s *GormJobStore) Delete(ctx context.Context, id string) error {
+	return s.Db.WithContext(ctx).Where("id = ?", id).Delete(&ExportJob{}).Error
+}
+
+func (s *GormJobStore) List(ctx context.Context, updatedBefore time.Time, statuses ...ExportJobStatus) ([]ExportJob, error) {
+	var jobs []ExportJob
+	err := s.Db.WithContext(ctx).
+		Where("status IN ? AND updated_at < ?", statuses, updatedBefore).
+		Find(&jobs).Error
+	return jobs, err
+}
+
+// =========================
+// RUNNER
+// =========================
+
+// ExportJobs runs exports in background goroutines of the process, writing
+// the files to Storage and the status to Store. Can be shared between
+// resources (GormResource.Jobs).
+type ExportJobs struct {
+	Store   JobStore
+	Storage ExportStorage
+
+	// MaxRunning limits the exports running at once; the others wait as
+	// pending. Default: 2.
+	MaxRunning int
+
+	// MaxRows limits each export. Default: no limit.
+	MaxRows int
+
+	// Retention removes finished jobs and their files after this time.
+	// Default: 24h; negative keeps them.
+	Retention time.Duration
+
+	// StaleAfter fails the pending / running jobs of other processes whose
+	// status was not written for this long (the process is gone, e.g. after
+	// a restart). Running jobs write their progress as the batches go and
+	// pending ones every StaleAfter/2. Default: 1h.
+	StaleAfter time.Duration
+
+	// OnError receives the errors of the jobs (failed exports, store
+	// errors), e.g. for logs. Internal errors are not shown in the job. job
+	// is nil for the errors of the background Sweep.
+	OnError func(job *ExportJob, err error)
+
+	once    sync.Once
+	running chan struct{}
+	wg      sync.WaitGroup
+
+	sweepOnce sync.Once
+	mu        sync.Mutex
+	active    map[string]bool // jobs deste processo
+}
+
+func NewExportJobs(store JobStore, storage ExportStorage) *ExportJobs {
+	return &ExportJobs{Store: store, Storage: storage}
+}
+
+// Wait blocks until the running jobs finish (graceful shutdown).
+func (j *ExportJobs) Wait() {
+	j.wg.Wait()
+}
+
+// acquire waits for a free slot, calling heartbeat while the job is pending.
+func (j *ExportJobs) acquire(heartbeat func()) {
+	j.once.Do(func() {
+		size := j.MaxRunning
+		if size <= 0 {
+			size = defaultExportJobsRunning
+		}
+		j.running = make(chan struct{}, size)
+	})
+
+	ticker := time.NewTicker(j.staleAfter() / 2)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case j.running <- struct{}{}:
+			return
+		case <-ticker.C:
+			heartbeat()
+		}
+	}
+}
+
+func (j *ExportJobs) release() {
+	<-j.running
+}
+
+func (j *ExportJobs) retention() time.Duration {
+	if j.Retention == 0 {
+		return defaultExportJobsRetention
+	}
+	return j.Retention
+}
+
+func (j *ExportJobs) staleAfter() time.Duration {
+	if j.StaleAfter <= 0 {
+		return defaultExportJobsStale
+	}
+	return j.StaleAfter
+}
+
+func (j *ExportJobs) setActive(id string, active bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	if j.active == nil {
+		j.active = map[string]bool{}
+	}
+	if active {
+		j.active[id] = true
+	} else {
+		delete(j.active, id)
+	}
+}
+
+func (j *ExportJobs) isActive(id string) bool {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	return j.active[id]
+}
+
+// Sweep works from the store, so it also covers the jobs of previous runs
+// and of other instances: it removes the finished jobs (and their files) not
+// written for Retention, and fails the pending / running jobs not written
+// for StaleAfter that are not running in this process. Call it on startup;
+// StartExportJob then runs it in the background every 10 minutes (or
+// Retention, when shorter).
+func (j *ExportJobs) Sweep(ctx context.Context) error {
+	now := time.Now()
+	var errs []error
+
+	if retention := j.retention(); retention > 0 {
+		finished, err := j.Store.List(ctx, now.Add(-retention), ExportJobDone, ExportJobFailed)
+		if err != nil {
+			return err
+		}
+
+		for _, job := range finished {
+			if err := j.Storage.Remove(ctx, job.File); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			errs = append(errs, j.Store.Delete(ctx, job.ID))
+		}
+	}
+
+	stale, err := j.Store.List(ctx, now.Add(-j.staleAfter()), ExportJobPending, ExportJobRunning)
+	if err != nil {
+		return err
+	}
+
+	for _, job := range stale {
+		if j.isActive(job.ID) {
+			continue
+		}
+
+		job.Status = ExportJobFailed
+		job.Error = "export interrupted"
+		job.FinishedAt = &now
+
+		errs = append(errs, j.Storage.Remove(ctx, job.File))
+		errs = append(errs, j.Store.Update(ctx, &job))
+	}
+
+	return errors.Join(errs...)
+}
+
+// startSweeper runs Sweep now and then periodically, for the life of the
+// process.
+func (j *ExportJobs) startSweeper() {
+	j.sweepOnce.Do(func() {
+		interval := exportJobSweepInterval
+		if retention := j.retention(); retention > 0 && retention < interval {
+			interval = retention
+		}
+
+		go func() {
+			for {
+				j.report(nil, j.Sweep(context.Background()))
+				time.Sleep(interval)
+			}
+		}()
+	})
+}
+
+func (j *ExportJobs) report(job *ExportJob, err error) {
+	if j.OnError != nil && err != nil {
+		j.OnError(job, err)
+	}
+}
+
+// StartExportJob registers a pending job and runs GormExport for it in the
+// background, detached from ctx (only its values are kept). The payload
+// should already be validated / scoped, like in the handlers.
+func StartExportJob[T any](
+	ctx context.Context,
+	jobs *ExportJobs,
+	db *gorm.DB,
+	payload fwork_server_orm.QueryPayload,
+	format ExportFormat,
+	opts ...ExportOptions[T],
+) (*ExportJob, error) {
+
+	var opt ExportOptions[T]
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+
+	return startExportJob(ctx, jobs, db, payload, format, opt, ExportJob{})
+}
+
+// startExportJob starts the job with the Owner / Scope of owner.
+func startExportJob[T any](
+	ctx context.Context,
+	jobs *ExportJobs,
+	db *gorm.DB,
+	payload fwork_server_orm.QueryPayload,
+	format ExportFormat,
+	opt ExportOptions[T],
+	owner ExportJob,
+) (*ExportJob, error) {
+
+	opt.MaxRows = jobs.MaxRows
+	if opt.MaxRows == 0 {
+		opt.MaxRows = -1
+	}
+
+	s := schemaOf(db, new(T))
+	if s == nil {
+		return nil, errors.New("export job: cannot parse model")
+	}
+
+	id, err := newJobID()
+	if err != nil {
+		return nil, err
+	}
+
+	fileName := opt.FileName
+	if fileName == "" {
+		fileName = s.Table
+	}
+
+	job := &ExportJob{
+		ID:        id,
+		Resource:  s.Table,
+		Format:    format.Name,
+		Query:     payload,
+		Status:    ExportJobPending,
+		File:      id + "." + format.Extension,
+		FileName:  fileName + "." + format.Extension,
+		Owner:     owner.Owner,
+		Scope:     owner.Scope,
+		CreatedAt: time.Now(),
+	}
+
+	if err := jobs.Store.Create(ctx, job); err != nil {
+		return nil, err
+	}
+
+	created := *job
+
+	jobs.startSweeper()
+	jobs.setActive(job.ID, true)
+
+	jobs.wg.Add(1)
+	go func() {
+		defer jobs.wg.Done()
+		defer jobs.setActive(job.ID, false)
+
+		ctx := context.WithoutCancel(ctx)
+
+		// pendente: mantém o UpdatedAt em dia para o Sweep de outras instâncias
+		jobs.acquire(func() {
+			jobs.report(job, jobs.Store.Update(ctx, job))
+		})
+		defer jobs.release()
+
+		runExportJob(ctx, jobs, db, job, format, opt)
+	}()
+
+	return &created, nil
+}
+
+func runExportJob[T any](
+	ctx context.Context,
+	jobs *ExportJobs,
+	db *gorm.DB,
+	job *ExportJob,
+	format ExportFormat,
+	opt ExportOptions[T],
+) {
+	started := time.Now()
+	job.Status = ExportJobRunning
+	job.StartedAt = &started
+	jobs.report(job, jobs.Store.Update(ctx, job))
+
+	// o timeout vale para a exportação, não para as gravações do status
+	err := func() error {
+		ctx := ctx
+		if opt.Timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, opt.Timeout)
+			defer cancel()
+		}
+
+		total, err := countExportRows[T](db.WithContext(ctx), job.Query)
+		if err != nil {
+			return err
+		}
+		job.Total = total
+
+		file, err := jobs.Storage.Create(ctx, job.File)
+		if err != nil {
+			return err
+		}
+
+		saved := time.Now()
+		opt.Progress = func(rows int) {
+			job.Rows = int64(rows)
+			if time.Since(saved) >= exportJobProgressInterval {
+				saved = time.Now()
+				jobs.report(job, jobs.Store.Update(ctx, job))
+			}
+		}
+
+		rows, err := GormExport(ctx, db, job.Query, file, format, opt)
+		job.Rows = int64(rows)
+
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		return err
+	}()
+
+	finished := time.Now()
+	job.FinishedAt = &finished
+	job.Status = ExportJobDone
+
+	if err != nil {
+		jobs.report(job, err)
+
+		job.Status = ExportJobFailed
+		job.Error = "export failed"
+		if e := HttpError(err); e.Kind != fwork_server_orm.ErrorInternal {
+			job.Error = e.Error()
+		}
+		jobs.report(job, jobs.Storage.Remove(ctx, job.File))
+	}
+
+	// a remoção após Retention fica com o Sweep
+	jobs.report(job, jobs.Store.Update(ctx, job))
+}
+
+// countExportRows counts the rows of the export (pagination ignored).
+func countExportRows[T any](db *gorm.DB, payload fwork_server_orm.QueryPayload) (int64, error) {
+	payload.Select = nil
+	payload.Order = nil
+	payload.Nested = ""
+	payload.Limit = nil
+	payload.Offset = nil
+	payload.Page = nil
+
+	var total int64
+	err := ApplyQuery(NewGormQueryBuilder(db.Model(new(T))), payload).Db.Count(&total).Error
+	return total, err
+}
+
+func newJobID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
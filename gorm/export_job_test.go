@@ -0,0 +1,135 @@
+package fwork_server_gorm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+func TestExportJobsSweep(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+
+	storage, err := NewLocalStorage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := NewMemoryJobStore()
+
+	jobs := NewExportJobs(store, storage)
+	jobs.Retention = time.Hour
+	jobs.StaleAfter = time.Hour
+
+	old := time.Now().Add(-2 * time.Hour)
+	for _, job := range []ExportJob{
+		{ID: "expired", Status: ExportJobDone, UpdatedAt: old},
+		{ID: "expired-failed", Status: ExportJobFailed, UpdatedAt: old},
+		{ID: "done", Status: ExportJobDone, UpdatedAt: time.Now()},
+		{ID: "stale", Status: ExportJobRunning, UpdatedAt: old},
+		{ID: "stale-pending", Status: ExportJobPending, UpdatedAt: old},
+		{ID: "active", Status: ExportJobRunning, UpdatedAt: old},
+		{ID: "pending", Status: ExportJobPending, UpdatedAt: time.Now()},
+	} {
+		job.File = job.ID + ".csv"
+		if err := os.WriteFile(filepath.Join(dir, job.File), []byte("id\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		// direto no mapa: Update trocaria o UpdatedAt
+		store.jobs[job.ID] = job
+	}
+	jobs.setActive("active", true)
+
+	if err := jobs.Sweep(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	for id, want := range map[string]struct {
+		status ExportJobStatus // "" = removido
+		file   bool
+	}{
+		"expired":        {"", false},
+		"expired-failed": {"", false},
+		"done":           {ExportJobDone, true},
+		"stale":          {ExportJobFailed, false},
+		"stale-pending":  {ExportJobFailed, false},
+		"active":         {ExportJobRunning, true},
+		"pending":        {ExportJobPending, true},
+	} {
+		job, err := store.Get(ctx, id)
+		switch {
+		case want.status == "":
+			if !errors.Is(err, ErrJobNotFound) {
+				t.Errorf("%s: Get = %+v, %v, want the job removed", id, job, err)
+			}
+		case err != nil:
+			t.Errorf("%s: %v", id, err)
+		case job.Status != want.status:
+			t.Errorf("%s: status = %s, want %s", id, job.Status, want.status)
+		case want.status == ExportJobFailed && (job.Error == "" || job.FinishedAt == nil):
+			t.Errorf("%s: failed job = %+v, want an error and FinishedAt", id, job)
+		}
+
+		_, err = os.Stat(filepath.Join(dir, id+".csv"))
+		if exists := err == nil; exists != want.file {
+			t.Errorf("%s: file exists = %v, want %v", id, exists, want.file)
+		}
+	}
+}
+
+func TestExportJobHandlersCheckTheRequester(t *testing.T) {
+	res := tenantResource(t)
+
+	storage, err := NewLocalStorage(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Jobs = NewExportJobs(NewMemoryJobStore(), storage)
+	res.JobOwner = func(r *http.Request) string { return r.Header.Get("X-User") }
+
+	router := mux.NewRouter()
+	router.HandleFunc("/rows/exports", res.StartExportJobHandler()).Methods("POST")
+	router.HandleFunc("/rows/exports/{id}", res.ExportJobHandler()).Methods("GET")
+	router.HandleFunc("/rows/exports/{id}/download", res.ExportJobDownloadHandler()).Methods("GET")
+
+	w := serve(router, "POST", "/rows/exports", `{"format":"csv","query":{"select":["id","name"]}}`, "X-Tenant", "1", "X-User", "ana")
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("start status = %d: %s", w.Code, w.Body)
+	}
+
+	var job ExportJob
+	if err := json.Unmarshal(w.Body.Bytes(), &job); err != nil {
+		t.Fatal(err)
+	}
+	res.Jobs.Wait()
+
+	for name, tc := range map[string]struct {
+		header []string
+		want   int
+	}{
+		"owner":        {[]string{"X-Tenant", "1", "X-User", "ana"}, http.StatusOK},
+		"other user":   {[]string{"X-Tenant", "1", "X-User", "bruno"}, http.StatusNotFound},
+		"other tenant": {[]string{"X-Tenant", "2", "X-User", "ana"}, http.StatusNotFound},
+		"anonymous":    {nil, http.StatusNotFound},
+	} {
+		t.Run(name, func(t *testing.T) {
+			for _, target := range []string{"/rows/exports/" + job.ID, "/rows/exports/" + job.ID + "/download"} {
+				w := serve(router, "GET", target, "", tc.header...)
+				if w.Code != tc.want {
+					t.Errorf("GET %s: status = %d, want %d: %s", target, w.Code, tc.want, w.Body)
+				}
+			}
+		})
+	}
+
+	w = serve(router, "GET", "/rows/exports/"+job.ID+"/download", "", "X-Tenant", "1", "X-User", "ana")
+	if got, want := w.Body.String(), "id,name\n1,one\n"; got != want {
+		t.Errorf("file = %q, want %q", got, want)
+	}
+}
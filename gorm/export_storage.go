@@ -0,0 +1,76 @@
+package fwork_server_gorm
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ExportStorage keeps the files of the export jobs. Names are generated by
+// the jobs (job id + extension).
+type ExportStorage interface {
+	Create(ctx context.Context, name string) (io.WriteCloser, error)
+	// Open returns the file; when it is an io.ReadSeeker the download
+	// supports range requests.
+	Open(ctx context.Context, name string) (io.ReadCloser, error)
+	Remove(ctx context.Context, name string) error
+}
+
+// LocalStorage stores the files in a directory of the local filesystem.
+type LocalStorage struct {
+	Dir string
+}
+
+// NewLocalStorage creates dir if needed. Empty dir uses
+// <os.TempDir>/goqlite-exports.
+func NewLocalStorage(dir string) (*LocalStorage, error) {
+	if dir == "" {
+		dir = filepath.Join(os.TempDir(), "goqlite-exports")
+	}
+
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+
+	return &LocalStorage{Dir: dir}, nil
+}
+
+func (s *LocalStorage) Create(ctx context.Context, name string) (io.WriteCloser, error) {
+	path, err := s.path(name)
+	if err != nil {
+		return nil, err
+	}
+	return os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o640)
+}
+
+func (s *LocalStorage) Open(ctx context.Context, name string) (io.ReadCloser, error) {
+	path, err := s.path(name)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+func (s *LocalStorage) Remove(ctx context.Context, name string) error {
+	path, err := s.path(name)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// path keeps the files inside Dir.
+func (s *LocalStorage) path(name string) (string, error) {
+	if name == "" || name != filepath.Base(name) || strings.HasPrefix(name, ".") {
+		return "", errors.New("invalid export file name: " + name)
+	}
+	return filepath.Join(s.Dir, name), nil
+}
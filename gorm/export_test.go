@@ -0,0 +1,124 @@
+package fwork_server_gorm
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	fwork_server_orm "github.com/joabssilveira/GoQLite/core"
+)
+
+func TestExportColumnsRejectUnreadableFields(t *testing.T) {
+	s := schemaOf(dryRunDB(t), new(testStudent))
+
+	for _, path := range []string{"nope", "password", "Password", "course.secret", "course.nope"} {
+		_, err := exportColumns(s, fwork_server_orm.QueryPayload{Select: []string{path}})
+
+		var e *fwork_server_orm.Error
+		if !errors.As(err, &e) || e.Kind != fwork_server_orm.ErrorBadQuery {
+			t.Errorf("%s: error = %v, want a bad query error", path, err)
+		}
+	}
+
+	columns, err := exportColumns(s, fwork_server_orm.QueryPayload{Select: []string{"fullName", "course.title", "metadata.tags.0"}})
+	if err != nil || len(columns) != 3 {
+		t.Fatalf("exportColumns = %v, %v", columns, err)
+	}
+
+	for _, column := range defaultExportColumns(s, "") {
+		if column.Name == "Password" {
+			t.Errorf("default columns include the write-only field %s", column.Name)
+		}
+	}
+}
+
+func TestExportCSVEscapesFormulas(t *testing.T) {
+	values := []any{"=SUM(A1:A2)", "+1", "-1", "@cmd", "\tx", "\rx", "plain", "", json.Number("-5"), true, nil}
+
+	columns := make([]ExportColumn, len(values))
+	for i := range columns {
+		columns[i].Name = "c"
+	}
+	columns[0].Name = "=name"
+
+	for name, tc := range map[string]struct {
+		format ExportFormat
+		header string
+		row    []string
+	}{
+		"escaped": {ExportCSV, "'=name", []string{"'=SUM(A1:A2)", "'+1", "'-1", "'@cmd", "'\tx", "'\rx", "plain", "", "-5", "true", ""}},
+		"raw":     {ExportCSVRaw, "=name", []string{"=SUM(A1:A2)", "+1", "-1", "@cmd", "\tx", "\rx", "plain", "", "-5", "true", ""}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			w := tc.format.NewWriter(&buf)
+			if err := w.WriteHeader(columns); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.WriteRow(values); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			records, err := csv.NewReader(&buf).ReadAll()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if records[0][0] != tc.header {
+				t.Errorf("header = %q, want %q", records[0][0], tc.header)
+			}
+			if !reflect.DeepEqual(records[1], tc.row) {
+				t.Errorf("row = %q\nwant  %q", records[1], tc.row)
+			}
+		})
+	}
+}
+
+type testExportRow struct {
+	ID     uint    `json:"id"`
+	Name   string  `json:"name"`
+	Score  float64 `json:"score"`
+	Active bool    `json:"active"`
+	Note   *string `json:"note"`
+}
+
+func TestExportNDJSONRoundTrip(t *testing.T) {
+	ctx := context.Background()
+	note := "=not a formula"
+	rows := []testExportRow{
+		{ID: 1, Name: "Ana", Score: 9.5, Active: true, Note: &note},
+		{ID: 2, Name: "Bruno \"B\"\nSilva", Score: -1, Active: false},
+	}
+
+	src := sqliteDB(t, &testExportRow{})
+	if err := src.Create(&rows).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	written, err := GormExport[testExportRow](ctx, src, fwork_server_orm.QueryPayload{}, &buf, ExportNDJSON)
+	if err != nil || written != len(rows) {
+		t.Fatalf("GormExport = %d, %v", written, err)
+	}
+
+	dst := sqliteDB(t, &testExportRow{})
+	result, err := GormImport[testExportRow](ctx, dst, &buf, ImportNDJSON)
+	if err != nil || result.Succeeded != len(rows) {
+		t.Fatalf("GormImport = %+v, %v", result, err)
+	}
+
+	var imported []testExportRow
+	if err := dst.Order("id").Find(&imported).Error; err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(imported, rows) {
+		t.Errorf("imported %+v, want %+v", imported, rows)
+	}
+}
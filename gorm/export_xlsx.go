@@ -0,0 +1,331 @@
+package fwork_server_gorm
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm/schema"
+)
+
+var ExportXLSX = ExportFormat{
+	Name:        "xlsx",
+	ContentType: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+	Extension:   "xlsx",
+	NewWriter:   func(w io.Writer) RowWriter { return &xlsxRowWriter{out: w} },
+}
+
+// =========================
+// XLSX
+// =========================
+
+// Estilos de célula (índices de cellXfs em xlsxStyles).
+const (
+	xlsxStyleHeader   = 1
+	xlsxStyleDateTime = 2
+	xlsxStyleDate     = 3
+)
+
+// limite de linhas de uma planilha (cabeçalho incluso); variável para os
+// testes
+var xlsxMaxRows = 1048576
+
+const (
+	// limite de caracteres de uma célula no Excel
+	xlsxMaxCellLength = 32767
+	// números com mais dígitos perdem precisão no Excel (double)
+	xlsxMaxNumberDigits = 15
+)
+
+// excelEpoch é o dia zero dos números de data do Excel (sistema 1900).
+var excelEpoch = time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+
+// xlsxRowWriter writes a workbook row by row: each sheet is a zip entry
+// written as the rows come, so only the current batch is kept in memory. At
+// the Excel row limit a new sheet (with the header) is started; the workbook
+// parts that list the sheets are written on Close.
+type xlsxRowWriter struct {
+	out     io.Writer
+	zip     *zip.Writer
+	sheet   *bufio.Writer
+	columns []ExportColumn
+	types   []schema.DataType
+	sheets  int
+	rows    int // linhas da planilha atual, cabeçalho incluso
+}
+
+func (x *xlsxRowWriter) WriteHeader(columns []ExportColumn) error {
+	x.zip = zip.NewWriter(x.out)
+
+	parts := []struct{ name, content string }{
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/styles.xml", xlsxStyles},
+	}
+	for _, part := range parts {
+		if err := x.writePart(part.name, part.content); err != nil {
+			return err
+		}
+	}
+
+	x.columns = columns
+	x.types = make([]schema.DataType, len(columns))
+	for i, column := range columns {
+		x.types[i] = column.DataType
+	}
+
+	return x.openSheet()
+}
+
+func (x *xlsxRowWriter) WriteRow(values []any) error {
+	if x.rows >= xlsxMaxRows {
+		if err := x.closeSheet(); err != nil {
+			return err
+		}
+		if err := x.openSheet(); err != nil {
+			return err
+		}
+	}
+
+	x.sheet.WriteString("<row>")
+	for i, value := range values {
+		x.writeCell(x.types[i], value)
+	}
+	x.sheet.WriteString("</row>")
+	x.rows++
+	return nil
+}
+
+func (x *xlsxRowWriter) Flush() error {
+	if x.sheet == nil {
+		return nil
+	}
+	if err := x.sheet.Flush(); err != nil {
+		return err
+	}
+	return x.zip.Flush()
+}
+
+func (x *xlsxRowWriter) Close() error {
+	if x.sheet == nil {
+		return nil
+	}
+
+	if err := x.closeSheet(); err != nil {
+		return err
+	}
+
+	var sheets, sheetRels, sheetTypes strings.Builder
+	for i := 1; i <= x.sheets; i++ {
+		n := strconv.Itoa(i)
+		sheets.WriteString(`<sheet name="Sheet` + n + `" sheetId="` + n + `" r:id="rId` + n + `"/>`)
+		sheetRels.WriteString(`<Relationship Id="rId` + n + `" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet` + n + `.xml"/>`)
+		sheetTypes.WriteString(`<Override PartName="/xl/worksheets/sheet` + n + `.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>`)
+	}
+	// styles fica depois das planilhas
+	stylesID := "rId" + strconv.Itoa(x.sheets+1)
+
+	parts := []struct{ name, content string }{
+		{"xl/workbook.xml", fmt.Sprintf(xlsxWorkbook, sheets.String())},
+		{"xl/_rels/workbook.xml.rels", fmt.Sprintf(xlsxWorkbookRels, sheetRels.String(), stylesID)},
+		{"[Content_Types].xml", fmt.Sprintf(xlsxContentTypes, sheetTypes.String())},
+	}
+	for _, part := range parts {
+		if err := x.writePart(part.name, part.content); err != nil {
+			return err
+		}
+	}
+
+	return x.zip.Close()
+}
+
+func (x *xlsxRowWriter) writePart(name, content string) error {
+	w, err := x.zip.Create(name)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(w, content)
+	return err
+}
+
+// openSheet starts the next sheet entry and writes its header row.
+func (x *xlsxRowWriter) openSheet() error {
+	x.sheets++
+
+	w, err := x.zip.Create("xl/worksheets/sheet" + strconv.Itoa(x.sheets) + ".xml")
+	if err != nil {
+		return err
+	}
+	x.sheet = bufio.NewWriter(w)
+
+	x.sheet.WriteString(xml.Header)
+	x.sheet.WriteString(`<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	// cabeçalho congelado
+	x.sheet.WriteString(`<sheetViews><sheetView workbookViewId="0"><pane ySplit="1" topLeftCell="A2" activePane="bottomLeft" state="frozen"/></sheetView></sheetViews>`)
+
+	if len(x.columns) > 0 {
+		x.sheet.WriteString("<cols>")
+		for i, column := range x.columns {
+			width := utf8.RuneCountInString(column.Header()) + 2
+			if width < 12 {
+				width = 12
+			}
+			if x.types[i] == schema.Time && width < 20 {
+				width = 20
+			}
+			if width > 60 {
+				width = 60
+			}
+			n := strconv.Itoa(i + 1)
+			x.sheet.WriteString(`<col min="` + n + `" max="` + n + `" width="` + strconv.Itoa(width) + `" customWidth="1"/>`)
+		}
+		x.sheet.WriteString("</cols>")
+	}
+
+	x.sheet.WriteString("<sheetData>")
+
+	x.sheet.WriteString("<row>")
+	for _, column := range x.columns {
+		x.writeString(column.Header(), xlsxStyleHeader)
+	}
+	x.sheet.WriteString("</row>")
+	x.rows = 1
+
+	return nil
+}
+
+func (x *xlsxRowWriter) closeSheet() error {
+	x.sheet.WriteString("</sheetData></worksheet>")
+	return x.sheet.Flush()
+}
+
+// writeCell types the cell by the schema of the column (dates) and by the JSON
+// value (numbers, booleans); anything else is written as text.
+func (x *xlsxRowWriter) writeCell(dataType schema.DataType, value any) {
+	switch v := value.(type) {
+	case nil:
+		x.sheet.WriteString("<c/>")
+		return
+
+	case bool:
+		if v {
+			x.sheet.WriteString(`<c t="b"><v>1</v></c>`)
+		} else {
+			x.sheet.WriteString(`<c t="b"><v>0</v></c>`)
+		}
+		return
+
+	case string:
+		if dataType == schema.Time || dataType == schema.DataType("date") {
+			if t, err := time.Parse(time.RFC3339Nano, v); err == nil {
+				style := xlsxStyleDateTime
+				if dataType != schema.Time {
+					style = xlsxStyleDate
+				}
+				x.sheet.WriteString(`<c s="` + strconv.Itoa(style) + `"><v>`)
+				x.sheet.WriteString(strconv.FormatFloat(excelSerial(t), 'f', -1, 64))
+				x.sheet.WriteString("</v></c>")
+				return
+			}
+		}
+	}
+
+	if number, ok := value.(json.Number); ok && isXLSXNumber(number) {
+		x.sheet.WriteString("<c><v>" + number.String() + "</v></c>")
+		return
+	}
+
+	x.writeString(ExportCellString(value), 0)
+}
+
+func (x *xlsxRowWriter) writeString(value string, style int) {
+	if utf8.RuneCountInString(value) > xlsxMaxCellLength {
+		value = string([]rune(value)[:xlsxMaxCellLength])
+	}
+
+	x.sheet.WriteString(`<c t="inlineStr"`)
+	if style > 0 {
+		x.sheet.WriteString(` s="` + strconv.Itoa(style) + `"`)
+	}
+	x.sheet.WriteString(`><is><t xml:space="preserve">`)
+	xml.EscapeText(x.sheet, []byte(xlsxCleanText(value)))
+	x.sheet.WriteString("</t></is></c>")
+}
+
+// isXLSXNumber reports whether a json.Number fits a numeric cell without
+// losing digits (ids, codes longer than that are kept as text).
+func isXLSXNumber(number json.Number) bool {
+	if _, err := number.Float64(); err != nil {
+		return false
+	}
+
+	digits := 0
+	for _, r := range number.String() {
+		if r == 'e' || r == 'E' {
+			break
+		}
+		if r >= '0' && r <= '9' {
+			digits++
+		}
+	}
+	return digits <= xlsxMaxNumberDigits
+}
+
+// excelSerial converts t to an Excel date number, keeping the wall clock of
+// its own time zone (Excel has no zones).
+func excelSerial(t time.Time) float64 {
+	wall := time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+	return wall.Sub(excelEpoch).Hours() / 24
+}
+
+// xlsxCleanText drops the control characters XML 1.0 does not allow.
+func xlsxCleanText(value string) string {
+	return strings.Map(func(r rune) rune {
+		if (r < 0x20 && r != '\t' && r != '\n' && r != '\r') || r == 0xFFFE || r == 0xFFFF {
+			return -1
+		}
+		return r
+	}, value)
+}
+
+const xlsxContentTypes = xml.Header + `<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+	`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+	`<Default Extension="xml" ContentType="application/xml"/>` +
+	`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+	`%s` +
+	`<Override PartName="/xl/styles.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.styles+xml"/>` +
+	`</Types>`
+
+const xlsxRootRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+	`</Relationships>`
+
+const xlsxWorkbook = xml.Header + `<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+	`<sheets>%s</sheets>` +
+	`</workbook>`
+
+const xlsxWorkbookRels = xml.Header + `<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+	`%s` +
+	`<Relationship Id="%s" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/styles" Target="styles.xml"/>` +
+	`</Relationships>`
+
+const xlsxStyles = xml.Header + `<styleSheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">` +
+	`<numFmts count="2"><numFmt numFmtId="164" formatCode="yyyy-mm-dd hh:mm:ss"/><numFmt numFmtId="165" formatCode="yyyy-mm-dd"/></numFmts>` +
+	`<fonts count="2"><font><sz val="11"/><name val="Calibri"/></font><font><b/><sz val="11"/><name val="Calibri"/></font></fonts>` +
+	`<fills count="2"><fill><patternFill patternType="none"/></fill><fill><patternFill patternType="gray125"/></fill></fills>` +
+	`<borders count="1"><border><left/><right/><top/><bottom/><diagonal/></border></borders>` +
+	`<cellStyleXfs count="1"><xf numFmtId="0" fontId="0" fillId="0" borderId="0"/></cellStyleXfs>` +
+	`<cellXfs count="4">` +
+	`<xf numFmtId="0" fontId="0" fillId="0" borderId="0" xfId="0"/>` +
+	`<xf numFmtId="0" fontId="1" fillId="0" borderId="0" xfId="0" applyFont="1"/>` +
+	`<xf numFmtId="164" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`<xf numFmtId="165" fontId="0" fillId="0" borderId="0" xfId="0" applyNumberFormat="1"/>` +
+	`</cellXfs>` +
+	`<cellStyles count="1"><cellStyle name="Normal" xfId="0" builtinId="0"/></cellStyles>` +
+	`</styleSheet>`
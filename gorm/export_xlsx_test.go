@@ -0,0 +1,156 @@
+package fwork_server_gorm
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm/schema"
+)
+
+// xlsxCell / xlsxSheet decode the parts of the worksheets the writer emits.
+type xlsxCell struct {
+	Type   string `xml:"t,attr"`
+	Style  string `xml:"s,attr"`
+	Value  string `xml:"v"`
+	Inline string `xml:"is>t"`
+}
+
+type xlsxSheet struct {
+	Rows []struct {
+		Cells []xlsxCell `xml:"c"`
+	} `xml:"sheetData>row"`
+}
+
+func readXLSX(t *testing.T, data []byte) (sheets []xlsxSheet, workbook string) {
+	t.Helper()
+
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	parts := map[string][]byte{}
+	for _, f := range r.File {
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		parts[f.Name], _ = io.ReadAll(rc)
+		rc.Close()
+	}
+
+	for i := 1; ; i++ {
+		raw, ok := parts["xl/worksheets/sheet"+strconv.Itoa(i)+".xml"]
+		if !ok {
+			break
+		}
+		var sheet xlsxSheet
+		if err := xml.Unmarshal(raw, &sheet); err != nil {
+			t.Fatal(err)
+		}
+		sheets = append(sheets, sheet)
+	}
+
+	return sheets, string(parts["xl/workbook.xml"])
+}
+
+func TestExportXLSXStartsNewSheetsAtTheRowLimit(t *testing.T) {
+	defer func(rows int) { xlsxMaxRows = rows }(xlsxMaxRows)
+	xlsxMaxRows = 3 // cabeçalho + 2 linhas
+
+	var buf bytes.Buffer
+	w := ExportXLSX.NewWriter(&buf)
+	if err := w.WriteHeader([]ExportColumn{{Name: "id", Label: "ID"}, {Name: "name"}}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 1; i <= 5; i++ {
+		if err := w.WriteRow([]any{json.Number(strconv.Itoa(i)), "row"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	sheets, workbook := readXLSX(t, buf.Bytes())
+	if len(sheets) != 3 {
+		t.Fatalf("%d sheets, want 3", len(sheets))
+	}
+	if strings.Count(workbook, "<sheet ") != 3 {
+		t.Errorf("workbook does not list the 3 sheets: %s", workbook)
+	}
+
+	var ids []string
+	for i, sheet := range sheets {
+		header := sheet.Rows[0].Cells
+		if header[0].Inline != "ID" || header[1].Inline != "name" {
+			t.Errorf("sheet %d header = %+v", i+1, header)
+		}
+		for _, row := range sheet.Rows[1:] {
+			ids = append(ids, row.Cells[0].Value)
+		}
+	}
+
+	if want := []string{"1", "2", "3", "4", "5"}; !reflect.DeepEqual(ids, want) {
+		t.Errorf("ids = %v, want %v", ids, want)
+	}
+}
+
+func TestExportXLSXTypesCells(t *testing.T) {
+	columns := []ExportColumn{
+		{Name: "count", Label: "Count"},
+		{Name: "createdAt", Label: "Created", DataType: schema.Time},
+		{Name: "active"},
+		{Name: "code"},
+		{Name: "note"},
+		{Name: "empty"},
+	}
+	values := []any{
+		json.Number("42"),
+		"2024-01-02T12:00:00Z",
+		true,
+		json.Number("12345678901234567890"), // mais dígitos que um double
+		"<b>&",
+		nil,
+	}
+
+	var buf bytes.Buffer
+	w := ExportXLSX.NewWriter(&buf)
+	if err := w.WriteHeader(columns); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteRow(values); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	sheets, _ := readXLSX(t, buf.Bytes())
+	header, row := sheets[0].Rows[0].Cells, sheets[0].Rows[1].Cells
+
+	for i, want := range []string{"Count", "Created", "active", "code", "note", "empty"} {
+		if header[i].Inline != want || header[i].Style != "1" {
+			t.Errorf("header %d = %+v, want bold %q", i, header[i], want)
+		}
+	}
+
+	want := []xlsxCell{
+		{Value: "42"},
+		{Style: "2", Value: "45293.5"},
+		{Type: "b", Value: "1"},
+		{Type: "inlineStr", Inline: "12345678901234567890"},
+		{Type: "inlineStr", Inline: "<b>&"},
+		{},
+	}
+	if !reflect.DeepEqual(row, want) {
+		t.Errorf("cells = %+v\nwant    %+v", row, want)
+	}
+}
@@ -0,0 +1,222 @@
+package fwork_server_gorm
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	fwork_server_orm "github.com/joabssilveira/GoQLite/core"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// Filter paths are resolved once per (schema, path) and reused across
+// requests: the schema lookups, relation hops and join clauses of a path do
+// not depend on the request. gorm caches the parsed schemas (shared by every
+// session of a DB), so the *schema.Schema pointer identifies the model.
+
+// the field paths come from the request: only paths naming a real field are
+// cached, and the cache stops growing at this size
+const maxFieldPathCacheEntries = 10000
+
+var (
+	fieldPathCache       sync.Map // fieldPathKey -> *resolvedPath
+	fieldPathCacheLength atomic.Int64
+)
+
+type fieldPathKey struct {
+	schema *schema.Schema
+	path   string
+}
+
+// resolvedPath is a filter field path translated to SQL.
+type resolvedPath struct {
+	SQLField string
+	IsJSONB  bool
+	Joins    []resolvedJoin
+	Field    *schema.Field // nil for JSONB paths and unknown fields
+
+	// Known is set when the path names a readable column of the model /
+	// relation, or a JSONB path inside one (see queryableField).
+	Known bool
+}
+
+type resolvedJoin struct {
+	Clause     string // LEFT JOIN ... ON ...
+	SoftDelete string // AND "alias"."deleted_at" IS NULL, when the model has one
+}
+
+// schemaOf returns the (gorm cached) schema of model. Nil when model cannot
+// be parsed.
+func schemaOf(db *gorm.DB, model any) *schema.Schema {
+	if model == nil {
+		return nil
+	}
+
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(model); err != nil {
+		return nil
+	}
+
+	return stmt.Schema
+}
+
+// resolveFieldPath translates a filter field ("name", "course.title",
+// "course.group.name", "metadata.tags.0") of the root schema.
+func resolveFieldPath(root *schema.Schema, path string) *resolvedPath {
+	key := fieldPathKey{schema: root, path: path}
+	if cached, ok := fieldPathCache.Load(key); ok {
+		return cached.(*resolvedPath)
+	}
+
+	resolved := buildFieldPath(root, path)
+
+	// caminhos inventados pelo cliente não ocupam o cache
+	if resolved.Known && fieldPathCacheLength.Load() < maxFieldPathCacheEntries {
+		if _, loaded := fieldPathCache.LoadOrStore(key, resolved); !loaded {
+			fieldPathCacheLength.Add(1)
+		}
+	}
+
+	return resolved
+}
+
+func buildFieldPath(root *schema.Schema, path string) *resolvedPath {
+	if !strings.Contains(path, ".") {
+		field := lookUpField(root, path)
+		return &resolvedPath{
+			SQLField: quoteTable(root.Table) + "." + quoteIdent(columnName(root, path)),
+			Field:    field,
+			Known:    queryableField(field),
+		}
+	}
+
+	parts := strings.Split(path, ".")
+	first := parts[0]
+	rest := parts[1:]
+
+	// tenta resolver o PRIMEIRO nível como relação
+	if _, isRelation := root.Relationships.Relations[fwork_server_orm.SnakeToCamel(first)]; !isRelation {
+		// ❌ NÃO é relação → JSONB
+		return &resolvedPath{
+			SQLField: fmt.Sprintf("%s.%s #>> '{%s}'", quoteTable(root.Table), quoteIdent(first), strings.Join(rest, ",")),
+			IsJSONB:  true,
+			Known:    queryableField(lookUpField(root, first)),
+		}
+	}
+
+	// ✅ É relação → cadeia ilimitada
+	resolved := &resolvedPath{}
+
+	current := root
+	currentAlias := ""
+	remainingParts := parts
+
+	for i := 0; i < len(parts)-1; i++ {
+		relationSnake := parts[i]
+
+		rel := current.Relationships.Relations[fwork_server_orm.SnakeToCamel(relationSnake)]
+		if rel == nil {
+			// JSONB dentro da relação
+			resolved.IsJSONB = true
+			resolved.SQLField = fmt.Sprintf(
+				"%s.%s #>> '{%s}'",
+				quoteIdent(currentAlias),
+				quoteIdent(remainingParts[0]),
+				strings.Join(remainingParts[1:], ","),
+			)
+			resolved.Known = queryableField(lookUpField(current, remainingParts[0]))
+			return resolved
+		}
+
+		resolved.Joins = append(resolved.Joins, relationJoin(current, rel, relationSnake, currentAlias))
+
+		current = rel.FieldSchema
+		currentAlias = relationSnake
+		remainingParts = remainingParts[1:]
+	}
+
+	// último item é o campo real
+	lastField := parts[len(parts)-1]
+
+	dbFieldName := lastField // fallback seguro
+	for _, f := range current.Fields {
+		if f.Name == fwork_server_orm.SnakeToCamel(lastField) || f.DBName == lastField || (f.DBName != "" && jsonFieldName(f) == lastField) {
+			dbFieldName = f.DBName
+			resolved.Field = f
+			break
+		}
+	}
+
+	resolved.SQLField = quoteIdent(currentAlias) + "." + quoteIdent(dbFieldName)
+	resolved.Known = queryableField(resolved.Field)
+	return resolved
+}
+
+// relationJoin builds the LEFT JOIN of rel, aliased as alias, from the parent
+// table (or parentAlias when the parent is itself a joined relation).
+func relationJoin(parent *schema.Schema, rel *schema.Relationship, alias string, parentAlias string) resolvedJoin {
+	var parentKey, childKey string
+	for _, ref := range rel.References {
+		parentKey = ref.PrimaryKey.DBName
+		childKey = ref.ForeignKey.DBName
+		break
+	}
+
+	parentTable := parentAlias
+	if parentTable == "" {
+		parentTable = parent.Table
+	}
+
+	relationTable := quoteTable(rel.FieldSchema.Table)
+
+	var clause string
+	if rel.Type == schema.BelongsTo {
+		clause = fmt.Sprintf(
+			`LEFT JOIN %s "%s" ON "%s"."%s" = "%s"."%s"`,
+			relationTable,
+			alias,
+			alias,
+			parentKey,
+			parentTable,
+			childKey,
+		)
+	} else {
+		clause = fmt.Sprintf(
+			`LEFT JOIN %s "%s" ON "%s"."%s" = "%s"."%s"`,
+			relationTable,
+			alias,
+			alias,
+			childKey,
+			parentTable,
+			parentKey,
+		)
+	}
+
+	join := resolvedJoin{Clause: clause}
+
+	// soft delete: filhos deletados não casam com o filtro
+	if field := deletedAtField(rel.FieldSchema); field != nil {
+		join.SoftDelete = fmt.Sprintf(` AND "%s"."%s" IS NULL`, alias, field.DBName)
+	}
+
+	return join
+}
+
+func (j resolvedJoin) apply(db *gorm.DB) {
+	join := j.Clause
+	if !db.Statement.Unscoped {
+		join += j.SoftDelete
+	}
+
+	if !hasJoin(db, join) {
+		db.Joins(join)
+	}
+}
+
+func (p *resolvedPath) applyJoins(db *gorm.DB) {
+	for _, join := range p.Joins {
+		join.apply(db)
+	}
+}
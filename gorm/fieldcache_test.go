@@ -0,0 +1,168 @@
+package fwork_server_gorm
+
+import (
+	"encoding/json"
+	"strconv"
+	"testing"
+
+	fwork_server_orm "github.com/joabssilveira/GoQLite/core"
+	"gorm.io/gorm"
+	"gorm.io/gorm/callbacks"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/schema"
+)
+
+// dryRunDialector only builds SQL (postgres quoting / placeholders): the
+// tests run with DryRun and never reach a database.
+type dryRunDialector struct{}
+
+func (dryRunDialector) Name() string { return "postgres" }
+
+func (dryRunDialector) Initialize(db *gorm.DB) error {
+	callbacks.RegisterDefaultCallbacks(db, &callbacks.Config{})
+	return nil
+}
+
+func (dryRunDialector) Migrator(db *gorm.DB) gorm.Migrator { return nil }
+
+func (dryRunDialector) DataTypeOf(field *schema.Field) string { return string(field.DataType) }
+
+func (dryRunDialector) DefaultValueOf(field *schema.Field) clause.Expression {
+	return clause.Expr{SQL: "DEFAULT"}
+}
+
+func (dryRunDialector) BindVarTo(writer clause.Writer, stmt *gorm.Statement, v interface{}) {
+	writer.WriteByte('$')
+	writer.WriteString(strconv.Itoa(len(stmt.Vars)))
+}
+
+func (dryRunDialector) QuoteTo(writer clause.Writer, str string) {
+	writer.WriteByte('"')
+	writer.WriteString(str)
+	writer.WriteByte('"')
+}
+
+func (dryRunDialector) Explain(sql string, vars ...interface{}) string {
+	return logger.ExplainSQL(sql, nil, `'`, vars...)
+}
+
+func dryRunDB(tb testing.TB) *gorm.DB {
+	tb.Helper()
+
+	db, err := gorm.Open(dryRunDialector{}, &gorm.Config{
+		DryRun: true,
+		Logger: logger.Discard,
+	})
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return db
+}
+
+type testGroup struct {
+	ID        uint
+	Name      string
+	DeletedAt gorm.DeletedAt
+}
+
+type testCourse struct {
+	ID        uint
+	Title     string
+	GroupID   uint
+	Group     testGroup
+	Metadata  []byte `gorm:"type:jsonb"`
+	Secret    string `gorm:"->:false;<-:create"`
+	DeletedAt gorm.DeletedAt
+}
+
+type testStudent struct {
+	ID        uint
+	Name      string `json:"fullName"`
+	Age       int
+	CourseID  uint
+	Course    testCourse
+	Metadata  []byte `gorm:"type:jsonb"`
+	Password  string `gorm:"->:false;<-:create"`
+	DeletedAt gorm.DeletedAt
+}
+
+func mustFilter(tb testing.TB, raw string) fwork_server_orm.Filter {
+	tb.Helper()
+
+	var filter fwork_server_orm.Filter
+	if err := json.Unmarshal([]byte(raw), &filter); err != nil {
+		tb.Fatal(err)
+	}
+	return filter
+}
+
+// relation chains (course.group.name), JSONB paths on the root and inside a
+// relation, and the soft delete joins of the relations
+const benchFilter = `{
+	"name": {"$ilike": "jo%"},
+	"age": {"$gte": 18},
+	"course.title": "Go",
+	"course.group.name": {"$in": ["a", "b"]},
+	"course.metadata.level": "advanced",
+	"metadata.tags.0": "new"
+}`
+
+func BenchmarkApplyQueryFieldPaths(b *testing.B) {
+	db := dryRunDB(b)
+	payload := fwork_server_orm.QueryPayload{
+		Where: mustFilter(b, benchFilter),
+		Order: []fwork_server_orm.Order{{Field: "age"}},
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		builder := ApplyQuery(NewGormQueryBuilder(db.Model(new(testStudent))), payload)
+
+		var students []testStudent
+		if err := builder.Db.Find(&students).Error; err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkBuildFieldPathUncached(b *testing.B) {
+	root := schemaOf(dryRunDB(b), new(testStudent))
+	paths := []string{"name", "course.title", "course.group.name", "course.metadata.level", "metadata.tags.0"}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+
+	for i := 0; i < b.N; i++ {
+		for _, path := range paths {
+			buildFieldPath(root, path)
+		}
+	}
+}
+
+func TestResolveFieldPathCachesKnownPathsOnly(t *testing.T) {
+	root := schemaOf(dryRunDB(t), new(testStudent))
+
+	for path, known := range map[string]bool{
+		"name":                  true,
+		"fullName":              true,
+		"course.group.name":     true,
+		"course.metadata.level": true,
+		"metadata.tags.0":       true,
+		"nope":                  false,
+		"course.nope":           false,
+		"nope.tags.0":           false,
+		"course.group.nope":     false,
+	} {
+		if got := resolveFieldPath(root, path).Known; got != known {
+			t.Errorf("%s: Known = %v, want %v", path, got, known)
+		}
+
+		_, cached := fieldPathCache.Load(fieldPathKey{schema: root, path: path})
+		if cached != known {
+			t.Errorf("%s: cached = %v, want %v", path, cached, known)
+		}
+	}
+}
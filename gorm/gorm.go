@@ -1,7 +1,9 @@
 package fwork_server_gorm
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"reflect"
@@ -19,12 +21,9 @@ type GormQueryBuilder struct {
 }
 
 func NewGormQueryBuilder(db *gorm.DB) *GormQueryBuilder {
-	stmt := &gorm.Statement{DB: db}
-	_ = stmt.Parse(db.Statement.Model)
-
 	return &GormQueryBuilder{
 		Db:     db,
-		Schema: stmt.Schema,
+		Schema: schemaOf(db, db.Statement.Model),
 	}
 }
 
@@ -67,6 +66,25 @@ func (g *GormQueryBuilder) Clone() fwork_server_orm.QueryBuilder {
 }
 
 func ApplyQuery(builder *GormQueryBuilder, payload fwork_server_orm.QueryPayload) *GormQueryBuilder {
+	// mesma validação dos planos: nomes desconhecidos não chegam ao SQL
+	if builder.Schema != nil {
+		if err := validatePayloadFields(builder.Schema, payload); err != nil {
+			builder.Db.AddError(err)
+			return builder
+		}
+	}
+
+	// SOFT DELETE (antes do WHERE: os joins de relação dependem do Unscoped)
+	if payload.WithDeleted || payload.OnlyDeleted {
+		builder.Db = builder.Db.Unscoped()
+
+		if field := deletedAtField(builder.Schema); payload.OnlyDeleted && field != nil {
+			builder.Db = builder.Db.Where(
+				quoteTable(builder.Schema.Table) + "." + quoteIdent(field.DBName) + " IS NOT NULL",
+			)
+		}
+	}
+
 	// WHERE
 	ApplyJoinsFromFilter(builder.Db, builder.Db.Statement.Model, payload.Where)
 	builder = fwork_server_orm.ApplyFilter(builder, payload.Where, applyFieldExpr).(*GormQueryBuilder)
@@ -76,16 +94,18 @@ func ApplyQuery(builder *GormQueryBuilder, payload fwork_server_orm.QueryPayload
 		qualified := make([]string, 0, len(payload.Select))
 
 		for _, fieldName := range payload.Select {
-			if strings.Contains(fieldName, ".") {
-				// já vem qualificado (ex: relation.field)
-				qualified = append(qualified, fieldName)
+			if strings.Contains(fieldName, ".") && builder.Schema != nil {
+				// coluna da relação (relation.field), com o join
+				resolved := resolveFieldPath(builder.Schema, fieldName)
+				resolved.applyJoins(builder.Db)
+				qualified = append(qualified, resolved.SQLField)
 				continue
 			}
 
 			if builder.Schema != nil {
 				qualified = append(
 					qualified,
-					quoteIdent(builder.Schema.Table)+"."+quoteIdent(fieldName),
+					quoteTable(builder.Schema.Table)+"."+quoteIdent(columnName(builder.Schema, fieldName)),
 				)
 			} else {
 				qualified = append(qualified, quoteIdent(fieldName))
@@ -97,11 +117,14 @@ func ApplyQuery(builder *GormQueryBuilder, payload fwork_server_orm.QueryPayload
 
 	// ORDER
 	for _, o := range payload.Order {
-		dir := strings.ToUpper(o.Dir)
-		if dir != "ASC" && dir != "DESC" {
-			dir = "ASC"
+		column := columnName(builder.Schema, o.Field)
+		if strings.Contains(o.Field, ".") && builder.Schema != nil {
+			// relação (com o join) ou caminho JSONB
+			resolved := resolveFieldPath(builder.Schema, o.Field)
+			resolved.applyJoins(builder.Db)
+			column = resolved.SQLField
 		}
-		builder.Db = builder.Db.Order(o.Field + " " + dir)
+		builder.Db = builder.Db.Order(column + " " + o.Direction())
 	}
 
 	// LIMIT
@@ -180,14 +203,12 @@ func resolveRelationKeysFromModel(model any, path string, Db *gorm.DB) (pk strin
 	parts := strings.Split(path, ".")
 	relationName := parts[len(parts)-1]
 
-	stmt := &gorm.Statement{DB: Db}
-	_ = stmt.Parse(model)
-
-	if stmt.Schema == nil {
+	s := schemaOf(Db, model)
+	if s == nil {
 		return
 	}
 
-	rel, ok := stmt.Schema.Relationships.Relations[relationName]
+	rel, ok := s.Relationships.Relations[relationName]
 	if !ok {
 		return
 	}
@@ -206,14 +227,12 @@ func resolveRelationKeysFromModel(model any, path string, Db *gorm.DB) (pk strin
 }
 
 func getChildModel(parentModel any, relationName string, db *gorm.DB) any {
-	stmt := &gorm.Statement{DB: db}
-	_ = stmt.Parse(parentModel)
-
-	if stmt.Schema == nil {
+	s := schemaOf(db, parentModel)
+	if s == nil {
 		return nil
 	}
 
-	rel, ok := stmt.Schema.Relationships.Relations[relationName]
+	rel, ok := s.Relationships.Relations[relationName]
 	if !ok {
 		return nil
 	}
@@ -230,64 +249,18 @@ func contains(arr []string, s string) bool {
 	return false
 }
 
-func applyRelationJoinWithParentAlias(
-	db *gorm.DB,
-	parentModel any,
-	rel *schema.Relationship,
-	alias string,
-	parentAlias string,
-) {
-
-	var parentKey, childKey string
-	for _, ref := range rel.References {
-		parentKey = ref.PrimaryKey.DBName
-		childKey = ref.ForeignKey.DBName
-		break
-	}
-
-	parentTable := parentAlias
-	if parentTable == "" {
-		stmt := &gorm.Statement{DB: db}
-		_ = stmt.Parse(parentModel)
-		parentTable = stmt.Schema.Table
-	}
-
-	relationTable := rel.FieldSchema.Table
-	if strings.Contains(relationTable, ".") {
-		parts := strings.Split(relationTable, ".")
-		relationTableSchema := parts[0]
-		relationTableNameOnly := parts[1]
-		relationTable = quoteIdent(relationTableSchema) + "." + quoteIdent(relationTableNameOnly)
-	} else {
-		relationTable = quoteIdent(relationTable)
-	}
-
-	var join string
-	if rel.Type == schema.BelongsTo {
-		join = fmt.Sprintf(
-			`LEFT JOIN %s "%s" ON "%s"."%s" = "%s"."%s"`,
-			relationTable,
-			alias,
-			alias,
-			parentKey,
-			parentTable,
-			childKey,
-		)
-	} else {
-		join = fmt.Sprintf(
-			`LEFT JOIN %s "%s" ON "%s"."%s" = "%s"."%s"`,
-			relationTable,
-			alias,
-			alias,
-			childKey,
-			parentTable,
-			parentKey,
-		)
+// deletedAtField returns the gorm.DeletedAt field of the schema, if any.
+func deletedAtField(s *schema.Schema) *schema.Field {
+	if s == nil {
+		return nil
 	}
 
-	if !hasJoin(db, join) {
-		db.Joins(join)
+	for _, f := range s.Fields {
+		if f.FieldType == reflect.TypeOf(gorm.DeletedAt{}) && f.DBName != "" {
+			return f
+		}
 	}
+	return nil
 }
 
 func applyFieldExpr(builder fwork_server_orm.QueryBuilder, field string, expr fwork_server_orm.FieldExpr) fwork_server_orm.QueryBuilder {
@@ -296,187 +269,106 @@ func applyFieldExpr(builder fwork_server_orm.QueryBuilder, field string, expr fw
 		return builder
 	}
 
-	db := gormBuilder.Db
-	sqlField := ""
-	isJSONB := false
-
-	if strings.Contains(field, ".") {
-		parts := strings.Split(field, ".")
-		first := parts[0]
-		rest := parts[1:]
-
-		stmt := &gorm.Statement{DB: db}
-		_ = stmt.Parse(db.Statement.Model)
-
-		if stmt.Schema == nil {
-			return builder
-		}
-
-		// tenta resolver o PRIMEIRO nível como relação
-		relName := fwork_server_orm.SnakeToCamel(first)
-		_, isRelation := stmt.Schema.Relationships.Relations[relName]
-
-		customjsonop := expr.Op != nil && expr.Op.Op == "@>"
+	// schema da raiz: os sub-builders ($and / $or / $not) perdem o Model
+	sqlField, isJSONB, joins, ok := filterField(gormBuilder.Schema, field, expr)
+	if !ok {
+		return builder
+	}
 
-		if customjsonop {
-			sqlField = quoteIdent(first) // só a coluna raiz (subjects)
-			isJSONB = false              // não precisa cast
-		} else if !isRelation && !customjsonop {
-			// ❌ NÃO é relação → JSONB (igual antes)
-			isJSONB = true
-			column := first
-			path := rest
+	for _, join := range joins {
+		join.apply(gormBuilder.Db)
+	}
 
-			sqlField = fmt.Sprintf(
-				"%s #>> '{%s}'",
-				quoteIdent(column),
-				strings.Join(path, ","),
-			)
-		} else {
-			// ✅ É relação → agora seguimos cadeia ilimitada
-			currentModel := db.Statement.Model
-			currentAlias := ""
-			var currentRel *schema.Relationship
-
-			// for i := 0; i < len(parts)-1; i++ {
-			// 	relationSnake := parts[i]
-			// 	relationName := fwork_server_orm.SnakeToCamel(relationSnake)
-
-			// 	stmt := &gorm.Statement{DB: db}
-			// 	_ = stmt.Parse(currentModel)
-
-			// 	currentRel = stmt.Schema.Relationships.Relations[relationName]
-			// 	if currentRel == nil {
-			// 		return builder
-			// 	}
-
-			// 	applyRelationJoinWithParentAlias(db, currentModel, currentRel, relationSnake, currentAlias)
-
-			// 	currentModel = reflect.New(currentRel.FieldSchema.ModelType).Interface()
-			// 	currentAlias = relationSnake
-			// }
-			remainingParts := parts
-			for i := 0; i < len(parts)-1; i++ {
-				relationSnake := parts[i]
-				relationName := fwork_server_orm.SnakeToCamel(relationSnake)
-
-				stmt := &gorm.Statement{DB: db}
-				_ = stmt.Parse(currentModel)
-
-				currentRel = stmt.Schema.Relationships.Relations[relationName]
-				if currentRel == nil {
-					isJSONB = true
-					column := remainingParts[0]
-					path := remainingParts[1:]
-
-					sqlField = fmt.Sprintf(
-						"%s.%s #>> '{%s}'",
-						quoteIdent(currentAlias),
-						quoteIdent(column),
-						strings.Join(path, ","),
-					)
-
-					break
-
-					// newParts := strings.Join(remainingParts, ".")
-					// return applyFieldExpr(builder, newParts, expr)
-				}
+	for _, cond := range fieldExprConds(sqlField, isJSONB, expr) {
+		builder = builder.Where(cond.SQL, cond.Args...)
+	}
 
-				applyRelationJoinWithParentAlias(db, currentModel, currentRel, relationSnake, currentAlias)
+	return builder
+}
 
-				currentModel = reflect.New(currentRel.FieldSchema.ModelType).Interface()
-				currentAlias = relationSnake
+// filterField resolves the SQL expression of a filter field of root and the
+// relation joins it needs. ok is false for dotted fields of an unknown model.
+func filterField(root *schema.Schema, field string, expr fwork_server_orm.FieldExpr) (sqlField string, isJSONB bool, joins []resolvedJoin, ok bool) {
+	switch {
+	case strings.Contains(field, ".") && expr.Op != nil && expr.Op.Op == "@>":
+		return quoteIdent(strings.Split(field, ".")[0]), false, nil, true // só a coluna raiz (subjects), sem cast
 
-				remainingParts = remainingParts[1:]
-			}
+	case strings.Contains(field, "."):
+		if root == nil {
+			return "", false, nil, false
+		}
 
-			if !isJSONB {
-				// último item é o campo real
-				lastField := parts[len(parts)-1]
+		resolved := resolveFieldPath(root, field)
+		return resolved.SQLField, resolved.IsJSONB, resolved.Joins, true
 
-				stmt = &gorm.Statement{DB: db}
-				_ = stmt.Parse(currentModel)
+	case root != nil:
+		return resolveFieldPath(root, field).SQLField, false, nil, true
 
-				var dbFieldName string
-				for _, f := range stmt.Schema.Fields {
-					if f.Name == fwork_server_orm.SnakeToCamel(lastField) || f.DBName == lastField {
-						dbFieldName = f.DBName
-						break
-					}
-				}
+	default:
+		return quoteIdent(field), false, nil, true
+	}
+}
 
-				if dbFieldName == "" {
-					dbFieldName = lastField // fallback seguro
-				}
+// fieldCond is one condition of a FieldExpr on a field.
+type fieldCond struct {
+	SQL  string
+	Args []interface{}
+}
 
-				sqlField = quoteIdent(currentAlias) + "." + quoteIdent(dbFieldName)
-			}
-		}
-	} else {
-		if gormBuilder.Schema != nil {
-			tableName := gormBuilder.Schema.Table
-			if strings.Contains(tableName, ".") {
-				parts := strings.Split(tableName, ".")
-				tableNameSchema := parts[0]
-				tableNameOnly := parts[1]
-
-				tableName = quoteIdent(tableNameSchema) + "." + quoteIdent(tableNameOnly)
-				sqlField = tableName + "." + quoteIdent(field)
-			} else {
-				sqlField = quoteIdent(gormBuilder.Schema.Table) + "." + quoteIdent(field)
-			}
+// =========================
+// Operadores
+// =========================
 
-		} else {
-			sqlField = quoteIdent(field)
-		}
+// fieldExprConds translates the operators of expr, in a fixed order (see
+// FieldExpr.OperatorShape). fieldExprArgs must follow the same order.
+func fieldExprConds(sqlField string, isJSONB bool, expr fwork_server_orm.FieldExpr) []fieldCond {
+	conds := make([]fieldCond, 0, 2)
+	add := func(sql string, args ...interface{}) {
+		conds = append(conds, fieldCond{SQL: sql, Args: args})
 	}
 
-	// =========================
-	// Operadores
-	// =========================
-
 	if expr.Eq != nil {
-		builder = builder.Where(sqlField+" = ?", expr.Eq)
+		add(sqlField+" = ?", expr.Eq)
 	}
 
 	if expr.Ne != nil {
-		builder = builder.Where(sqlField+" <> ?", expr.Ne)
+		add(sqlField+" <> ?", expr.Ne)
 	}
 
 	if expr.Gt != nil {
-		builder = builder.Where(fwork_server_orm.CastIfJSONB(sqlField, isJSONB, expr.Gt)+" > ?", expr.Gt)
+		add(fwork_server_orm.CastIfJSONB(sqlField, isJSONB, expr.Gt)+" > ?", expr.Gt)
 	}
 
 	if expr.Gte != nil {
-		builder = builder.Where(fwork_server_orm.CastIfJSONB(sqlField, isJSONB, expr.Gte)+" >= ?", expr.Gte)
+		add(fwork_server_orm.CastIfJSONB(sqlField, isJSONB, expr.Gte)+" >= ?", expr.Gte)
 	}
 
 	if expr.Lt != nil {
-		builder = builder.Where(fwork_server_orm.CastIfJSONB(sqlField, isJSONB, expr.Lt)+" < ?", expr.Lt)
+		add(fwork_server_orm.CastIfJSONB(sqlField, isJSONB, expr.Lt)+" < ?", expr.Lt)
 	}
 
 	if expr.Lte != nil {
-		builder = builder.Where(fwork_server_orm.CastIfJSONB(sqlField, isJSONB, expr.Lte)+" <= ?", expr.Lte)
+		add(fwork_server_orm.CastIfJSONB(sqlField, isJSONB, expr.Lte)+" <= ?", expr.Lte)
 	}
 
 	if len(expr.In) > 0 {
-		builder = builder.Where(sqlField+" IN ?", expr.In)
+		add(sqlField+" IN ?", expr.In)
 	}
 
 	if len(expr.Nin) > 0 {
-		builder = builder.Where(sqlField+" NOT IN ?", expr.Nin)
+		add(sqlField+" NOT IN ?", expr.Nin)
 	}
 
 	if expr.Like != "" {
-		builder = builder.Where(sqlField+" LIKE ?", "%"+expr.Like+"%")
+		add(sqlField+" LIKE ?", "%"+expr.Like+"%")
 	}
 
 	if expr.ILike != "" {
-		builder = builder.Where(sqlField+" ILIKE ?", "%"+expr.ILike+"%")
+		add(sqlField+" ILIKE ?", "%"+expr.ILike+"%")
 	}
 
 	if len(expr.Between) == 2 {
-		builder = builder.Where(
+		add(
 			fwork_server_orm.CastIfJSONB(sqlField, isJSONB, expr.Between[0])+" BETWEEN ? AND ?",
 			expr.Between[0],
 			expr.Between[1],
@@ -485,47 +377,167 @@ func applyFieldExpr(builder fwork_server_orm.QueryBuilder, field string, expr fw
 
 	if expr.Exists != nil {
 		if *expr.Exists {
-			builder = builder.Where(sqlField + " IS NOT NULL")
+			add(sqlField + " IS NOT NULL")
 		} else {
-			builder = builder.Where(sqlField + " IS NULL")
+			add(sqlField + " IS NULL")
 		}
 	}
 
 	if expr.IsNull != nil {
 		if *expr.IsNull {
-			builder = builder.Where(sqlField + " IS NULL")
+			add(sqlField + " IS NULL")
 		} else {
-			builder = builder.Where(sqlField + " IS NOT NULL")
+			add(sqlField + " IS NOT NULL")
 		}
 	}
 
 	if expr.Op != nil {
-		builder = builder.Where(sqlField+" "+expr.Op.Op+" ?", expr.Op.Value)
+		add(sqlField+" "+expr.Op.Op+" ?", expr.Op.Value)
 	}
 
-	return builder
+	return conds
+}
+
+// fieldExprArgs appends the args of fieldExprConds(expr), one slice per
+// condition, without building the SQL.
+func fieldExprArgs(expr fwork_server_orm.FieldExpr, args [][]interface{}) [][]interface{} {
+	if expr.Eq != nil {
+		args = append(args, []interface{}{expr.Eq})
+	}
+	if expr.Ne != nil {
+		args = append(args, []interface{}{expr.Ne})
+	}
+	if expr.Gt != nil {
+		args = append(args, []interface{}{expr.Gt})
+	}
+	if expr.Gte != nil {
+		args = append(args, []interface{}{expr.Gte})
+	}
+	if expr.Lt != nil {
+		args = append(args, []interface{}{expr.Lt})
+	}
+	if expr.Lte != nil {
+		args = append(args, []interface{}{expr.Lte})
+	}
+	if len(expr.In) > 0 {
+		args = append(args, []interface{}{expr.In})
+	}
+	if len(expr.Nin) > 0 {
+		args = append(args, []interface{}{expr.Nin})
+	}
+	if expr.Like != "" {
+		args = append(args, []interface{}{"%" + expr.Like + "%"})
+	}
+	if expr.ILike != "" {
+		args = append(args, []interface{}{"%" + expr.ILike + "%"})
+	}
+	if len(expr.Between) == 2 {
+		args = append(args, []interface{}{expr.Between[0], expr.Between[1]})
+	}
+	if expr.Exists != nil {
+		args = append(args, nil)
+	}
+	if expr.IsNull != nil {
+		args = append(args, nil)
+	}
+	if expr.Op != nil {
+		args = append(args, []interface{}{expr.Op.Value})
+	}
+	return args
 }
 
 func quoteIdent(s string) string {
 	return `"` + s + `"`
 }
 
-func GormGetList[T any](db *gorm.DB, payload fwork_server_orm.QueryPayload) (fwork_server_orm.GetListData[T], error) {
+// quoteTable quotes a table name, keeping the schema prefix if any (schema.table).
+func quoteTable(table string) string {
+	if strings.Contains(table, ".") {
+		parts := strings.SplitN(table, ".", 2)
+		return quoteIdent(parts[0]) + "." + quoteIdent(parts[1])
+	}
+	return quoteIdent(table)
+}
+
+type ListConsistency string
+
+const (
+	// ListSequential runs count and data one after the other (default).
+	ListSequential ListConsistency = ""
+	// ListSnapshot runs both in a read-only repeatable-read transaction, so
+	// the count always matches the page.
+	ListSnapshot ListConsistency = "snapshot"
+	// ListConcurrent runs both at the same time on separate connections.
+	// Faster, but the count may disagree with the page under writes.
+	ListConcurrent ListConsistency = "concurrent"
+)
+
+type ListOptions[T any] struct {
+	Hooks *Hooks[T]
+
+	Consistency ListConsistency
+
+	// Plans, when set, compiles the count and data queries once per query
+	// shape (see PlanCache).
+	Plans *PlanCache
+}
+
+// applyListQuery applies payload to the model T through plans, when set.
+func applyListQuery[T any](db *gorm.DB, payload fwork_server_orm.QueryPayload, plans *PlanCache) (*gorm.DB, error) {
+	if plans != nil {
+		return plans.Apply(db.Model(new(T)), payload)
+	}
+
+	return ApplyQuery(NewGormQueryBuilder(db.Model(new(T))), payload).Db, nil
+}
+
+func GormGetList[T any](ctx context.Context, db *gorm.DB, payload fwork_server_orm.QueryPayload, opts ...ListOptions[T]) (fwork_server_orm.GetListData[T], error) {
+	db = db.WithContext(ctx)
+
+	var opt ListOptions[T]
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+
+	if err := opt.Hooks.beforeQuery(ctx, &payload); err != nil {
+		return fwork_server_orm.GetListData[T]{}, err
+	}
+
 	fwork_server_orm.ApplyPagination(&payload)
 
+	if !payload.Count.IsValid() {
+		return fwork_server_orm.GetListData[T]{}, badQuery("invalid count mode: %s", payload.Count)
+	}
+
 	// =========================
 	// 1) COUNT
 	// =========================
 
-	var total int64
+	countResult := fwork_server_orm.ListCount{Mode: payload.Count}
 
-	countPayload := fwork_server_orm.ExtractCountPayload(payload)
+	var count func(db *gorm.DB) error
 
-	countBuilder := NewGormQueryBuilder(db.Model(new(T)))
-	countBuilder = ApplyQuery(countBuilder, countPayload)
+	switch payload.Count {
+	case "", fwork_server_orm.CountExact, fwork_server_orm.CountEstimated:
+		count = func(db *gorm.DB) error {
+			countPayload := fwork_server_orm.ExtractCountPayload(payload)
 
-	if err := countBuilder.Db.Count(&total).Error; err != nil {
-		return fwork_server_orm.GetListData[T]{}, err
+			countQuery, err := applyListQuery[T](db, countPayload, opt.Plans)
+			if err != nil {
+				return err
+			}
+
+			if payload.Count == fwork_server_orm.CountEstimated {
+				if estimate, ok, err := estimateCount[T](countQuery); err != nil || ok {
+					countResult.Total = estimate
+					return err
+				}
+				// sem estimativa: conta de verdade
+				countResult.Mode = fwork_server_orm.CountExact
+			}
+
+			return countQuery.Count(&countResult.Total).Error
+		}
 	}
 
 	// =========================
@@ -534,10 +546,36 @@ func GormGetList[T any](db *gorm.DB, payload fwork_server_orm.QueryPayload) (fwo
 
 	var list []T
 
-	dataBuilder := NewGormQueryBuilder(db.Model(new(T)))
-	dataBuilder = ApplyQuery(dataBuilder, payload)
+	dataPayload := payload
+	hasMore := payload.Count == fwork_server_orm.CountHasMore && payload.Limit != nil
+	if hasMore {
+		limit := *payload.Limit + 1
+		dataPayload.Limit = &limit
+	}
+
+	find := func(db *gorm.DB) error {
+		dataQuery, err := applyListQuery[T](db, dataPayload, opt.Plans)
+		if err != nil {
+			return err
+		}
+
+		return dataQuery.Find(&list).Error
+	}
 
-	if err := dataBuilder.Db.Find(&list).Error; err != nil {
+	if err := runCountAndFind(ctx, db, opt.Consistency, count, find); err != nil {
+		return fwork_server_orm.GetListData[T]{}, err
+	}
+
+	if payload.Count == fwork_server_orm.CountHasMore {
+		hasNextPage := hasMore && len(list) > *payload.Limit
+		if hasNextPage {
+			list = list[:*payload.Limit]
+		}
+		countResult.HasNextPage = &hasNextPage
+	}
+
+	list, err := opt.Hooks.afterFind(ctx, list)
+	if err != nil {
 		return fwork_server_orm.GetListData[T]{}, err
 	}
 
@@ -547,33 +585,46 @@ func GormGetList[T any](db *gorm.DB, payload fwork_server_orm.QueryPayload) (fwo
 
 	return fwork_server_orm.GetListData[T]{
 		Payload:    list,
-		Pagination: fwork_server_orm.BuildPaginationMeta(payload, total),
+		Pagination: fwork_server_orm.BuildPaginationMetaFromCount(payload, countResult),
 	}, nil
 }
 
 func GormGetListHttp[T any](db *gorm.DB, r *http.Request, additionalWhere fwork_server_orm.Filter) (fwork_server_orm.GetListData[T], error) {
+	payload, err := QueryPayloadFromRequest(r)
+	if err != nil {
+		return fwork_server_orm.GetListData[T]{}, err
+	}
+
+	payload.Where = fwork_server_orm.MergeWhereWithAnd(payload.Where, additionalWhere)
+
+	// page -> skip
+	// It already exists in GormGetList.
+	// fwork_server_orm.ApplyPagination(&payload)
+
+	return GormGetList[T](r.Context(), db, payload)
+}
+
+func QueryPayloadFromRequest(r *http.Request) (fwork_server_orm.QueryPayload, error) {
 	var payload fwork_server_orm.QueryPayload
 
 	// where
 	if raw := r.URL.Query().Get("where"); raw != "" {
 		if err := json.Unmarshal([]byte(raw), &payload.Where); err != nil {
-			return fwork_server_orm.GetListData[T]{}, fmt.Errorf("invalid where json: %w", err)
+			return payload, badQuery("invalid where json: %w", err)
 		}
 	}
 
-	payload.Where = fwork_server_orm.MergeWhereWithAnd(payload.Where, additionalWhere)
-
 	// select
 	if raw := r.URL.Query().Get("select"); raw != "" {
 		if err := json.Unmarshal([]byte(raw), &payload.Select); err != nil {
-			return fwork_server_orm.GetListData[T]{}, fmt.Errorf("invalid select json: %w", err)
+			return payload, badQuery("invalid select json: %w", err)
 		}
 	}
 
 	// sort
 	if raw := r.URL.Query().Get("sort"); raw != "" {
 		if err := json.Unmarshal([]byte(raw), &payload.Order); err != nil {
-			return fwork_server_orm.GetListData[T]{}, fmt.Errorf("invalid sort json: %w", err)
+			return payload, badQuery("invalid sort json: %w", err)
 		}
 	}
 
@@ -581,7 +632,7 @@ func GormGetListHttp[T any](db *gorm.DB, r *http.Request, additionalWhere fwork_
 	if raw := r.URL.Query().Get("limit"); raw != "" {
 		var v int
 		if err := json.Unmarshal([]byte(raw), &v); err != nil {
-			return fwork_server_orm.GetListData[T]{}, fmt.Errorf("invalid limit: %w", err)
+			return payload, badQuery("invalid limit: %w", err)
 		}
 		payload.Limit = &v
 	}
@@ -590,7 +641,7 @@ func GormGetListHttp[T any](db *gorm.DB, r *http.Request, additionalWhere fwork_
 	if raw := r.URL.Query().Get("skip"); raw != "" {
 		var v int
 		if err := json.Unmarshal([]byte(raw), &v); err != nil {
-			return fwork_server_orm.GetListData[T]{}, fmt.Errorf("invalid skip: %w", err)
+			return payload, badQuery("invalid skip: %w", err)
 		}
 		payload.Offset = &v
 	}
@@ -599,7 +650,7 @@ func GormGetListHttp[T any](db *gorm.DB, r *http.Request, additionalWhere fwork_
 	if raw := r.URL.Query().Get("page"); raw != "" {
 		var v int
 		if err := json.Unmarshal([]byte(raw), &v); err != nil {
-			return fwork_server_orm.GetListData[T]{}, fmt.Errorf("invalid page: %w", err)
+			return payload, badQuery("invalid page: %w", err)
 		}
 		payload.Page = &v
 	}
@@ -607,11 +658,160 @@ func GormGetListHttp[T any](db *gorm.DB, r *http.Request, additionalWhere fwork_
 	// nested
 	payload.Nested = r.URL.Query().Get("nested")
 
-	// page -> skip
-	// It already exists in GormGetList.
-	// fwork_server_orm.ApplyPagination(&payload)
+	// soft delete
+	if raw := r.URL.Query().Get("withDeleted"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &payload.WithDeleted); err != nil {
+			return payload, badQuery("invalid withDeleted: %w", err)
+		}
+	}
+
+	if raw := r.URL.Query().Get("onlyDeleted"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &payload.OnlyDeleted); err != nil {
+			return payload, badQuery("invalid onlyDeleted: %w", err)
+		}
+	}
+
+	// count
+	payload.Count = fwork_server_orm.CountMode(r.URL.Query().Get("count"))
+	if !payload.Count.IsValid() {
+		return payload, badQuery("invalid count mode: %s", payload.Count)
+	}
+
+	return payload, nil
+}
+
+// GormGet loads a single record by key. The optional payload is used for
+// select / nested / where; pagination fields are ignored.
+func GormGet[T any](
+	ctx context.Context,
+	id any,
+	db *gorm.DB,
+	keyName string,
+	payload ...fwork_server_orm.QueryPayload,
+) (*T, error) {
+
+	db = db.WithContext(ctx)
+
+	var query fwork_server_orm.QueryPayload
+	if len(payload) > 0 {
+		query = payload[0]
+	}
+
+	query.Where = fwork_server_orm.MergeWhereWithAnd(query.Where, keyFilter(keyName, id))
+	query.Limit = nil
+	query.Offset = nil
+	query.Page = nil
+
+	builder := NewGormQueryBuilder(db.Model(new(T)))
+	builder = ApplyQuery(builder, query)
+
+	var item T
+	if err := builder.Db.Take(&item).Error; err != nil {
+		return nil, err
+	}
+
+	return &item, nil
+}
+
+type DeleteOptions[T any] struct {
+	Hooks *Hooks[T]
+}
+
+// GormDelete deletes a record by key. Models with gorm.DeletedAt are soft deleted.
+func GormDelete[T any](
+	ctx context.Context,
+	id any,
+	db *gorm.DB,
+	keyName string,
+	opts ...DeleteOptions[T],
+) error {
+
+	db = db.WithContext(ctx)
+
+	var opt DeleteOptions[T]
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+
+	return inTransaction(db, opt.Hooks.hasDelete(), func(tx *gorm.DB) error {
+		if err := opt.Hooks.beforeDelete(tx, id); err != nil {
+			return err
+		}
+
+		if err := deleteByKey[T](id, tx, keyName); err != nil {
+			return err
+		}
+
+		return opt.Hooks.afterDelete(tx, id)
+	})
+}
+
+func deleteByKey[T any](id any, db *gorm.DB, keyName string) error {
+	result := db.Where(fmt.Sprintf("%s = ?", keyName), id).Delete(new(T))
+	if result.Error != nil {
+		return result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	return nil
+}
+
+// GormHardDelete permanently deletes a record by key, including soft-deleted ones.
+func GormHardDelete[T any](
+	ctx context.Context,
+	id any,
+	db *gorm.DB,
+	keyName string,
+	opts ...DeleteOptions[T],
+) error {
+
+	db = db.WithContext(ctx)
+
+	return GormDelete[T](ctx, id, db.Unscoped(), keyName, opts...)
+}
+
+// GormRestore clears the gorm.DeletedAt field of a soft-deleted record.
+func GormRestore[T any](
+	ctx context.Context,
+	id any,
+	db *gorm.DB,
+	keyName string,
+) (*T, error) {
+
+	db = db.WithContext(ctx)
+
+	builder := NewGormQueryBuilder(db.Model(new(T)))
+
+	field := deletedAtField(builder.Schema)
+	if field == nil {
+		return nil, ErrSoftDeleteNotSupported
+	}
+
+	result := db.Unscoped().
+		Model(new(T)).
+		Where(fmt.Sprintf("%s = ?", keyName), id).
+		Where(quoteIdent(field.DBName)+" IS NOT NULL").
+		Update(field.DBName, nil)
+	if result.Error != nil {
+		return nil, result.Error
+	}
+
+	if result.RowsAffected == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
 
-	return GormGetList[T](db, payload)
+	return GormGet[T](ctx, id, db, keyName)
+}
+
+func keyFilter(keyName string, id any) fwork_server_orm.Filter {
+	return fwork_server_orm.Filter{
+		Fields: map[string]fwork_server_orm.FieldExpr{
+			keyName: {Eq: id},
+		},
+	}
 }
 
 func ApplyJoinsFromFilter(
@@ -644,28 +844,13 @@ func ensureJoin(db *gorm.DB, model any, fieldPath string) {
 		return
 	}
 
-	currentModel := model
-	parentAlias := "" // tabela raiz
-
-	for i := 0; i < len(parts)-1; i++ {
-		relationSnake := parts[i] // course, course_group
-		relationName := fwork_server_orm.SnakeToCamel(relationSnake)
-
-		stmt := &gorm.Statement{DB: db}
-		_ = stmt.Parse(currentModel)
-
-		rel, ok := stmt.Schema.Relationships.Relations[relationName]
-		if !ok {
-			return
-		}
-
-		alias := relationSnake
-
-		applyRelationJoinWithParentAlias(db, currentModel, rel, alias, parentAlias)
-
-		currentModel = reflect.New(rel.FieldSchema.ModelType).Interface()
-		parentAlias = alias
+	root := schemaOf(db, model)
+	if root == nil {
+		return
 	}
+
+	// joins de cada relação do caminho (course, course_group...)
+	resolveFieldPath(root, fieldPath).applyJoins(db)
 }
 
 func hasJoin(db *gorm.DB, alias string) bool {
@@ -703,6 +888,43 @@ func parseColumnName(tag string, fallback string) string {
 	return fallback
 }
 
+// lookUpField resolves a field by DB column, struct field name or JSON name.
+func lookUpField(s *schema.Schema, name string) *schema.Field {
+	if s == nil {
+		return nil
+	}
+
+	if f := s.LookUpField(name); f != nil {
+		return f
+	}
+
+	for _, f := range s.Fields {
+		if jsonFieldName(f) == name {
+			return f
+		}
+	}
+
+	return nil
+}
+
+// columnName translates a field name of the query (JSON name, field name or
+// column) to its column. Unknown and dotted names are kept.
+func columnName(s *schema.Schema, name string) string {
+	if strings.Contains(name, ".") {
+		return name
+	}
+
+	if field := lookUpField(s, name); field != nil && field.DBName != "" {
+		return field.DBName
+	}
+	return name
+}
+
+// jsonFieldName returns the name used by encoding/json for the field ("" if ignored).
+func jsonFieldName(f *schema.Field) string {
+	return jsonName(f.StructField)
+}
+
 func diffStruct[T any](old T, new T, keyName string) map[string]interface{} {
 	changes := make(map[string]interface{})
 
@@ -732,40 +954,127 @@ func diffStruct[T any](old T, new T, keyName string) map[string]interface{} {
 	return changes
 }
 
+var ErrSoftDeleteNotSupported = errors.New("model does not support soft delete")
+
 type PersistSanitizer interface {
 	SanitizeForPersist()
 }
 
+type CreateOptions[T any] struct {
+	Upsert *UpsertOptions
+
+	// Validator overrides DefaultStructValidator.
+	Validator StructValidator
+
+	Hooks *Hooks[T]
+}
+
+// GormCreate sanitizes, runs the BeforeCreate hooks, validates and inserts
+// payload. With create hooks everything runs in one transaction.
 func GormCreate[T any](
+	ctx context.Context,
 	payload T,
 	db *gorm.DB,
+	opts ...CreateOptions[T],
 ) (*T, error) {
 
+	db = db.WithContext(ctx)
+
+	var opt CreateOptions[T]
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+
 	// 🔴 sanitiza se o tipo suportar
 	if s, ok := any(&payload).(PersistSanitizer); ok {
 		s.SanitizeForPersist()
 	}
 
-	if err := db.Create(&payload).Error; err != nil {
+	err := inTransaction(db, opt.Hooks.hasCreate(), func(tx *gorm.DB) error {
+		if err := opt.Hooks.beforeCreate(tx, &payload); err != nil {
+			return err
+		}
+
+		if err := validateForPersist(&payload, opt.Validator); err != nil {
+			return err
+		}
+
+		if err := withUpsert[T](tx, opt.Upsert).Create(&payload).Error; err != nil {
+			return translateDBError[T](tx, err)
+		}
+
+		return opt.Hooks.afterCreate(tx, &payload)
+	})
+	if err != nil {
 		return nil, err
 	}
 
 	return &payload, nil
 }
 
+type UpdateOptions[T any] struct {
+	// Validator overrides DefaultStructValidator.
+	Validator StructValidator
+
+	Hooks *Hooks[T]
+}
+
 func GormUpdate[T any](
+	ctx context.Context,
+	payload T,
+	id any,
+	db *gorm.DB,
+	keyName string,
+	opts ...UpdateOptions[T],
+) (*T, error) {
+
+	var opt UpdateOptions[T]
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+
+	return gormUpdate(ctx, payload, id, db, keyName, opt, false)
+}
+
+// gormUpdate is GormUpdate; with mustExist a key that matches no row fails
+// with gorm.ErrRecordNotFound (RowsAffected is 0).
+func gormUpdate[T any](
+	ctx context.Context,
 	payload T,
 	id any,
 	db *gorm.DB,
 	keyName string,
+	opt UpdateOptions[T],
+	mustExist bool,
 ) (*T, error) {
 
+	db = db.WithContext(ctx)
+
 	// 🔴 sanitiza se o tipo suportar
 	if s, ok := any(&payload).(PersistSanitizer); ok {
 		s.SanitizeForPersist()
 	}
 
-	if err := db.Where(fmt.Sprintf("%s = ?", keyName), id).Updates(&payload).Error; err != nil {
+	err := inTransaction(db, opt.Hooks.hasUpdate(), func(tx *gorm.DB) error {
+		if err := opt.Hooks.beforeUpdate(tx, id, &payload); err != nil {
+			return err
+		}
+
+		if err := validateForUpdate(tx, keyName, id, &payload, opt.Validator); err != nil {
+			return err
+		}
+
+		result := tx.Where(fmt.Sprintf("%s = ?", keyName), id).Updates(&payload)
+		if result.Error != nil {
+			return translateDBError[T](tx, result.Error)
+		}
+		if mustExist && result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+
+		return opt.Hooks.afterUpdate(tx, id, &payload)
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -0,0 +1,177 @@
+package fwork_server_gorm
+
+import (
+	"context"
+
+	fwork_server_orm "github.com/joabssilveira/GoQLite/core"
+	"gorm.io/gorm"
+)
+
+type QueryHook func(ctx context.Context, payload *fwork_server_orm.QueryPayload) error
+
+type FindHook[T any] func(ctx context.Context, items []T) ([]T, error)
+
+type CreateHook[T any] func(ctx context.Context, tx *gorm.DB, item *T) error
+
+type UpdateHook[T any] func(ctx context.Context, tx *gorm.DB, id any, item *T) error
+
+type DeleteHook func(ctx context.Context, tx *gorm.DB, id any) error
+
+// Hooks are the lifecycle callbacks of a model. Each chain runs in order and
+// stops at the first error. Write hooks run in the same transaction as the
+// statement, so an error in an After hook rolls the write back.
+//
+//	hooks := &Hooks[User]{}
+//	hooks.BeforeQuery = append(hooks.BeforeQuery, func(ctx context.Context, p *QueryPayload) error {
+//		p.Where = MergeWhereWithAnd(p.Where, tenantFilter(ctx))
+//		return nil
+//	})
+//
+// Update / delete by filter do not run hooks.
+type Hooks[T any] struct {
+	// BeforeQuery can change the payload of list and get queries.
+	BeforeQuery []QueryHook
+	// AfterFind can transform the loaded rows.
+	AfterFind []FindHook[T]
+
+	BeforeCreate []CreateHook[T]
+	AfterCreate  []CreateHook[T]
+
+	BeforeUpdate []UpdateHook[T]
+	AfterUpdate  []UpdateHook[T]
+
+	BeforeDelete []DeleteHook
+	AfterDelete  []DeleteHook
+}
+
+func (h *Hooks[T]) beforeQuery(ctx context.Context, payload *fwork_server_orm.QueryPayload) error {
+	if h == nil {
+		return nil
+	}
+	for _, hook := range h.BeforeQuery {
+		if err := hook(ctx, payload); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h *Hooks[T]) afterFind(ctx context.Context, items []T) ([]T, error) {
+	if h == nil {
+		return items, nil
+	}
+	for _, hook := range h.AfterFind {
+		var err error
+		if items, err = hook(ctx, items); err != nil {
+			return nil, err
+		}
+	}
+	return items, nil
+}
+
+// withoutBeforeQuery is used by handlers that already ran BeforeQuery.
+func (h *Hooks[T]) withoutBeforeQuery() *Hooks[T] {
+	if h == nil {
+		return nil
+	}
+	c := *h
+	c.BeforeQuery = nil
+	return &c
+}
+
+func (h *Hooks[T]) hasCreate() bool {
+	return h != nil && len(h.BeforeCreate)+len(h.AfterCreate) > 0
+}
+
+func (h *Hooks[T]) hasUpdate() bool {
+	return h != nil && len(h.BeforeUpdate)+len(h.AfterUpdate) > 0
+}
+
+func (h *Hooks[T]) hasDelete() bool {
+	return h != nil && len(h.BeforeDelete)+len(h.AfterDelete) > 0
+}
+
+func (h *Hooks[T]) beforeCreate(tx *gorm.DB, item *T) error {
+	if h == nil {
+		return nil
+	}
+	return runCreateHooks(h.BeforeCreate, tx, item)
+}
+
+func (h *Hooks[T]) afterCreate(tx *gorm.DB, item *T) error {
+	if h == nil {
+		return nil
+	}
+	return runCreateHooks(h.AfterCreate, tx, item)
+}
+
+func (h *Hooks[T]) beforeUpdate(tx *gorm.DB, id any, item *T) error {
+	if h == nil {
+		return nil
+	}
+	return runUpdateHooks(h.BeforeUpdate, tx, id, item)
+}
+
+func (h *Hooks[T]) afterUpdate(tx *gorm.DB, id any, item *T) error {
+	if h == nil {
+		return nil
+	}
+	return runUpdateHooks(h.AfterUpdate, tx, id, item)
+}
+
+func (h *Hooks[T]) beforeDelete(tx *gorm.DB, id any) error {
+	if h == nil {
+		return nil
+	}
+	return runDeleteHooks(h.BeforeDelete, tx, id)
+}
+
+func (h *Hooks[T]) afterDelete(tx *gorm.DB, id any) error {
+	if h == nil {
+		return nil
+	}
+	return runDeleteHooks(h.AfterDelete, tx, id)
+}
+
+func runCreateHooks[T any](hooks []CreateHook[T], tx *gorm.DB, item *T) error {
+	for _, hook := range hooks {
+		if err := hook(dbContext(tx), tx, item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runUpdateHooks[T any](hooks []UpdateHook[T], tx *gorm.DB, id any, item *T) error {
+	for _, hook := range hooks {
+		if err := hook(dbContext(tx), tx, id, item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func runDeleteHooks(hooks []DeleteHook, tx *gorm.DB, id any) error {
+	for _, hook := range hooks {
+		if err := hook(dbContext(tx), tx, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// inTransaction runs fn in a transaction when withTx is set (nested calls use
+// savepoints), otherwise directly on db.
+func inTransaction(db *gorm.DB, withTx bool, fn func(tx *gorm.DB) error) error {
+	if !withTx {
+		return fn(db)
+	}
+	return db.Transaction(fn)
+}
+
+func dbContext(db *gorm.DB) context.Context {
+	if db.Statement != nil && db.Statement.Context != nil {
+		return db.Statement.Context
+	}
+	return context.Background()
+}
@@ -1,8 +1,14 @@
 package fwork_server_gorm
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
 	"net/http"
+	"time"
 
 	"github.com/gorilla/mux"
 	fwork_server_orm "github.com/joabssilveira/GoQLite/core"
@@ -12,15 +18,146 @@ import (
 // GET
 
 func GormListHandler[T any](db *gorm.DB) http.HandlerFunc {
+	return NewGormResource[T](db, "").ListHandler()
+}
+
+// ListHandler serves the list as JSON, or as a file when an export format
+// is requested (see GormExport).
+func (res *GormResource[T]) ListHandler() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		resp, err := GormGetListHttp[T](db, r, fwork_server_orm.Filter{})
+		payload, err := QueryPayloadFromRequest(r)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			res.writeError(w, r, err)
 			return
 		}
 
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(resp)
+		format, err := negotiateExportFormat(r)
+		if err != nil {
+			res.writeError(w, r, err)
+			return
+		}
+
+		if payload.RequestsDeleted() && !res.canSeeDeleted(r) {
+			res.writeError(w, r, errDeletedNotAllowed)
+			return
+		}
+
+		payload.Where = fwork_server_orm.MergeWhereWithAnd(payload.Where, res.scope(r))
+
+		if payload.Count == "" {
+			payload.Count = res.CountMode
+		}
+
+		// BeforeQuery roda aqui para que o ETag use o payload final
+		if err := res.Hooks.beforeQuery(r.Context(), &payload); err != nil {
+			res.writeError(w, r, err)
+			return
+		}
+
+		if format != nil {
+			res.writeExport(w, r, payload, *format)
+			return
+		}
+
+		useHash := res.Cache.ETag == ETagHash
+
+		if res.Cache.ETag == ETagUpdatedAt {
+			var version *resourceVersion
+			err := res.query(r, func(ctx context.Context, db *gorm.DB) (err error) {
+				version, err = collectionVersion[T](db, payload, res.Cache.updatedAtColumn(), r.URL.RawQuery)
+				return err
+			})
+			if err != nil {
+				res.writeError(w, r, err)
+				return
+			}
+
+			if version == nil {
+				useHash = true
+			} else if checkNotModified(w, r, version, res.Cache.CacheControl) {
+				return
+			}
+		}
+
+		var resp fwork_server_orm.GetListData[T]
+		err = res.query(r, func(ctx context.Context, db *gorm.DB) (err error) {
+			resp, err = GormGetList(ctx, db, payload, ListOptions[T]{Hooks: res.Hooks.withoutBeforeQuery(), Consistency: res.ListConsistency, Plans: res.Plans})
+			return err
+		})
+		if err != nil {
+			res.writeError(w, r, err)
+			return
+		}
+
+		if err := writeCachedJSON(w, r, resp, useHash, res.Cache.CacheControl); err != nil {
+			res.writeError(w, r, err)
+		}
+	}
+}
+
+func GormGetHandler[T any](db *gorm.DB, keyName string) http.HandlerFunc {
+	return NewGormResource[T](db, keyName).GetHandler()
+}
+
+// GetHandler serves a single record by the {id} route var. The select and
+// nested query params are honored.
+func (res *GormResource[T]) GetHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := mux.Vars(r)["id"]
+
+		payload, err := QueryPayloadFromRequest(r)
+		if err != nil {
+			res.writeError(w, r, err)
+			return
+		}
+
+		if payload.RequestsDeleted() && !res.canSeeDeleted(r) {
+			res.writeError(w, r, errDeletedNotAllowed)
+			return
+		}
+
+		payload.Where = fwork_server_orm.MergeWhereWithAnd(payload.Where, res.scope(r))
+
+		if err := res.Hooks.beforeQuery(r.Context(), &payload); err != nil {
+			res.writeError(w, r, err)
+			return
+		}
+
+		var item *T
+		err = res.query(r, func(ctx context.Context, db *gorm.DB) (err error) {
+			item, err = GormGet[T](ctx, id, db, res.KeyName, payload)
+			return err
+		})
+		if err != nil {
+			res.writeError(w, r, err)
+			return
+		}
+
+		items, err := res.Hooks.afterFind(r.Context(), []T{*item})
+		if err != nil {
+			res.writeError(w, r, err)
+			return
+		}
+		if len(items) == 0 {
+			res.writeError(w, r, gorm.ErrRecordNotFound)
+			return
+		}
+		item = &items[0]
+
+		useHash := res.Cache.ETag == ETagHash
+
+		if res.Cache.ETag == ETagUpdatedAt {
+			version := recordVersion(res.Db, item, res.Cache.updatedAtColumn(), r.URL.RawQuery)
+			if version == nil {
+				useHash = true
+			} else if checkNotModified(w, r, version, res.Cache.CacheControl) {
+				return
+			}
+		}
+
+		if err := writeCachedJSON(w, r, item, useHash, res.Cache.CacheControl); err != nil {
+			res.writeError(w, r, err)
+		}
 	}
 }
 
@@ -38,6 +175,10 @@ func GormCreateHandler[T any](
 	db *gorm.DB,
 	resolver ...CreatePayloadResolver[T],
 ) http.HandlerFunc {
+	return NewGormResource[T](db, "").CreateHandler(resolver...)
+}
+
+func (res *GormResource[T]) CreateHandler(resolver ...CreatePayloadResolver[T]) http.HandlerFunc {
 
 	// default
 	resolve := BodyPayloadResolver[T]
@@ -48,7 +189,13 @@ func GormCreateHandler[T any](
 	return func(w http.ResponseWriter, r *http.Request) {
 		payload, err := resolve(r)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			res.writeError(w, r, requestError(err))
+			return
+		}
+
+		upsert, err := res.upsert(r)
+		if err != nil {
+			res.writeError(w, r, err)
 			return
 		}
 
@@ -56,9 +203,13 @@ func GormCreateHandler[T any](
 		// 	http.Error(w, err.Error(), http.StatusInternalServerError)
 		// 	return
 		// }
-		created, err := GormCreate(payload, db)
+		var created *T
+		err = res.query(r, func(ctx context.Context, db *gorm.DB) (err error) {
+			created, err = GormCreate(ctx, payload, db, CreateOptions[T]{Upsert: upsert, Validator: res.Validator, Hooks: res.upsertScopeHooks(r, res.scopeHooks(r, res.Hooks, false), upsert)})
+			return err
+		})
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			res.writeError(w, r, err)
 			return
 		}
 
@@ -83,6 +234,10 @@ func GormUpdateHandler[T any](
 	keyName string,
 	resolver ...UpdateStructResolver[T],
 ) http.HandlerFunc {
+	return NewGormResource[T](db, keyName).UpdateHandler(resolver...)
+}
+
+func (res *GormResource[T]) UpdateHandler(resolver ...UpdateStructResolver[T]) http.HandlerFunc {
 
 	resolve := BodyStructResolver[T]
 	if len(resolver) > 0 && resolver[0] != nil {
@@ -94,13 +249,17 @@ func GormUpdateHandler[T any](
 
 		payload, err := resolve(r)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			res.writeError(w, r, requestError(err))
 			return
 		}
 
-		updated, err := GormUpdate(payload, id, db, keyName)
+		var updated *T
+		err = res.query(r, func(ctx context.Context, db *gorm.DB) (err error) {
+			updated, err = GormUpdate(ctx, payload, id, db, res.KeyName, UpdateOptions[T]{Validator: res.Validator, Hooks: res.scopeHooks(r, res.Hooks, false)})
+			return err
+		})
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			res.writeError(w, r, err)
 			return
 		}
 
@@ -109,3 +268,592 @@ func GormUpdateHandler[T any](
 		json.NewEncoder(w).Encode(updated)
 	}
 }
+
+// DELETE
+
+func (res *GormResource[T]) DeleteHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := mux.Vars(r)["id"]
+
+		err := res.query(r, func(ctx context.Context, db *gorm.DB) error {
+			return GormDelete(ctx, id, db, res.KeyName, DeleteOptions[T]{Hooks: res.scopeHooks(r, res.Hooks, false)})
+		})
+		if err != nil {
+			res.writeError(w, r, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// HardDeleteHandler permanently deletes the record {id}, also when soft
+// deleted. Needs AllowHardDelete (or AllowDeleted).
+func (res *GormResource[T]) HardDeleteHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := mux.Vars(r)["id"]
+
+		if !res.canHardDelete(r) {
+			res.writeError(w, r, errHardDeleteNotAllowed)
+			return
+		}
+
+		err := res.query(r, func(ctx context.Context, db *gorm.DB) error {
+			return GormHardDelete(ctx, id, db, res.KeyName, DeleteOptions[T]{Hooks: res.scopeHooks(r, res.Hooks, true)})
+		})
+		if err != nil {
+			res.writeError(w, r, err)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// RestoreHandler clears the deleted_at of the record {id}. Needs
+// AllowRestore (or AllowDeleted).
+func (res *GormResource[T]) RestoreHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := mux.Vars(r)["id"]
+
+		if !res.canRestore(r) {
+			res.writeError(w, r, errRestoreNotAllowed)
+			return
+		}
+
+		var restored *T
+		err := res.query(r, func(ctx context.Context, db *gorm.DB) (err error) {
+			return res.inScopeTx(r, db, id, true, func(tx *gorm.DB) (err error) {
+				restored, err = GormRestore[T](ctx, id, tx, res.KeyName)
+				return err
+			})
+		})
+		if err != nil {
+			res.writeError(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(restored)
+	}
+}
+
+// WHERE
+
+func (res *GormResource[T]) UpdateWhereHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var payload fwork_server_orm.UpdateWherePayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			res.writeError(w, r, requestError(err))
+			return
+		}
+
+		dryRun := payload.DryRun || r.URL.Query().Get("dryRun") == "true"
+
+		var affected int64
+		err := res.query(r, func(ctx context.Context, db *gorm.DB) (err error) {
+			affected, err = GormUpdateWhere[T](
+				ctx,
+				db,
+				payload.Where,
+				res.scope(r),
+				payload.Set,
+				WhereOptions{DryRun: dryRun},
+			)
+			return err
+		})
+		if err != nil {
+			res.writeError(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(fwork_server_orm.WriteResult{Affected: affected, DryRun: dryRun})
+	}
+}
+
+func (res *GormResource[T]) DeleteWhereHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var payload fwork_server_orm.DeleteWherePayload
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			res.writeError(w, r, requestError(err))
+			return
+		}
+
+		dryRun := payload.DryRun || r.URL.Query().Get("dryRun") == "true"
+
+		var affected int64
+		err := res.query(r, func(ctx context.Context, db *gorm.DB) (err error) {
+			affected, err = GormDeleteWhere[T](
+				ctx,
+				db,
+				payload.Where,
+				res.scope(r),
+				WhereOptions{DryRun: dryRun},
+			)
+			return err
+		})
+		if err != nil {
+			res.writeError(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(fwork_server_orm.WriteResult{Affected: affected, DryRun: dryRun})
+	}
+}
+
+// BULK
+
+func (res *GormResource[T]) BulkCreateHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var items []T
+		if err := json.NewDecoder(r.Body).Decode(&items); err != nil {
+			res.writeError(w, r, requestError(err))
+			return
+		}
+
+		upsert, err := res.upsert(r)
+		if err != nil {
+			res.writeError(w, r, err)
+			return
+		}
+
+		opts := res.Bulk
+		opts.Upsert = upsert
+		if opts.Validator == nil {
+			opts.Validator = res.Validator
+		}
+		if opts.Hooks == nil {
+			opts.Hooks = res.Hooks
+		}
+		opts.Hooks = res.upsertScopeHooks(r, res.scopeHooks(r, opts.Hooks, false), upsert)
+
+		var result fwork_server_orm.BulkResult[T]
+		err = res.query(r, func(ctx context.Context, db *gorm.DB) (err error) {
+			result, err = GormCreateBulk(ctx, items, db, opts)
+			return err
+		})
+		res.writeBulkResult(w, r, result, err, http.StatusCreated)
+	}
+}
+
+func (res *GormResource[T]) BulkUpdateHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var items []T
+		if err := json.NewDecoder(r.Body).Decode(&items); err != nil {
+			res.writeError(w, r, requestError(err))
+			return
+		}
+
+		opts := res.Bulk
+		if opts.Validator == nil {
+			opts.Validator = res.Validator
+		}
+		if opts.Hooks == nil {
+			opts.Hooks = res.Hooks
+		}
+		opts.Hooks = res.scopeHooks(r, opts.Hooks, false)
+
+		var result fwork_server_orm.BulkResult[T]
+		err := res.query(r, func(ctx context.Context, db *gorm.DB) (err error) {
+			result, err = GormUpdateBulk(ctx, items, db, res.KeyName, opts)
+			return err
+		})
+		res.writeBulkResult(w, r, result, err, http.StatusOK)
+	}
+}
+
+// BulkDeleteHandler expects a JSON array of keys.
+func (res *GormResource[T]) BulkDeleteHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// json.Number: ids acima de 2^53 não passam por float64
+		decoder := json.NewDecoder(r.Body)
+		decoder.UseNumber()
+
+		var keys []any
+		if err := decoder.Decode(&keys); err != nil {
+			res.writeError(w, r, requestError(err))
+			return
+		}
+
+		opts := res.Bulk
+		if opts.Hooks == nil {
+			opts.Hooks = res.Hooks
+		}
+		opts.Hooks = res.scopeHooks(r, opts.Hooks, false)
+
+		var result fwork_server_orm.BulkResult[T]
+		err := res.query(r, func(ctx context.Context, db *gorm.DB) (err error) {
+			result, err = GormDeleteBulk(ctx, keys, db, res.KeyName, opts)
+			return err
+		})
+		res.writeBulkResult(w, r, result, err, http.StatusOK)
+	}
+}
+
+// IMPORT
+
+// ImportHandler creates the records of a CSV / NDJSON upload: the request
+// body, or the first file of a multipart form. ?mode=upsert upserts them
+// (needs Import.Upsert or Upsert) and ?dryRun=true only reports. Answers the
+// import report with the bulk statuses (see writeBulkResult).
+func (res *GormResource[T]) ImportHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+
+		opts := res.Import
+		if opts.Validator == nil {
+			opts.Validator = res.Validator
+		}
+		if opts.Hooks == nil {
+			opts.Hooks = res.Hooks
+		}
+		opts.Hooks = res.scopeHooks(r, opts.Hooks, false)
+		if query.Get("dryRun") == "true" {
+			opts.DryRun = true
+		}
+
+		switch query.Get("mode") {
+		case "":
+		case "create":
+			opts.Mode = ImportCreate
+		case "upsert":
+			if opts.Upsert == nil {
+				opts.Upsert = res.Upsert
+			}
+			if opts.Upsert == nil {
+				res.writeError(w, r, errUpsertDisabled)
+				return
+			}
+			opts.Mode = ImportUpsert
+		default:
+			res.writeError(w, r, badQuery("invalid import mode: %s", query.Get("mode")))
+			return
+		}
+
+		if opts.Mode == ImportUpsert {
+			var upsert UpsertOptions
+			if opts.Upsert != nil {
+				upsert = *opts.Upsert
+			}
+			opts.Hooks = res.upsertScopeHooks(r, opts.Hooks, &upsert)
+		}
+
+		body, format, err := importUpload(r)
+		if err != nil {
+			res.writeError(w, r, requestError(err))
+			return
+		}
+
+		var result fwork_server_orm.ImportResult
+		err = withQueryTimeout(r.Context(), res.Db, opts.Timeout, func(ctx context.Context, db *gorm.DB) (err error) {
+			result, err = GormImport(ctx, db, body, format, opts)
+			return err
+		})
+
+		okStatus := http.StatusCreated
+		if opts.DryRun {
+			okStatus = http.StatusOK
+		}
+
+		status, err := bulkStatus(err, result.Failed, okStatus)
+		if err != nil {
+			res.writeError(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(status)
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
+// importUpload returns the file of an import request and its format.
+func importUpload(r *http.Request) (io.Reader, ImportFormat, error) {
+	name := r.URL.Query().Get("format")
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType != "multipart/form-data" {
+		format, err := detectImportFormat(name, r.Header.Get("Content-Type"), "")
+		return r.Body, format, err
+	}
+
+	parts, err := r.MultipartReader()
+	if err != nil {
+		return nil, ImportFormat{}, err
+	}
+
+	for {
+		part, err := parts.NextPart()
+		if err == io.EOF {
+			return nil, ImportFormat{}, badQuery("no file in the multipart form")
+		}
+		if err != nil {
+			return nil, ImportFormat{}, err
+		}
+
+		if part.FileName() == "" {
+			continue
+		}
+
+		format, err := detectImportFormat(name, part.Header.Get("Content-Type"), part.FileName())
+		return part, format, err
+	}
+}
+
+// EXPORT JOBS
+
+var errExportJobsDisabled = errors.New("export jobs are not enabled for this resource")
+
+// StartExportJobHandler starts a background export of the ExportJobPayload
+// body (see ExportJobs) and answers 202 with the job. The query goes through
+// the same checks as the list handler (deleted rows, Scope, BeforeQuery).
+func (res *GormResource[T]) StartExportJobHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if res.Jobs == nil {
+			res.writeError(w, r, fwork_server_orm.NewError(fwork_server_orm.ErrorNotFound, errExportJobsDisabled.Error(), nil))
+			return
+		}
+
+		var body fwork_server_orm.ExportJobPayload
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			res.writeError(w, r, requestError(err))
+			return
+		}
+
+		if body.Format == "" {
+			body.Format = ExportCSV.Name
+		}
+		format, ok := LookupExportFormat(body.Format)
+		if !ok {
+			res.writeError(w, r, badQuery("unsupported format: %s", body.Format))
+			return
+		}
+
+		payload := body.Query
+
+		if payload.RequestsDeleted() && !res.canSeeDeleted(r) {
+			res.writeError(w, r, errDeletedNotAllowed)
+			return
+		}
+
+		payload.Where = fwork_server_orm.MergeWhereWithAnd(payload.Where, res.scope(r))
+
+		if err := res.Hooks.beforeQuery(r.Context(), &payload); err != nil {
+			res.writeError(w, r, err)
+			return
+		}
+
+		// erros de campo antes de criar o job
+		if s := schemaOf(res.Db, new(T)); s != nil {
+			if err := validatePayloadFields(s, payload); err != nil {
+				res.writeError(w, r, err)
+				return
+			}
+			if _, err := exportColumns(s, payload); err != nil {
+				res.writeError(w, r, err)
+				return
+			}
+		}
+
+		opts := res.Export
+		if opts.Hooks == nil {
+			opts.Hooks = res.Hooks
+		}
+		opts.Hooks = opts.Hooks.withoutBeforeQuery()
+
+		owner, err := res.jobOwner(r)
+		if err != nil {
+			res.writeError(w, r, err)
+			return
+		}
+
+		job, err := startExportJob(r.Context(), res.Jobs, res.Db, payload, format, opts, owner)
+		if err != nil {
+			res.writeError(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusAccepted)
+		json.NewEncoder(w).Encode(job)
+	}
+}
+
+// ExportJobHandler answers the status / progress of the job {id}.
+func (res *GormResource[T]) ExportJobHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		job, err := res.exportJob(r)
+		if err != nil {
+			res.writeError(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		json.NewEncoder(w).Encode(job)
+	}
+}
+
+// ExportJobDownloadHandler serves the file of the job {id} (409 until it is
+// done).
+func (res *GormResource[T]) ExportJobDownloadHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		job, err := res.exportJob(r)
+		if err != nil {
+			res.writeError(w, r, err)
+			return
+		}
+
+		if job.Status != ExportJobDone {
+			res.writeError(w, r, fwork_server_orm.NewError(fwork_server_orm.ErrorConflict, "export job is "+string(job.Status), nil))
+			return
+		}
+
+		file, err := res.Jobs.Storage.Open(r.Context(), job.File)
+		if err != nil {
+			res.writeError(w, r, err)
+			return
+		}
+		defer file.Close()
+
+		if format, ok := LookupExportFormat(job.Format); ok {
+			w.Header().Set("Content-Type", format.ContentType)
+		}
+		w.Header().Set("Content-Disposition", `attachment; filename="`+job.FileName+`"`)
+
+		if seeker, ok := file.(io.ReadSeeker); ok {
+			var modified time.Time
+			if job.FinishedAt != nil {
+				modified = *job.FinishedAt
+			}
+			http.ServeContent(w, r, job.FileName, modified, seeker)
+			return
+		}
+
+		w.WriteHeader(http.StatusOK)
+		io.Copy(w, file)
+	}
+}
+
+// exportJob loads the job {id} of this resource.
+func (res *GormResource[T]) exportJob(r *http.Request) (*ExportJob, error) {
+	if res.Jobs == nil {
+		return nil, fwork_server_orm.NewError(fwork_server_orm.ErrorNotFound, errExportJobsDisabled.Error(), nil)
+	}
+
+	job, err := res.Jobs.Store.Get(r.Context(), mux.Vars(r)["id"])
+	if errors.Is(err, ErrJobNotFound) {
+		return nil, fwork_server_orm.NewError(fwork_server_orm.ErrorNotFound, err.Error(), err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// jobs de outro recurso (store compartilhado) não aparecem aqui
+	if s := schemaOf(res.Db, new(T)); s != nil && job.Resource != s.Table {
+		return nil, fwork_server_orm.NewError(fwork_server_orm.ErrorNotFound, ErrJobNotFound.Error(), ErrJobNotFound)
+	}
+
+	// nem os de outro usuário / tenant
+	owner, err := res.jobOwner(r)
+	if err != nil {
+		return nil, err
+	}
+	if job.Owner != owner.Owner || job.Scope != owner.Scope {
+		return nil, fwork_server_orm.NewError(fwork_server_orm.ErrorNotFound, ErrJobNotFound.Error(), ErrJobNotFound)
+	}
+
+	return job, nil
+}
+
+// writeBulkResult: 2xx when every item succeeded, 207 when some items failed
+// in best-effort mode and 422 when the transaction was rolled back.
+func (res *GormResource[T]) writeBulkResult(w http.ResponseWriter, r *http.Request, result fwork_server_orm.BulkResult[T], err error, okStatus int) {
+	status, err := bulkStatus(err, result.Failed, okStatus)
+	if err != nil {
+		res.writeError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(result)
+}
+
+// bulkStatus returns the status of a bulk result, or the error when there is
+// no result to send.
+func bulkStatus(err error, failed int, okStatus int) (int, error) {
+	switch {
+	case errors.Is(err, ErrBulkRolledBack):
+		return http.StatusUnprocessableEntity, nil
+	case err != nil:
+		return 0, err
+	case failed > 0:
+		return http.StatusMultiStatus, nil
+	}
+	return okStatus, nil
+}
+
+// ERRORS
+
+var (
+	errDeletedNotAllowed    = fwork_server_orm.NewError(fwork_server_orm.ErrorForbidden, "deleted records are not available", nil)
+	errHardDeleteNotAllowed = fwork_server_orm.NewError(fwork_server_orm.ErrorForbidden, "hard delete is not allowed", nil)
+	errRestoreNotAllowed    = fwork_server_orm.NewError(fwork_server_orm.ErrorForbidden, "restore is not allowed", nil)
+	errUpsertDisabled       = fwork_server_orm.NewError(fwork_server_orm.ErrorBadQuery, "upsert is not enabled for this resource", nil)
+)
+
+// badQuery is an error caused by the request: 400, with its message.
+func badQuery(format string, args ...any) error {
+	return fwork_server_orm.NewError(fwork_server_orm.ErrorBadQuery, "", fmt.Errorf(format, args...))
+}
+
+// requestError classifies the errors of reading the request (body, upload,
+// payload resolvers) as bad queries. Typed errors keep their kind.
+func requestError(err error) error {
+	var e *fwork_server_orm.Error
+	if errors.As(err, &e) {
+		return err
+	}
+	return fwork_server_orm.NewError(fwork_server_orm.ErrorBadQuery, "", err)
+}
+
+// writeError sends err to the resource ErrorResponder (problem+json by
+// default).
+func (res *GormResource[T]) writeError(w http.ResponseWriter, r *http.Request, err error) {
+	responder := res.ErrorResponder
+	if responder == nil {
+		responder = fwork_server_orm.DefaultErrorResponder
+	}
+
+	responder.RespondError(w, r, HttpError(err))
+}
+
+// HttpError classifies the errors returned by this package: record not found
+// is a not found error, refused filters are bad queries and validation
+// errors keep their field errors. Any other error (database, hooks) is an
+// internal error, so its message does not reach the client.
+func HttpError(err error) *fwork_server_orm.Error {
+	var e *fwork_server_orm.Error
+	if errors.As(err, &e) {
+		return e
+	}
+
+	switch {
+	case isQueryTimeout(err):
+		return fwork_server_orm.NewError(fwork_server_orm.ErrorTimeout, "query timed out", err)
+	case errors.Is(err, context.Canceled):
+		return fwork_server_orm.NewError(fwork_server_orm.ErrorUnavailable, "request cancelled", err)
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return fwork_server_orm.NewError(fwork_server_orm.ErrorNotFound, "", err)
+	case errors.Is(err, ErrEmptyFilter), errors.Is(err, ErrSoftDeleteNotSupported):
+		return fwork_server_orm.NewError(fwork_server_orm.ErrorBadQuery, "", err)
+	}
+
+	return fwork_server_orm.AsError(err, fwork_server_orm.ErrorInternal)
+}
@@ -0,0 +1,326 @@
+package fwork_server_gorm
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	fwork_server_orm "github.com/joabssilveira/GoQLite/core"
+	"gorm.io/gorm"
+)
+
+func TestHttpErrorKinds(t *testing.T) {
+	for name, tc := range map[string]struct {
+		err  error
+		want fwork_server_orm.ErrorKind
+	}{
+		"driver":       {errors.New(`pq: relation "users" does not exist`), fwork_server_orm.ErrorInternal},
+		"wrapped":      {fmt.Errorf("hook: %w", errors.New("boom")), fwork_server_orm.ErrorInternal},
+		"not found":    {gorm.ErrRecordNotFound, fwork_server_orm.ErrorNotFound},
+		"empty filter": {ErrEmptyFilter, fwork_server_orm.ErrorBadQuery},
+		"bad query":    {badQuery("invalid count mode: %s", "bogus"), fwork_server_orm.ErrorBadQuery},
+		"request":      {requestError(errors.New("unexpected EOF")), fwork_server_orm.ErrorBadQuery},
+		"typed":        {requestError(errDeletedNotAllowed), fwork_server_orm.ErrorForbidden},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if got := HttpError(tc.err).Kind; got != tc.want {
+				t.Errorf("HttpError(%v).Kind = %s, want %s", tc.err, got, tc.want)
+			}
+		})
+	}
+}
+
+type testCachedRow struct {
+	ID        uint      `json:"id"`
+	Name      string    `json:"name"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+func cachedResource(t *testing.T, mode ETagMode) *GormResource[testCachedRow] {
+	db := sqliteDB(t, &testCachedRow{})
+
+	rows := []testCachedRow{
+		{ID: 1, Name: "one", UpdatedAt: time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)},
+		{ID: 2, Name: "two", UpdatedAt: time.Date(2024, 5, 2, 10, 0, 0, 0, time.UTC)},
+	}
+	if err := db.Create(&rows).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	res := NewGormResource[testCachedRow](db, "id")
+	res.Cache = CacheOptions{ETag: mode, CacheControl: "private, max-age=0"}
+	return res
+}
+
+func TestEtagMatches(t *testing.T) {
+	for header, want := range map[string]bool{
+		`"abc"`:           true,
+		`W/"abc"`:         true,
+		`"x", "abc"`:      true,
+		`*`:               true,
+		`"abcd"`:          false,
+		`"x", W/"y"`:      false,
+		`W/"abc", "x"`:    true,
+		` "abc" `:         true,
+		`"ABC"`:           false,
+		`W/"x",  W/"abc"`: true,
+	} {
+		if got := etagMatches(header, `W/"abc"`); got != want {
+			t.Errorf("etagMatches(%q) = %v, want %v", header, got, want)
+		}
+	}
+
+	if etagMatches("*", "") {
+		t.Error("etagMatches matched an empty ETag")
+	}
+}
+
+func TestListHandlerHashETag(t *testing.T) {
+	res := cachedResource(t, ETagHash)
+	handler := res.ListHandler()
+
+	w := serve(handler, "GET", "/rows", "")
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || !strings.HasPrefix(etag, `"`) {
+		t.Fatalf("status = %d, ETag = %q, want 200 and a strong ETag", w.Code, etag)
+	}
+	if got := w.Header().Get("Cache-Control"); got != "private, max-age=0" {
+		t.Errorf("Cache-Control = %q", got)
+	}
+	if want := strongETag(w.Body.Bytes()); etag != want {
+		t.Errorf("ETag = %s, want the hash of the body %s", etag, want)
+	}
+
+	for _, inm := range []string{etag, "W/" + etag, `"other", ` + etag} {
+		w = serve(handler, "GET", "/rows", "", "If-None-Match", inm)
+		if w.Code != http.StatusNotModified || w.Body.Len() != 0 {
+			t.Errorf("If-None-Match %s: status = %d, body = %q, want an empty 304", inm, w.Code, w.Body)
+		}
+	}
+
+	res.Db.Model(&testCachedRow{ID: 1}).Update("name", "uno")
+
+	w = serve(handler, "GET", "/rows", "", "If-None-Match", etag)
+	if w.Code != http.StatusOK || w.Header().Get("ETag") == etag {
+		t.Errorf("after an update: status = %d, ETag = %s, want 200 and a new ETag", w.Code, w.Header().Get("ETag"))
+	}
+}
+
+func TestListHandlerCollectionETag(t *testing.T) {
+	res := cachedResource(t, ETagUpdatedAt)
+	handler := res.ListHandler()
+
+	// count | max(updated_at) | query string
+	maxUpdatedAt := time.Date(2024, 5, 2, 10, 0, 0, 0, time.UTC)
+	want := weakETag(fmt.Sprintf("%d|%d|%s", 2, maxUpdatedAt.UnixNano(), "limit=10"))
+
+	w := serve(handler, "GET", "/rows?limit=10", "")
+	etag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || etag != want {
+		t.Fatalf("status = %d, ETag = %s, want 200 and %s", w.Code, etag, want)
+	}
+	if w.Header().Get("Last-Modified") != "" {
+		t.Errorf("collections must not send Last-Modified")
+	}
+
+	w = serve(handler, "GET", "/rows?limit=10", "", "If-None-Match", etag)
+	if w.Code != http.StatusNotModified {
+		t.Errorf("If-None-Match: status = %d, want 304", w.Code)
+	}
+
+	if other := serve(handler, "GET", "/rows?limit=1", "").Header().Get("ETag"); other == etag {
+		t.Errorf("another query string has the same ETag %s", other)
+	}
+
+	// apagar a linha mais antiga não move o max(updated_at): o count muda o ETag
+	res.Db.Delete(&testCachedRow{ID: 1})
+
+	w = serve(handler, "GET", "/rows?limit=10", "", "If-None-Match", etag)
+	if w.Code != http.StatusOK || w.Header().Get("ETag") == etag {
+		t.Errorf("after a delete: status = %d, ETag = %s, want 200 and a new ETag", w.Code, w.Header().Get("ETag"))
+	}
+	etag = w.Header().Get("ETag")
+
+	res.Db.Model(&testCachedRow{ID: 2}).Update("name", "dos")
+
+	w = serve(handler, "GET", "/rows?limit=10", "", "If-None-Match", etag)
+	if w.Code != http.StatusOK || w.Header().Get("ETag") == etag {
+		t.Errorf("after an update: status = %d, ETag = %s, want 200 and a new ETag", w.Code, w.Header().Get("ETag"))
+	}
+}
+
+func TestGetHandlerRecordETag(t *testing.T) {
+	res := cachedResource(t, ETagUpdatedAt)
+
+	router := mux.NewRouter()
+	router.HandleFunc("/rows/{id}", res.GetHandler())
+
+	updatedAt := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	w := serve(router, "GET", "/rows/1", "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+	etag := w.Header().Get("ETag")
+	if want := weakETag(fmt.Sprintf("%d|%s", updatedAt.UnixNano(), "")); etag != want {
+		t.Errorf("ETag = %s, want %s", etag, want)
+	}
+	if got := w.Header().Get("Last-Modified"); got != updatedAt.Format(http.TimeFormat) {
+		t.Errorf("Last-Modified = %q", got)
+	}
+
+	for name, header := range map[string][]string{
+		"if-none-match":     {"If-None-Match", etag},
+		"if-modified-since": {"If-Modified-Since", updatedAt.Format(http.TimeFormat)},
+	} {
+		if w := serve(router, "GET", "/rows/1", "", header...); w.Code != http.StatusNotModified {
+			t.Errorf("%s: status = %d, want 304", name, w.Code)
+		}
+	}
+
+	// If-None-Match diferente vence o If-Modified-Since
+	w = serve(router, "GET", "/rows/1", "", "If-None-Match", `W/"other"`, "If-Modified-Since", updatedAt.Format(http.TimeFormat))
+	if w.Code != http.StatusOK {
+		t.Errorf("stale If-None-Match: status = %d, want 200", w.Code)
+	}
+}
+
+type testSoftRow struct {
+	ID        uint           `json:"id"`
+	Name      string         `json:"name"`
+	DeletedAt gorm.DeletedAt `json:"deletedAt"`
+}
+
+// softResource has rows 1 (live) and 2 (soft deleted).
+func softResource(t *testing.T) (*GormResource[testSoftRow], *mux.Router) {
+	db := sqliteDB(t, &testSoftRow{})
+	if err := db.Create(&[]testSoftRow{{ID: 1, Name: "live"}, {ID: 2, Name: "deleted"}}).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Delete(&testSoftRow{ID: 2}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	res := NewGormResource[testSoftRow](db, "id")
+
+	router := mux.NewRouter()
+	router.HandleFunc("/rows", res.ListHandler()).Methods("GET")
+	router.HandleFunc("/rows/{id}", res.HardDeleteHandler()).Methods("DELETE")
+	router.HandleFunc("/rows/{id}/restore", res.RestoreHandler()).Methods("POST")
+	return res, router
+}
+
+func allowHeader(name string) func(r *http.Request) bool {
+	return func(r *http.Request) bool { return r.Header.Get(name) == "yes" }
+}
+
+func TestListHandlerDeletedRows(t *testing.T) {
+	res, router := softResource(t)
+
+	for _, query := range []string{"withDeleted=true", "onlyDeleted=true"} {
+		if w := serve(router, "GET", "/rows?"+query, ""); w.Code != http.StatusForbidden {
+			t.Errorf("%s without AllowDeleted: status = %d, want 403", query, w.Code)
+		}
+	}
+
+	res.AllowDeleted = func(r *http.Request) bool { return true }
+
+	for query, want := range map[string][]uint{
+		"":                 {1},
+		"withDeleted=true": {1, 2},
+		"onlyDeleted=true": {2},
+	} {
+		w := serve(router, "GET", `/rows?sort=[{"field":"id"}]&`+query, "")
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: status = %d: %s", query, w.Code, w.Body)
+		}
+
+		var resp fwork_server_orm.GetListData[testSoftRow]
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+
+		var ids []uint
+		for _, row := range resp.Payload {
+			ids = append(ids, row.ID)
+		}
+		if !reflect.DeepEqual(ids, want) {
+			t.Errorf("%q: ids = %v, want %v", query, ids, want)
+		}
+	}
+}
+
+func TestHardDeleteAndRestoreNeedAuthorization(t *testing.T) {
+	for name, tc := range map[string]struct {
+		configure func(res *GormResource[testSoftRow])
+		header    []string
+		hard      int
+		restore   int
+	}{
+		"nothing allowed": {
+			configure: func(res *GormResource[testSoftRow]) {},
+			hard:      http.StatusForbidden,
+			restore:   http.StatusForbidden,
+		},
+		"allow deleted": {
+			configure: func(res *GormResource[testSoftRow]) { res.AllowDeleted = allowHeader("X-Admin") },
+			header:    []string{"X-Admin", "yes"},
+			hard:      http.StatusNoContent,
+			restore:   http.StatusOK,
+		},
+		"allow deleted, other requester": {
+			configure: func(res *GormResource[testSoftRow]) { res.AllowDeleted = allowHeader("X-Admin") },
+			hard:      http.StatusForbidden,
+			restore:   http.StatusForbidden,
+		},
+		"own checks win": {
+			configure: func(res *GormResource[testSoftRow]) {
+				res.AllowDeleted = allowHeader("X-Admin")
+				res.AllowHardDelete = allowHeader("X-Owner")
+				res.AllowRestore = allowHeader("X-Owner")
+			},
+			header:  []string{"X-Admin", "yes"},
+			hard:    http.StatusForbidden,
+			restore: http.StatusForbidden,
+		},
+		"restore only": {
+			configure: func(res *GormResource[testSoftRow]) { res.AllowRestore = allowHeader("X-Admin") },
+			header:    []string{"X-Admin", "yes"},
+			hard:      http.StatusForbidden,
+			restore:   http.StatusOK,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			res, router := softResource(t)
+			tc.configure(res)
+
+			w := serve(router, "POST", "/rows/2/restore", "", tc.header...)
+			if w.Code != tc.restore {
+				t.Errorf("restore: status = %d, want %d: %s", w.Code, tc.restore, w.Body)
+			}
+
+			var restored int64
+			res.Db.Model(new(testSoftRow)).Where("id = 2").Count(&restored)
+			if (restored == 1) != (tc.restore == http.StatusOK) {
+				t.Errorf("restore: row 2 visible = %v", restored == 1)
+			}
+
+			w = serve(router, "DELETE", "/rows/1", "", tc.header...)
+			if w.Code != tc.hard {
+				t.Errorf("hard delete: status = %d, want %d: %s", w.Code, tc.hard, w.Body)
+			}
+
+			var left int64
+			res.Db.Unscoped().Model(new(testSoftRow)).Where("id = 1").Count(&left)
+			if (left == 0) != (tc.hard == http.StatusNoContent) {
+				t.Errorf("hard delete: row 1 left = %d", left)
+			}
+		})
+	}
+}
@@ -0,0 +1,353 @@
+package fwork_server_gorm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	fwork_server_orm "github.com/joabssilveira/GoQLite/core"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+const DefaultImportMaxRows = 10000
+
+type ImportMode string
+
+const (
+	// ImportCreate inserts every row (default).
+	ImportCreate ImportMode = ""
+	// ImportUpsert inserts or updates the rows (INSERT ... ON CONFLICT).
+	ImportUpsert ImportMode = "upsert"
+)
+
+type ImportOptions[T any] struct {
+	Mode ImportMode
+
+	// Upsert is the conflict target / update columns of ImportUpsert. Nil:
+	// primary key. Without UpdateColumns the columns of the file are updated.
+	Upsert *UpsertOptions
+
+	// BulkMode: BulkAtomic (default) imports all rows or none;
+	// BulkBestEffort imports the valid rows and reports the others.
+	BulkMode BulkMode
+
+	// BatchSize is the number of rows inserted per statement. Default: 100.
+	BatchSize int
+
+	// DryRun runs the whole import in a transaction that is rolled back, so
+	// the report includes database errors (unique, foreign keys...).
+	DryRun bool
+
+	// MaxRows refuses larger files (bad query). Default:
+	// DefaultImportMaxRows; negative means no limit.
+	MaxRows int
+
+	// Headers maps file columns to fields, for headers that are not a JSON
+	// name / field name / column ("Course": "course_id").
+	Headers map[string]string
+
+	// IgnoreUnknown skips unknown columns instead of refusing the file.
+	IgnoreUnknown bool
+
+	// Timeout limits the whole import (the handler does not use QueryTimeout
+	// here).
+	Timeout time.Duration
+
+	// Validator overrides DefaultStructValidator.
+	Validator StructValidator
+
+	Hooks *Hooks[T]
+}
+
+func (o ImportOptions[T]) maxRows() int {
+	if o.MaxRows == 0 {
+		return DefaultImportMaxRows
+	}
+	return o.MaxRows
+}
+
+var errImportDryRun = errors.New("import dry run")
+
+// GormImport reads the records of in and creates (or upserts) them as T,
+// through the bulk create path: sanitization, validation, hooks and batches.
+// Columns are matched to fields by JSON name, field name or column; values
+// are coerced to the field types. Rows that cannot be parsed are reported
+// with their line and field errors.
+//
+// In atomic mode (default) any failed row rolls back the import and
+// ErrBulkRolledBack is returned with the report.
+func GormImport[T any](
+	ctx context.Context,
+	db *gorm.DB,
+	in io.Reader,
+	format ImportFormat,
+	opts ...ImportOptions[T],
+) (fwork_server_orm.ImportResult, error) {
+
+	db = db.WithContext(ctx)
+
+	var opt ImportOptions[T]
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+
+	s := schemaOf(db, new(T))
+	if s == nil {
+		return fwork_server_orm.ImportResult{}, fmt.Errorf("import: cannot parse model %T", new(T))
+	}
+
+	// COLUNAS (resolvidas uma vez por nome)
+	fields := map[string]*schema.Field{}
+	var unknown []string
+
+	resolve := func(name string) *schema.Field {
+		if field, ok := fields[name]; ok {
+			return field
+		}
+
+		field := importField(s, name, opt)
+		fields[name] = field
+		if field == nil {
+			unknown = append(unknown, name)
+		}
+		return field
+	}
+
+	// LEITURA
+	var (
+		items []T
+		lines []int
+		errs  []error
+	)
+
+	reader := format.NewReader(in)
+	for {
+		record, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fwork_server_orm.ImportResult{}, fwork_server_orm.NewError(fwork_server_orm.ErrorBadQuery, err.Error(), err)
+		}
+
+		if limit := opt.maxRows(); limit > 0 && len(items) >= limit {
+			return fwork_server_orm.ImportResult{}, fwork_server_orm.NewError(
+				fwork_server_orm.ErrorBadQuery,
+				fmt.Sprintf("import exceeds %d rows, split the file", limit),
+				nil,
+			)
+		}
+
+		item, err := importRow[T](record, resolve)
+		items = append(items, item)
+		lines = append(lines, reader.Line())
+		errs = append(errs, err)
+	}
+
+	if len(unknown) > 0 && !opt.IgnoreUnknown {
+		return fwork_server_orm.ImportResult{}, fwork_server_orm.NewError(
+			fwork_server_orm.ErrorBadQuery,
+			"unknown columns: "+strings.Join(unknown, ", "),
+			nil,
+		)
+	}
+
+	// GRAVAÇÃO
+	bulkOpt := bulkOptions([]BulkOptions[T]{{
+		Mode:      opt.BulkMode,
+		BatchSize: opt.BatchSize,
+		Validator: opt.Validator,
+		Hooks:     opt.Hooks,
+	}})
+	if opt.Mode == ImportUpsert {
+		bulkOpt.Upsert = importUpsert(opt.Upsert, fields)
+	}
+
+	var (
+		bulk fwork_server_orm.BulkResult[T]
+		err  error
+	)
+
+	if opt.DryRun {
+		var bulkErr error
+		err = db.Transaction(func(tx *gorm.DB) error {
+			bulk, bulkErr = createBulk(ctx, items, tx, bulkOpt, errs)
+			return errImportDryRun // desfaz tudo
+		})
+		if errors.Is(err, errImportDryRun) {
+			err = bulkErr
+		}
+	} else {
+		bulk, err = createBulk(ctx, items, db, bulkOpt, errs)
+	}
+
+	if err != nil && !errors.Is(err, ErrBulkRolledBack) {
+		return fwork_server_orm.ImportResult{}, err
+	}
+
+	result := fwork_server_orm.ImportResult{
+		Atomic:    bulk.Atomic,
+		DryRun:    opt.DryRun,
+		Total:     len(items),
+		Succeeded: bulk.Succeeded,
+		Failed:    bulk.Failed,
+	}
+
+	for _, item := range bulk.Items {
+		if item.Status == fwork_server_orm.BulkItemFailed {
+			result.Errors = append(result.Errors, fwork_server_orm.ImportRowError{
+				Row:    lines[item.Index],
+				Error:  item.Error,
+				Errors: item.Errors,
+			})
+		}
+	}
+
+	return result, err
+}
+
+// importUpsert defaults the update columns to the columns of the file, so
+// an import with some columns does not reset the others.
+func importUpsert(upsert *UpsertOptions, fields map[string]*schema.Field) *UpsertOptions {
+	var opt UpsertOptions
+	if upsert != nil {
+		opt = *upsert
+	}
+
+	if opt.DoNothing || len(opt.UpdateColumns) > 0 {
+		return &opt
+	}
+
+	for _, field := range fields {
+		if field != nil && !field.PrimaryKey && field.Updatable && !contains(opt.UpdateColumns, field.DBName) {
+			opt.UpdateColumns = append(opt.UpdateColumns, field.DBName)
+		}
+	}
+	sort.Strings(opt.UpdateColumns)
+
+	// só a chave no arquivo: nada a atualizar
+	if len(opt.UpdateColumns) == 0 {
+		opt.DoNothing = true
+	}
+
+	return &opt
+}
+
+// importField resolves a file column to a writable field of s (nil when
+// unknown or read only).
+func importField[T any](s *schema.Schema, name string, opt ImportOptions[T]) *schema.Field {
+	if mapped, ok := opt.Headers[name]; ok {
+		name = mapped
+	}
+
+	field := lookUpField(s, name)
+	if field == nil || field.DBName == "" || jsonFieldName(field) == "" {
+		return nil
+	}
+
+	if !field.Creatable {
+		return nil
+	}
+
+	return field
+}
+
+// importRow builds a T from the JSON of the coerced values, so custom
+// UnmarshalJSON (JSONB, enums...) applies as in the JSON handlers.
+func importRow[T any](record map[string]any, resolve func(name string) *schema.Field) (T, error) {
+	var item T
+
+	names := make([]string, 0, len(record))
+	for name := range record {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	values := make(map[string]any, len(record))
+	verr := &fwork_server_orm.ValidationError{}
+
+	for _, name := range names {
+		value := record[name]
+		field := resolve(name)
+		if field == nil || value == nil {
+			continue
+		}
+
+		typed, err := coerceImportValue(field, value)
+		if err != nil {
+			verr.Add(name, fwork_server_orm.FieldErrorInvalid, fmt.Sprintf("invalid %s value: %v", importTypeName(field), value))
+			continue
+		}
+		values[jsonFieldName(field)] = typed
+	}
+
+	if err := verr.OrNil(); err != nil {
+		return item, err
+	}
+
+	raw, err := json.Marshal(values)
+	if err != nil {
+		return item, err
+	}
+	if err := json.Unmarshal(raw, &item); err != nil {
+		return item, requestError(err)
+	}
+
+	return item, nil
+}
+
+// Formatos de data aceitos além do RFC 3339.
+var importTimeLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// coerceImportValue converts a file value to the field type. Text (CSV) is
+// read as a JSON literal when the field is not a string (numbers, booleans,
+// JSONB objects), and dates also accept the usual layouts without zone.
+func coerceImportValue(field *schema.Field, value any) (any, error) {
+	text, ok := value.(string)
+	if !ok {
+		return coerceFieldValue(field, value)
+	}
+
+	switch field.DataType {
+	case schema.String:
+		return coerceFieldValue(field, text)
+
+	case schema.Bool:
+		b, err := strconv.ParseBool(strings.TrimSpace(text))
+		if err != nil {
+			return nil, err
+		}
+		return coerceFieldValue(field, b)
+
+	case schema.Time, schema.DataType("date"):
+		for _, layout := range importTimeLayouts {
+			if t, err := time.Parse(layout, strings.TrimSpace(text)); err == nil {
+				return coerceFieldValue(field, t)
+			}
+		}
+	}
+
+	if typed, err := coerceFieldValue(field, json.RawMessage(text)); err == nil {
+		return typed, nil
+	}
+	return coerceFieldValue(field, text)
+}
+
+func importTypeName(field *schema.Field) string {
+	if field.DataType != "" {
+		return string(field.DataType)
+	}
+	return field.FieldType.String()
+}
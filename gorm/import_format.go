@@ -0,0 +1,208 @@
+package fwork_server_gorm
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"path"
+	"strings"
+	"sync"
+)
+
+// maior linha aceita num arquivo NDJSON
+const maxImportLineSize = 4 << 20
+
+// RowReader reads the records of an import file, by column name. Values are
+// strings (CSV) or decoded JSON values with json.Number (NDJSON).
+type RowReader interface {
+	// Next returns io.EOF after the last record.
+	Next() (map[string]any, error)
+	// Line is the line of the last record in the file.
+	Line() int
+}
+
+type ImportFormat struct {
+	// Name is the value of the format query param.
+	Name        string
+	ContentType string
+	Extension   string
+	NewReader   func(r io.Reader) RowReader
+}
+
+var (
+	ImportCSV = ImportFormat{
+		Name:        "csv",
+		ContentType: "text/csv",
+		Extension:   "csv",
+		NewReader:   func(r io.Reader) RowReader { return newCSVRowReader(r) },
+	}
+
+	ImportNDJSON = ImportFormat{
+		Name:        "ndjson",
+		ContentType: "application/x-ndjson",
+		Extension:   "ndjson",
+		NewReader:   func(r io.Reader) RowReader { return newNDJSONRowReader(r) },
+	}
+)
+
+var (
+	importFormatsMu sync.RWMutex
+	importFormats   = map[string]ImportFormat{
+		ImportCSV.Name:    ImportCSV,
+		ImportNDJSON.Name: ImportNDJSON,
+	}
+)
+
+// RegisterImportFormat makes a format available to the import handlers
+// (?format=<name>, Content-Type or file extension).
+func RegisterImportFormat(format ImportFormat) {
+	importFormatsMu.Lock()
+	defer importFormatsMu.Unlock()
+
+	importFormats[format.Name] = format
+}
+
+func LookupImportFormat(name string) (ImportFormat, bool) {
+	importFormatsMu.RLock()
+	defer importFormatsMu.RUnlock()
+
+	format, ok := importFormats[name]
+	return format, ok
+}
+
+// detectImportFormat resolves the format of an upload: the format param,
+// then the content type, then the extension of the file name.
+func detectImportFormat(name, contentType, fileName string) (ImportFormat, error) {
+	if name != "" {
+		format, ok := LookupImportFormat(name)
+		if !ok {
+			return ImportFormat{}, badQuery("unsupported format: %s", name)
+		}
+		return format, nil
+	}
+
+	importFormatsMu.RLock()
+	defer importFormatsMu.RUnlock()
+
+	if mediaType, _, err := mime.ParseMediaType(contentType); err == nil {
+		for _, format := range importFormats {
+			if format.ContentType == mediaType {
+				return format, nil
+			}
+		}
+	}
+
+	if ext := strings.TrimPrefix(path.Ext(fileName), "."); ext != "" {
+		for _, format := range importFormats {
+			if strings.EqualFold(format.Extension, ext) {
+				return format, nil
+			}
+		}
+	}
+
+	return ImportFormat{}, badQuery("unknown import format: set ?format= or the Content-Type")
+}
+
+// =========================
+// CSV
+// =========================
+
+// csvRowReader uses the first line as header. Empty cells are left out of
+// the record (the field keeps its zero / default value).
+type csvRowReader struct {
+	r      *csv.Reader
+	header []string
+	line   int
+}
+
+func newCSVRowReader(r io.Reader) *csvRowReader {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+	return &csvRowReader{r: reader}
+}
+
+func (c *csvRowReader) Next() (map[string]any, error) {
+	if c.header == nil {
+		header, err := c.r.Read()
+		if err == io.EOF {
+			return nil, io.EOF
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		// planilhas do Excel começam com BOM
+		header[0] = strings.TrimPrefix(header[0], "\ufeff")
+		for i := range header {
+			header[i] = strings.TrimSpace(header[i])
+		}
+		c.header = header
+	}
+
+	cells, err := c.r.Read()
+	if err != nil {
+		return nil, err
+	}
+	c.line, _ = c.r.FieldPos(0)
+
+	record := make(map[string]any, len(cells))
+	for i, cell := range cells {
+		if cell != "" {
+			record[c.header[i]] = cell
+		}
+	}
+	return record, nil
+}
+
+func (c *csvRowReader) Line() int {
+	return c.line
+}
+
+// =========================
+// NDJSON
+// =========================
+
+// ndjsonRowReader reads one JSON object per line; blank lines are skipped.
+type ndjsonRowReader struct {
+	s    *bufio.Scanner
+	line int
+}
+
+func newNDJSONRowReader(r io.Reader) *ndjsonRowReader {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxImportLineSize)
+	return &ndjsonRowReader{s: scanner}
+}
+
+func (n *ndjsonRowReader) Next() (map[string]any, error) {
+	for n.s.Scan() {
+		n.line++
+
+		raw := bytes.TrimSpace(n.s.Bytes())
+		if len(raw) == 0 {
+			continue
+		}
+
+		decoder := json.NewDecoder(bytes.NewReader(raw))
+		decoder.UseNumber()
+
+		var record map[string]any
+		if err := decoder.Decode(&record); err != nil || record == nil {
+			return nil, fmt.Errorf("line %d: expected a JSON object", n.line)
+		}
+		return record, nil
+	}
+
+	if err := n.s.Err(); err != nil {
+		return nil, fmt.Errorf("line %d: %w", n.line+1, err)
+	}
+	return nil, io.EOF
+}
+
+func (n *ndjsonRowReader) Line() int {
+	return n.line
+}
@@ -0,0 +1,224 @@
+package fwork_server_gorm
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	fwork_server_orm "github.com/joabssilveira/GoQLite/core"
+)
+
+type testImportRow struct {
+	ID     uint                             `json:"id"`
+	Name   string                           `json:"name" gorm:"uniqueIndex"`
+	Age    int                              `json:"age"`
+	Score  float64                          `json:"score"`
+	Active bool                             `json:"active"`
+	Born   time.Time                        `json:"born"`
+	Tags   fwork_server_orm.JSONB[[]string] `json:"tags"`
+}
+
+func TestCoerceImportValue(t *testing.T) {
+	s := schemaOf(dryRunDB(t), new(testImportRow))
+
+	for name, tc := range map[string]struct {
+		field string
+		value any
+		want  any
+	}{
+		"int":          {"age", "42", 42},
+		"float":        {"score", "9.5", 9.5},
+		"bool":         {"active", " true ", true},
+		"bool digit":   {"active", "0", false},
+		"date":         {"born", "2024-05-01", time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)},
+		"datetime":     {"born", "2024-05-01 10:30:00", time.Date(2024, 5, 1, 10, 30, 0, 0, time.UTC)},
+		"rfc3339":      {"born", "2024-05-01T10:30:00-03:00", time.Date(2024, 5, 1, 13, 30, 0, 0, time.UTC)},
+		"json":         {"tags", `["a","b"]`, fwork_server_orm.JSONB[[]string]{Data: []string{"a", "b"}}},
+		"string":       {"name", "123", "123"},
+		"ndjson value": {"age", float64(7), 7},
+	} {
+		t.Run(name, func(t *testing.T) {
+			got, err := coerceImportValue(lookUpField(s, tc.field), tc.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if want, ok := tc.want.(time.Time); ok {
+				if !got.(time.Time).Equal(want) {
+					t.Errorf("coerceImportValue(%q) = %v, want %v", tc.value, got, want)
+				}
+				return
+			}
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("coerceImportValue(%q) = %#v, want %#v", tc.value, got, tc.want)
+			}
+		})
+	}
+
+	for name, tc := range map[string]struct {
+		field string
+		value string
+	}{
+		"int":  {"age", "abc"},
+		"bool": {"active", "maybe"},
+		"date": {"born", "01/05/2024"},
+		"json": {"tags", "{"},
+	} {
+		t.Run("invalid "+name, func(t *testing.T) {
+			if got, err := coerceImportValue(lookUpField(s, tc.field), tc.value); err == nil {
+				t.Errorf("coerceImportValue(%q) = %#v, want an error", tc.value, got)
+			}
+		})
+	}
+}
+
+func TestImportReportsFileLines(t *testing.T) {
+	ctx := context.Background()
+
+	for name, tc := range map[string]struct {
+		format ImportFormat
+		file   string
+		want   []int
+	}{
+		"csv": {
+			ImportCSV,
+			"name,age,active\n" +
+				"Ana,30,true\n" +
+				"\"Bruno\nSilva\",abc,true\n" + // o registro começa na linha 3
+				"Carla,25,yes\n" +
+				"Davi,40,false\n",
+			[]int{3, 5},
+		},
+		"ndjson": {
+			ImportNDJSON,
+			`{"name":"Ana","age":30}` + "\n" +
+				"\n" +
+				`{"name":"Bruno","age":"abc"}` + "\n" +
+				`{"name":"Carla","active":"yes"}` + "\n" +
+				`{"name":"Davi","age":40}` + "\n",
+			[]int{3, 4},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			db := sqliteDB(t, &testImportRow{})
+
+			result, err := GormImport[testImportRow](ctx, db, strings.NewReader(tc.file), tc.format, ImportOptions[testImportRow]{
+				BulkMode: BulkBestEffort,
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if result.Total != 4 || result.Succeeded != 2 || result.Failed != 2 {
+				t.Errorf("result = %+v, want 2 of 4 rows", result)
+			}
+
+			var lines []int
+			for _, e := range result.Errors {
+				lines = append(lines, e.Row)
+				if len(e.Errors) != 1 || e.Errors[0].Code != fwork_server_orm.FieldErrorInvalid {
+					t.Errorf("row %d: field errors = %+v", e.Row, e.Errors)
+				}
+			}
+			if !reflect.DeepEqual(lines, tc.want) {
+				t.Errorf("error lines = %v, want %v", lines, tc.want)
+			}
+
+			var count int64
+			db.Model(new(testImportRow)).Count(&count)
+			if count != 2 {
+				t.Errorf("%d rows imported, want 2", count)
+			}
+		})
+	}
+}
+
+func TestImportAtomicRollsBack(t *testing.T) {
+	db := sqliteDB(t, &testImportRow{})
+
+	result, err := GormImport[testImportRow](context.Background(), db, strings.NewReader("name,age\nAna,30\nBruno,abc\n"), ImportCSV)
+	if !errors.Is(err, ErrBulkRolledBack) {
+		t.Fatalf("err = %v, want ErrBulkRolledBack", err)
+	}
+	if !result.Atomic || len(result.Errors) != 1 || result.Errors[0].Row != 3 {
+		t.Errorf("result = %+v", result)
+	}
+
+	var count int64
+	db.Model(new(testImportRow)).Count(&count)
+	if count != 0 {
+		t.Errorf("%d rows imported, want none", count)
+	}
+}
+
+func TestImportDryRun(t *testing.T) {
+	db := sqliteDB(t, &testImportRow{})
+	if err := db.Create(&testImportRow{Name: "Ana"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// o nome repetido só falha no banco (índice único)
+	file := "name,age\nBruno,20\nAna,30\nCarla,40\n"
+
+	result, err := GormImport[testImportRow](context.Background(), db, strings.NewReader(file), ImportCSV, ImportOptions[testImportRow]{
+		BulkMode: BulkBestEffort,
+		DryRun:   true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !result.DryRun || result.Succeeded != 2 || result.Failed != 1 {
+		t.Errorf("result = %+v, want 2 ok and 1 failed", result)
+	}
+	if len(result.Errors) != 1 || result.Errors[0].Row != 3 {
+		t.Errorf("errors = %+v, want line 3", result.Errors)
+	}
+
+	var names []string
+	db.Model(new(testImportRow)).Pluck("name", &names)
+	if !reflect.DeepEqual(names, []string{"Ana"}) {
+		t.Errorf("rows after the dry run = %v, want only Ana", names)
+	}
+}
+
+func TestImportUpsertUpdatesFileColumns(t *testing.T) {
+	db := sqliteDB(t, &testImportRow{})
+	if err := db.Create(&testImportRow{ID: 1, Name: "Ana", Age: 30, Active: true}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	file := "id,name,age\n1,Ana,31\n2,Bruno,20\n"
+
+	result, err := GormImport[testImportRow](context.Background(), db, strings.NewReader(file), ImportCSV, ImportOptions[testImportRow]{
+		Mode: ImportUpsert,
+	})
+	if err != nil || result.Succeeded != 2 {
+		t.Fatalf("GormImport = %+v, %v", result, err)
+	}
+
+	var rows []testImportRow
+	if err := db.Order("id").Find(&rows).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if len(rows) != 2 {
+		t.Fatalf("rows = %+v, want 2", rows)
+	}
+	// active não está no arquivo: fica como estava
+	if rows[0].Age != 31 || !rows[0].Active {
+		t.Errorf("updated row = %+v, want age 31 and active kept", rows[0])
+	}
+	if rows[1].Name != "Bruno" || rows[1].Age != 20 {
+		t.Errorf("inserted row = %+v", rows[1])
+	}
+
+	// sem o modo upsert a chave repetida é um erro
+	_, err = GormImport[testImportRow](context.Background(), db, strings.NewReader("id,name\n1,Ana\n"), ImportCSV)
+	if !errors.Is(err, ErrBulkRolledBack) {
+		t.Errorf("create import of an existing key: err = %v, want ErrBulkRolledBack", err)
+	}
+}
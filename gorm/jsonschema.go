@@ -0,0 +1,252 @@
+package fwork_server_gorm
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+
+	fwork_server_orm "github.com/joabssilveira/GoQLite/core"
+)
+
+const jsonSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// QuerySchema is the JSON Schema (draft 2020-12) of the QueryPayload of a
+// model: the where filter with the fields of the model (JSON names), their
+// value types / enum values and operators, relation fields ("course.title",
+// one level) and paths inside JSON fields, plus sort / select / nested and
+// the pagination options. Unknown fields and operators are rejected.
+func QuerySchema(desc *ModelDescription) map[string]any {
+	result := querySchema(desc, map[string]any{"$ref": "#/$defs/Filter"})
+	result["$schema"] = jsonSchemaDialect
+	result["title"] = desc.Model + " query"
+	result["$defs"] = map[string]any{
+		"Filter": filterSchema(desc, "#/$defs/Filter"),
+	}
+	return result
+}
+
+// QuerySchemaHandler serves QuerySchema of the resource model.
+func (res *GormResource[T]) QuerySchemaHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		desc, err := res.Describe()
+		if err != nil {
+			res.writeError(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/schema+json")
+		json.NewEncoder(w).Encode(QuerySchema(desc))
+	}
+}
+
+func querySchema(desc *ModelDescription, where map[string]any) map[string]any {
+	integer := func(minimum int) map[string]any {
+		return map[string]any{"type": "integer", "minimum": minimum}
+	}
+
+	properties := map[string]any{
+		"where":  where,
+		"sort":   sortSchema(desc),
+		"select": selectSchema(desc),
+		"limit":  integer(0),
+		"skip":   integer(0),
+		"page":   integer(1),
+		"count": map[string]any{"enum": []string{
+			string(fwork_server_orm.CountExact),
+			string(fwork_server_orm.CountNone),
+			string(fwork_server_orm.CountHasMore),
+			string(fwork_server_orm.CountEstimated),
+		}},
+		"withDeleted": map[string]any{"type": "boolean"},
+		"onlyDeleted": map[string]any{"type": "boolean"},
+	}
+
+	if relations := relationNames(desc); len(relations) > 0 {
+		properties["nested"] = map[string]any{
+			"type":        "string",
+			"description": "Relations to load, e.g. {" + relations[0] + "}",
+			"examples":    []string{"{" + relations[0] + "}"},
+		}
+	}
+
+	return map[string]any{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+}
+
+func sortSchema(desc *ModelDescription) map[string]any {
+	return map[string]any{
+		"type": "array",
+		"items": map[string]any{
+			"type": "object",
+			"properties": map[string]any{
+				"field": map[string]any{"enum": fieldNames(desc, func(f FieldDescription) bool { return f.Sortable })},
+				"dir":   map[string]any{"enum": []string{"asc", "desc"}},
+			},
+			"required":             []string{"field"},
+			"additionalProperties": false,
+		},
+	}
+}
+
+func selectSchema(desc *ModelDescription) map[string]any {
+	return map[string]any{
+		"type":        "array",
+		"items":       map[string]any{"enum": fieldNames(desc, func(f FieldDescription) bool { return f.Selectable })},
+		"uniqueItems": true,
+	}
+}
+
+// filterSchema is the schema of a Filter of the model; self is the reference
+// to the schema itself ($and / $or / $not).
+func filterSchema(desc *ModelDescription, self string) map[string]any {
+	ref := map[string]any{"$ref": self}
+
+	properties := map[string]any{
+		"$and": map[string]any{"type": "array", "items": ref},
+		"$or":  map[string]any{"type": "array", "items": ref},
+		"$not": ref,
+	}
+	patterns := map[string]any{}
+
+	add := func(prefix string, fields []FieldDescription) {
+		for _, field := range fields {
+			if !field.Filterable {
+				continue
+			}
+
+			name := prefix + field.Name
+			properties[name] = fieldExprSchema(field)
+
+			// caminhos dentro do documento (meta.tags.0)
+			if field.Type == fwork_server_orm.KindJSON {
+				patterns["^"+regexp.QuoteMeta(name)+`\.`] = fieldExprSchema(field)
+			}
+		}
+	}
+
+	add("", desc.Fields)
+	for _, rel := range desc.Relations {
+		add(rel.Name+".", describeSchema(rel.schema).Fields)
+	}
+
+	result := map[string]any{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+	if len(patterns) > 0 {
+		result["patternProperties"] = patterns
+	}
+	return result
+}
+
+// fieldExprSchema is the FieldExpr of a field, limited to its operators, or
+// the plain value ({"name": "Joao"}, an implicit $eq).
+func fieldExprSchema(field FieldDescription) map[string]any {
+	value := kindSchema(field.Type)
+	if len(field.Enum) > 0 {
+		value["enum"] = field.Enum
+	}
+	values := map[string]any{"type": "array", "items": value}
+
+	properties := map[string]any{}
+	for _, op := range field.Operators {
+		switch op {
+		case fwork_server_orm.OpEq, fwork_server_orm.OpNe,
+			fwork_server_orm.OpGt, fwork_server_orm.OpGte,
+			fwork_server_orm.OpLt, fwork_server_orm.OpLte:
+			properties[op] = value
+		case fwork_server_orm.OpIn, fwork_server_orm.OpNin:
+			properties[op] = values
+		case fwork_server_orm.OpBetween:
+			properties[op] = map[string]any{"type": "array", "items": value, "minItems": 2, "maxItems": 2}
+		case fwork_server_orm.OpLike, fwork_server_orm.OpILike:
+			properties[op] = map[string]any{"type": "string"}
+		case fwork_server_orm.OpExists, fwork_server_orm.OpNull:
+			properties[op] = map[string]any{"type": "boolean"}
+		case fwork_server_orm.OpOp:
+			properties[op] = map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"op":    map[string]any{"type": "string"},
+					"value": map[string]any{},
+				},
+				"required": []string{"op"},
+			}
+		}
+	}
+
+	operators := map[string]any{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+
+	if _, ok := properties[fwork_server_orm.OpEq]; !ok {
+		return operators
+	}
+
+	shorthand := value
+	if field.Type == fwork_server_orm.KindJSON {
+		// um objeto seria lido como operadores
+		shorthand = map[string]any{"not": map[string]any{"type": "object"}}
+	}
+
+	return map[string]any{"oneOf": []any{shorthand, operators}}
+}
+
+// fieldSchema is the JSON Schema of a field value in the payloads.
+func fieldSchema(field FieldDescription) map[string]any {
+	s := kindSchema(field.Type)
+
+	if len(field.Enum) > 0 {
+		s["enum"] = field.Enum
+	}
+	if field.Nullable {
+		if t, ok := s["type"].(string); ok {
+			s["type"] = []string{t, "null"}
+		}
+		if enum, ok := s["enum"].([]any); ok {
+			s["enum"] = append(append([]any{}, enum...), nil)
+		}
+	}
+	if field.ReadOnly {
+		s["readOnly"] = true
+	}
+
+	return s
+}
+
+func kindSchema(kind fwork_server_orm.FieldKind) map[string]any {
+	switch kind {
+	case fwork_server_orm.KindInteger:
+		return map[string]any{"type": "integer"}
+	case fwork_server_orm.KindNumber:
+		return map[string]any{"type": "number"}
+	case fwork_server_orm.KindBoolean:
+		return map[string]any{"type": "boolean"}
+	case fwork_server_orm.KindTime:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case fwork_server_orm.KindDate:
+		return map[string]any{"type": "string", "format": "date"}
+	case fwork_server_orm.KindBytes:
+		return map[string]any{"type": "string", "contentEncoding": "base64"}
+	case fwork_server_orm.KindJSON:
+		return map[string]any{} // qualquer valor JSON
+	default:
+		return map[string]any{"type": "string"}
+	}
+}
+
+func fieldNames(desc *ModelDescription, keep func(field FieldDescription) bool) []string {
+	names := make([]string, 0, len(desc.Fields))
+	for _, field := range desc.Fields {
+		if keep(field) {
+			names = append(names, field.Name)
+		}
+	}
+	return names
+}
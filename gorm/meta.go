@@ -0,0 +1,101 @@
+package fwork_server_gorm
+
+import (
+	"encoding/json"
+	"net/http"
+	"path"
+
+	"github.com/gorilla/mux"
+	fwork_server_orm "github.com/joabssilveira/GoQLite/core"
+)
+
+// ResourceMeta describes a registered resource for generic admin UIs: its
+// fields with the filter / sort / select flags and operators, and its
+// relations with the target resource.
+type ResourceMeta struct {
+	Resource   string      `json:"resource"`
+	Path       string      `json:"path"`
+	Operations []Operation `json:"operations"`
+	*ModelDescription
+}
+
+// resourceName is the last segment of the path ("/api/students" ->
+// "students").
+func resourceName(p string) string {
+	return path.Base(p)
+}
+
+// Meta describes the resource registered with the given name (last segment
+// of its path). ok is false for unknown resources.
+func (reg *Registry) Meta(name string) (meta *ResourceMeta, ok bool, err error) {
+	resources := reg.registered()
+
+	for _, r := range resources {
+		if resourceName(r.Path) != name {
+			continue
+		}
+
+		desc, err := r.Describer.Describe()
+		if err != nil {
+			return nil, true, err
+		}
+
+		// recurso registrado do modelo alvo de cada relação
+		for i, rel := range desc.Relations {
+			for _, target := range resources {
+				targetDesc, err := target.Describer.Describe()
+				if err != nil {
+					return nil, true, err
+				}
+				if targetDesc.Table == rel.Table {
+					desc.Relations[i].Resource = resourceName(target.Path)
+					break
+				}
+			}
+		}
+
+		return &ResourceMeta{
+			Resource:         name,
+			Path:             r.Path,
+			Operations:       r.Operations,
+			ModelDescription: desc,
+		}, true, nil
+	}
+
+	return nil, false, nil
+}
+
+// MetaHandler answers GET /_meta/{resource} with the ResourceMeta of the
+// resource. Mounted without the {resource} var it lists the registered
+// resources:
+//
+//	r.HandleFunc("/_meta", api.MetaHandler()).Methods("GET")
+//	r.HandleFunc("/_meta/{resource}", api.MetaHandler()).Methods("GET")
+func (reg *Registry) MetaHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		name, ok := mux.Vars(r)["resource"]
+		if !ok {
+			names := []string{}
+			for _, res := range reg.registered() {
+				names = append(names, resourceName(res.Path))
+			}
+
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(map[string]any{"resources": names})
+			return
+		}
+
+		meta, found, err := reg.Meta(name)
+		if err != nil {
+			fwork_server_orm.DefaultErrorResponder.RespondError(w, r, fwork_server_orm.NewError(fwork_server_orm.ErrorInternal, "", err))
+			return
+		}
+		if !found {
+			fwork_server_orm.DefaultErrorResponder.RespondError(w, r, fwork_server_orm.NewError(fwork_server_orm.ErrorNotFound, "unknown resource: "+name, nil))
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(meta)
+	}
+}
@@ -0,0 +1,459 @@
+package fwork_server_gorm
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+
+	fwork_server_orm "github.com/joabssilveira/GoQLite/core"
+)
+
+// Operation is a route of a registered resource.
+type Operation string
+
+const (
+	OpList   Operation = "list"   // GET    {path}
+	OpGet    Operation = "get"    // GET    {path}/{id}
+	OpCreate Operation = "create" // POST   {path}
+	OpUpdate Operation = "update" // PUT    {path}/{id}
+	OpDelete Operation = "delete" // DELETE {path}/{id}
+)
+
+var defaultOperations = []Operation{OpList, OpGet, OpCreate, OpUpdate, OpDelete}
+
+// Describer is implemented by GormResource.
+type Describer interface {
+	Describe() (*ModelDescription, error)
+}
+
+// Registry lists the resources of the API and where they are mounted, to
+// generate its documents. The routes themselves are still registered in the
+// router:
+//
+//	api := NewRegistry("School API", "1.0.0")
+//	api.Register("/students", students)
+//	r.HandleFunc("/openapi.json", api.OpenAPIHandler()).Methods("GET")
+type Registry struct {
+	Title       string
+	Version     string
+	Description string
+	Servers     []string
+
+	mu        sync.RWMutex
+	resources []registeredResource
+	openAPI   []byte // documento gerado (limpo a cada Register)
+}
+
+type registeredResource struct {
+	Path       string
+	Describer  Describer
+	Operations []Operation
+}
+
+func NewRegistry(title, version string) *Registry {
+	return &Registry{Title: title, Version: version}
+}
+
+// Register adds a resource mounted at path ("/students"; the item routes
+// are path/{id}). Without ops all five CRUD operations are documented.
+func (reg *Registry) Register(path string, res Describer, ops ...Operation) {
+	if len(ops) == 0 {
+		ops = defaultOperations
+	}
+
+	reg.mu.Lock()
+	defer reg.mu.Unlock()
+
+	reg.resources = append(reg.resources, registeredResource{
+		Path:       "/" + strings.Trim(path, "/"),
+		Describer:  res,
+		Operations: ops,
+	})
+	reg.openAPI = nil
+}
+
+func (reg *Registry) registered() []registeredResource {
+	reg.mu.RLock()
+	defer reg.mu.RUnlock()
+
+	return append([]registeredResource(nil), reg.resources...)
+}
+
+// OpenAPI generates the OpenAPI 3.1 document of the registered resources.
+func (reg *Registry) OpenAPI() (map[string]any, error) {
+	doc := &openAPIDoc{schemas: map[string]any{}}
+
+	paths := map[string]any{}
+	for _, r := range reg.registered() {
+		desc, err := r.Describer.Describe()
+		if err != nil {
+			return nil, err
+		}
+
+		collection, item := doc.paths(r, desc)
+		if len(collection) > 0 {
+			paths[r.Path] = collection
+		}
+		if len(item) > 0 {
+			paths[r.Path+"/{id}"] = item
+		}
+	}
+
+	info := map[string]any{"title": reg.Title, "version": reg.Version}
+	if reg.Description != "" {
+		info["description"] = reg.Description
+	}
+
+	result := map[string]any{
+		"openapi": "3.1.0",
+		"info":    info,
+		"paths":   paths,
+		"components": map[string]any{
+			"schemas":    doc.schemas,
+			"parameters": listParameters(),
+			"responses": map[string]any{
+				"Problem": map[string]any{
+					"description": "Error (RFC 7807)",
+					"content": map[string]any{
+						"application/problem+json": map[string]any{"schema": schemaRef("Problem")},
+					},
+				},
+			},
+		},
+	}
+
+	if len(reg.Servers) > 0 {
+		servers := make([]any, 0, len(reg.Servers))
+		for _, url := range reg.Servers {
+			servers = append(servers, map[string]any{"url": url})
+		}
+		result["servers"] = servers
+	}
+
+	doc.commonSchemas()
+	return result, nil
+}
+
+// OpenAPIHandler serves the document as JSON, at the route chosen by the
+// application. The document is generated once per set of resources.
+func (reg *Registry) OpenAPIHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		reg.mu.RLock()
+		body := reg.openAPI
+		reg.mu.RUnlock()
+
+		if body == nil {
+			doc, err := reg.OpenAPI()
+			if err == nil {
+				body, err = json.Marshal(doc)
+			}
+			if err != nil {
+				fwork_server_orm.DefaultErrorResponder.RespondError(w, r, fwork_server_orm.NewError(fwork_server_orm.ErrorInternal, "", err))
+				return
+			}
+
+			reg.mu.Lock()
+			reg.openAPI = body
+			reg.mu.Unlock()
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(body)
+	}
+}
+
+// =========================
+// DOCUMENT
+// =========================
+
+type openAPIDoc struct {
+	schemas map[string]any
+}
+
+func schemaRef(name string) map[string]any {
+	return map[string]any{"$ref": "#/components/schemas/" + name}
+}
+
+func (doc *openAPIDoc) paths(r registeredResource, desc *ModelDescription) (collection, item map[string]any) {
+	model := doc.modelSchema(desc)
+	tag := desc.Model
+
+	collection = map[string]any{}
+	item = map[string]any{}
+
+	idParam := map[string]any{
+		"name":     "id",
+		"in":       "path",
+		"required": true,
+		"schema":   doc.keySchema(desc),
+	}
+
+	problem := map[string]any{"$ref": "#/components/responses/Problem"}
+	jsonContent := func(schema any) map[string]any {
+		return map[string]any{"application/json": map[string]any{"schema": schema}}
+	}
+	body := map[string]any{"required": true, "content": jsonContent(schemaRef(model))}
+
+	for _, op := range r.Operations {
+		switch op {
+		case OpList:
+			list := desc.Model + "List"
+			doc.schemas[list] = map[string]any{
+				"type": "object",
+				"properties": map[string]any{
+					"payload":    map[string]any{"type": "array", "items": schemaRef(model)},
+					"pagination": schemaRef("PaginationMeta"),
+				},
+			}
+
+			collection["get"] = map[string]any{
+				"operationId": "list" + desc.Model,
+				"tags":        []string{tag},
+				"parameters":  append(doc.queryParameters(desc), paginationParameters()...),
+				"responses": map[string]any{
+					"200":     map[string]any{"description": "OK", "content": jsonContent(schemaRef(list))},
+					"default": problem,
+				},
+			}
+
+		case OpCreate:
+			collection["post"] = map[string]any{
+				"operationId": "create" + desc.Model,
+				"tags":        []string{tag},
+				"requestBody": body,
+				"responses": map[string]any{
+					"201":     map[string]any{"description": "Created", "content": jsonContent(schemaRef(model))},
+					"default": problem,
+				},
+			}
+
+		case OpGet:
+			item["get"] = map[string]any{
+				"operationId": "get" + desc.Model,
+				"tags":        []string{tag},
+				"parameters":  append([]any{idParam}, doc.itemParameters(desc)...),
+				"responses": map[string]any{
+					"200":     map[string]any{"description": "OK", "content": jsonContent(schemaRef(model))},
+					"default": problem,
+				},
+			}
+
+		case OpUpdate:
+			item["put"] = map[string]any{
+				"operationId": "update" + desc.Model,
+				"tags":        []string{tag},
+				"parameters":  []any{idParam},
+				"requestBody": body,
+				"responses": map[string]any{
+					"200":     map[string]any{"description": "OK", "content": jsonContent(schemaRef(model))},
+					"default": problem,
+				},
+			}
+
+		case OpDelete:
+			item["delete"] = map[string]any{
+				"operationId": "delete" + desc.Model,
+				"tags":        []string{tag},
+				"parameters":  []any{idParam},
+				"responses": map[string]any{
+					"204":     map[string]any{"description": "Deleted"},
+					"default": problem,
+				},
+			}
+		}
+	}
+
+	return collection, item
+}
+
+// modelSchema adds the schema of the model (and of its relations) to the
+// components and returns its name.
+func (doc *openAPIDoc) modelSchema(desc *ModelDescription) string {
+	if _, ok := doc.schemas[desc.Model]; ok {
+		return desc.Model
+	}
+
+	properties := map[string]any{}
+	doc.schemas[desc.Model] = map[string]any{"type": "object", "properties": properties}
+
+	for _, field := range desc.Fields {
+		properties[field.Name] = fieldSchema(field)
+	}
+
+	for _, rel := range desc.Relations {
+		if rel.Field == "" {
+			continue
+		}
+
+		target := doc.modelSchema(describeSchema(rel.schema))
+		if rel.Many {
+			properties[rel.Field] = map[string]any{"type": "array", "items": schemaRef(target)}
+		} else {
+			properties[rel.Field] = schemaRef(target)
+		}
+	}
+
+	return desc.Model
+}
+
+func (doc *openAPIDoc) keySchema(desc *ModelDescription) map[string]any {
+	for _, field := range desc.Fields {
+		if field.Column == desc.Key {
+			return kindSchema(field.Type)
+		}
+	}
+	return map[string]any{"type": "string"}
+}
+
+// =========================
+// PARAMETERS
+// =========================
+
+// filterOperators lists the filterable fields (JSON names, relation.field
+// paths) with their operators.
+func filterOperators(desc *ModelDescription) map[string][]string {
+	operators := map[string][]string{}
+	add := func(prefix string, fields []FieldDescription) {
+		for _, field := range fields {
+			if field.Filterable {
+				operators[prefix+field.Name] = field.Operators
+			}
+		}
+	}
+
+	add("", desc.Fields)
+	for _, rel := range desc.Relations {
+		add(rel.Name+".", describeSchema(rel.schema).Fields)
+	}
+
+	return operators
+}
+
+func relationNames(desc *ModelDescription) []string {
+	names := make([]string, 0, len(desc.Relations))
+	for _, rel := range desc.Relations {
+		names = append(names, rel.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func jsonParameter(name, description string, schema any) map[string]any {
+	return map[string]any{
+		"name":        name,
+		"in":          "query",
+		"description": description,
+		"content":     map[string]any{"application/json": map[string]any{"schema": schema}},
+	}
+}
+
+// queryParameters: where / sort / select / nested of the list route.
+func (doc *openAPIDoc) queryParameters(desc *ModelDescription) []any {
+	filter := desc.Model + "Filter"
+	doc.schemas[filter] = filterSchema(desc, "#/components/schemas/"+filter)
+
+	where := jsonParameter(
+		"where",
+		`Filter (JSON): {"<field>": {"$op": value}, "$and": [...], "$or": [...], "$not": {...}}. `+
+			"Relation fields use relation.field; JSON fields accept paths inside the document. "+
+			"x-goqlite-operators lists the operators of each field.",
+		schemaRef(filter),
+	)
+	where["x-goqlite-operators"] = filterOperators(desc)
+
+	return append([]any{where, jsonParameter("sort", "Sort (JSON)", sortSchema(desc))}, doc.itemParameters(desc)...)
+}
+
+// itemParameters: select / nested, also accepted by the get route.
+func (doc *openAPIDoc) itemParameters(desc *ModelDescription) []any {
+	params := []any{
+		jsonParameter("select", "Fields to return (JSON array)", selectSchema(desc)),
+	}
+
+	if relations := relationNames(desc); len(relations) > 0 {
+		params = append(params, map[string]any{
+			"name":        "nested",
+			"in":          "query",
+			"description": "Relations to load, e.g. {" + relations[0] + "}. Relations: " + strings.Join(relations, ", "),
+			"schema":      map[string]any{"type": "string"},
+		})
+	}
+
+	return params
+}
+
+func paginationParameters() []any {
+	names := []string{"limit", "skip", "page", "count", "withDeleted", "onlyDeleted"}
+
+	params := make([]any, 0, len(names))
+	for _, name := range names {
+		params = append(params, map[string]any{"$ref": "#/components/parameters/" + name})
+	}
+	return params
+}
+
+func listParameters() map[string]any {
+	query := func(name, description string, schema map[string]any) map[string]any {
+		return map[string]any{"name": name, "in": "query", "description": description, "schema": schema}
+	}
+
+	return map[string]any{
+		"limit": query("limit", "Page size", map[string]any{"type": "integer", "minimum": 0}),
+		"skip":  query("skip", "Rows to skip", map[string]any{"type": "integer", "minimum": 0}),
+		"page":  query("page", "Page number (with limit)", map[string]any{"type": "integer", "minimum": 1}),
+		"count": query("count", "How the total is computed", map[string]any{
+			"type": "string",
+			"enum": []string{
+				string(fwork_server_orm.CountExact),
+				string(fwork_server_orm.CountNone),
+				string(fwork_server_orm.CountHasMore),
+				string(fwork_server_orm.CountEstimated),
+			},
+		}),
+		"withDeleted": query("withDeleted", "Include soft deleted rows", map[string]any{"type": "boolean"}),
+		"onlyDeleted": query("onlyDeleted", "Only soft deleted rows", map[string]any{"type": "boolean"}),
+	}
+}
+
+// commonSchemas: PaginationMeta and the problem envelope.
+func (doc *openAPIDoc) commonSchemas() {
+	integer := map[string]any{"type": "integer"}
+
+	doc.schemas["PaginationMeta"] = map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"skip":        integer,
+			"limit":       integer,
+			"count":       integer,
+			"pageCount":   integer,
+			"currentPage": integer,
+			"countMode":   map[string]any{"type": "string"},
+			"hasNextPage": map[string]any{"type": "boolean"},
+		},
+	}
+
+	doc.schemas["FieldError"] = map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"field":   map[string]any{"type": "string"},
+			"code":    map[string]any{"type": "string"},
+			"message": map[string]any{"type": "string"},
+		},
+		"required": []string{"code", "message"},
+	}
+
+	doc.schemas["Problem"] = map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"type":     map[string]any{"type": "string"},
+			"title":    map[string]any{"type": "string"},
+			"status":   integer,
+			"detail":   map[string]any{"type": "string"},
+			"instance": map[string]any{"type": "string"},
+			"errors":   map[string]any{"type": "array", "items": schemaRef("FieldError")},
+		},
+		"required": []string{"type", "title", "status"},
+	}
+}
@@ -0,0 +1,414 @@
+package fwork_server_gorm
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	fwork_server_orm "github.com/joabssilveira/GoQLite/core"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+)
+
+var ErrPlanShapeMismatch = errors.New("payload does not match the query plan shape")
+
+// QueryPlan is a QueryPayload compiled for one model: fields validated,
+// relation joins resolved and the conditions built as SQL with placeholders.
+// Payloads with the same shape (QueryPayload.Shape) reuse the plan and only
+// bind their values. A plan is immutable and safe for concurrent use.
+type QueryPlan struct {
+	Shape string
+	Hash  string
+
+	// Where is a readable skeleton of the WHERE clause (for logs / stats).
+	Where string
+
+	unscoped    bool
+	onlyDeleted string
+	joins       []string
+	filter      planFilter
+	selects     []string
+	order       []string
+	nested      string
+}
+
+// planFilter mirrors a Filter: conds has one SQL per condition of each field,
+// in SortedFieldNames / fieldExprConds order.
+type planFilter struct {
+	conds []string
+	and   []planFilter
+	or    []planFilter
+	not   *planFilter
+}
+
+// CompileQuery compiles payload for the model T. Unknown fields in where,
+// select and sort are reported as bad query errors.
+func CompileQuery[T any](db *gorm.DB, payload fwork_server_orm.QueryPayload) (*QueryPlan, error) {
+	s := schemaOf(db, new(T))
+	if s == nil {
+		return nil, fmt.Errorf("query plan: cannot parse model %T", new(T))
+	}
+
+	return compileQuery(s, payload, payload.Shape())
+}
+
+func compileQuery(s *schema.Schema, payload fwork_server_orm.QueryPayload, shape string) (*QueryPlan, error) {
+	if err := validatePayloadFields(s, payload); err != nil {
+		return nil, err
+	}
+
+	plan := &QueryPlan{
+		Shape:    shape,
+		Hash:     fwork_server_orm.ShapeHash(shape),
+		unscoped: payload.WithDeleted || payload.OnlyDeleted,
+		nested:   payload.Nested,
+	}
+
+	// SOFT DELETE
+	if field := deletedAtField(s); payload.OnlyDeleted && field != nil {
+		plan.onlyDeleted = quoteTable(s.Table) + "." + quoteIdent(field.DBName) + " IS NOT NULL"
+	}
+
+	// JOINS (mesma regra do ApplyJoinsFromFilter)
+	plan.collectJoins(s, payload.Where)
+
+	// WHERE
+	plan.filter = compileFilter(s, payload.Where)
+	plan.Where = plan.filter.String()
+
+	// SELECT
+	for _, fieldName := range payload.Select {
+		if strings.Contains(fieldName, ".") {
+			resolved := resolveFieldPath(s, fieldName)
+			plan.addJoins(resolved.Joins)
+			plan.selects = append(plan.selects, resolved.SQLField)
+			continue
+		}
+		plan.selects = append(plan.selects, quoteTable(s.Table)+"."+quoteIdent(columnName(s, fieldName)))
+	}
+
+	// ORDER
+	for _, o := range payload.Order {
+		column := columnName(s, o.Field)
+		if strings.Contains(o.Field, ".") {
+			resolved := resolveFieldPath(s, o.Field)
+			plan.addJoins(resolved.Joins)
+			column = resolved.SQLField
+		}
+		plan.order = append(plan.order, column+" "+o.Direction())
+	}
+
+	return plan, nil
+}
+
+func (p *QueryPlan) collectJoins(s *schema.Schema, filter fwork_server_orm.Filter) {
+	for _, field := range filter.SortedFieldNames() {
+		if !strings.Contains(field, ".") {
+			continue
+		}
+
+		p.addJoins(resolveFieldPath(s, field).Joins)
+	}
+
+	for _, sub := range filter.And {
+		p.collectJoins(s, sub)
+	}
+	for _, sub := range filter.Or {
+		p.collectJoins(s, sub)
+	}
+	if filter.Not != nil {
+		p.collectJoins(s, *filter.Not)
+	}
+}
+
+func (p *QueryPlan) addJoins(joins []resolvedJoin) {
+	for _, join := range joins {
+		sql := join.Clause
+		if !p.unscoped {
+			sql += join.SoftDelete
+		}
+		if !contains(p.joins, sql) {
+			p.joins = append(p.joins, sql)
+		}
+	}
+}
+
+func compileFilter(s *schema.Schema, filter fwork_server_orm.Filter) planFilter {
+	var compiled planFilter
+
+	for _, field := range filter.SortedFieldNames() {
+		expr := filter.Fields[field]
+
+		sqlField, isJSONB, _, _ := filterField(s, field, expr)
+		for _, cond := range fieldExprConds(sqlField, isJSONB, expr) {
+			compiled.conds = append(compiled.conds, cond.SQL)
+		}
+	}
+
+	for _, sub := range filter.And {
+		compiled.and = append(compiled.and, compileFilter(s, sub))
+	}
+	for _, sub := range filter.Or {
+		compiled.or = append(compiled.or, compileFilter(s, sub))
+	}
+	if filter.Not != nil {
+		not := compileFilter(s, *filter.Not)
+		compiled.not = &not
+	}
+
+	return compiled
+}
+
+func (f planFilter) String() string {
+	parts := append([]string{}, f.conds...)
+	for _, sub := range f.and {
+		parts = append(parts, "("+sub.String()+")")
+	}
+
+	where := strings.Join(parts, " AND ")
+	for _, sub := range f.or {
+		if where == "" {
+			where = "(" + sub.String() + ")"
+		} else {
+			where += " OR (" + sub.String() + ")"
+		}
+	}
+
+	if f.not != nil {
+		if where != "" {
+			where += " AND "
+		}
+		where += "NOT (" + f.not.String() + ")"
+	}
+
+	return where
+}
+
+// validatePayloadFields checks that the fields of where, select and sort exist
+// in the model (or in a relation / JSONB column of it). Runs for plans and
+// for ApplyQuery.
+func validatePayloadFields(s *schema.Schema, payload fwork_server_orm.QueryPayload) error {
+	var unknown []string
+
+	var walk func(filter fwork_server_orm.Filter)
+	walk = func(filter fwork_server_orm.Filter) {
+		for field, expr := range filter.Fields {
+			if !fieldPathExists(s, field, expr.Op != nil && expr.Op.Op == "@>") {
+				unknown = append(unknown, field)
+			}
+		}
+		for _, sub := range filter.And {
+			walk(sub)
+		}
+		for _, sub := range filter.Or {
+			walk(sub)
+		}
+		if filter.Not != nil {
+			walk(*filter.Not)
+		}
+	}
+	walk(payload.Where)
+
+	for _, field := range payload.Select {
+		// relation.field: só colunas (não caminhos JSONB)
+		if strings.Contains(field, ".") {
+			if !fieldPathExists(s, field, false) || resolveFieldPath(s, field).IsJSONB {
+				unknown = append(unknown, field)
+			}
+		} else if !queryableField(lookUpField(s, field)) {
+			unknown = append(unknown, field)
+		}
+	}
+
+	for _, o := range payload.Order {
+		if !fieldPathExists(s, o.Field, false) {
+			unknown = append(unknown, o.Field)
+		}
+	}
+
+	if len(unknown) > 0 {
+		return fwork_server_orm.NewError(
+			fwork_server_orm.ErrorBadQuery,
+			"unknown fields: "+strings.Join(unknown, ", "),
+			nil,
+		)
+	}
+
+	return nil
+}
+
+func fieldPathExists(s *schema.Schema, path string, rootColumnOnly bool) bool {
+	if !strings.Contains(path, ".") {
+		return queryableField(lookUpField(s, path))
+	}
+
+	// as chaves de JSON entram no SQL ('{a,b}')
+	if !safePath(path) {
+		return false
+	}
+
+	first := strings.Split(path, ".")[0]
+	if rootColumnOnly {
+		return queryableField(lookUpField(s, first))
+	}
+
+	return resolveFieldPath(s, path).Known
+}
+
+// queryableField reports whether where / sort / select may use field: a
+// column the model reads. gorm:"-" fields have no column and write-only
+// fields (gorm:"->:false") stay out of the queries.
+func queryableField(field *schema.Field) bool {
+	return field != nil && field.DBName != "" && field.Readable
+}
+
+// safePath reports whether every segment of a dotted path is a plain name
+// (letters, digits, _ and -).
+func safePath(path string) bool {
+	for _, part := range strings.Split(path, ".") {
+		if part == "" {
+			return false
+		}
+		for _, r := range part {
+			if !(r == '_' || r == '-' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// Apply binds the values of payload to the plan, like ApplyQuery. db must
+// have the model set (db.Model(new(T))). Payloads of another shape are
+// rejected with ErrPlanShapeMismatch.
+func (p *QueryPlan) Apply(db *gorm.DB, payload fwork_server_orm.QueryPayload) (*gorm.DB, error) {
+	if payload.Shape() != p.Shape {
+		return nil, ErrPlanShapeMismatch
+	}
+
+	return p.apply(db, payload)
+}
+
+func (p *QueryPlan) apply(db *gorm.DB, payload fwork_server_orm.QueryPayload) (*gorm.DB, error) {
+	// SOFT DELETE
+	if p.unscoped {
+		db = db.Unscoped()
+	}
+	if p.onlyDeleted != "" {
+		db = db.Where(p.onlyDeleted)
+	}
+
+	// JOINS
+	for _, join := range p.joins {
+		if !hasJoin(db, join) {
+			db = db.Joins(join)
+		}
+	}
+
+	// WHERE
+	exprs, err := bindFilter(p.filter, payload.Where)
+	if err != nil {
+		return nil, err
+	}
+	if len(exprs) > 0 {
+		db = db.Clauses(clause.Where{Exprs: exprs})
+	}
+
+	// SELECT
+	if len(p.selects) > 0 {
+		db = db.Select(p.selects)
+	}
+
+	// ORDER
+	for _, order := range p.order {
+		db = db.Order(order)
+	}
+
+	// LIMIT / OFFSET
+	if payload.Limit != nil {
+		db = db.Limit(*payload.Limit)
+	}
+	if payload.Offset != nil {
+		db = db.Offset(*payload.Offset)
+	}
+
+	// NESTED (preloads: a árvore é mutável, então é montada a cada execução)
+	if p.nested != "" {
+		for _, node := range fwork_server_orm.ParseNestedTree(p.nested) {
+			applyNestedNode(db, db.Statement.Model, node, "")
+		}
+	}
+
+	return db, nil
+}
+
+// bindFilter binds the values of filter to compiled. The expressions are the
+// ones ApplyFilter produces, built directly instead of through sub queries.
+func bindFilter(compiled planFilter, filter fwork_server_orm.Filter) ([]clause.Expression, error) {
+	var args [][]interface{}
+	for _, field := range filter.SortedFieldNames() {
+		args = fieldExprArgs(filter.Fields[field], args)
+	}
+
+	if len(args) != len(compiled.conds) ||
+		len(filter.And) != len(compiled.and) ||
+		len(filter.Or) != len(compiled.or) ||
+		(filter.Not == nil) != (compiled.not == nil) {
+		return nil, ErrPlanShapeMismatch
+	}
+
+	exprs := make([]clause.Expression, 0, len(compiled.conds)+len(compiled.and)+len(compiled.or)+1)
+
+	for i, cond := range compiled.conds {
+		exprs = append(exprs, clause.Expr{SQL: cond, Vars: args[i]})
+	}
+
+	for i, sub := range compiled.and {
+		subExprs, err := bindFilter(sub, filter.And[i])
+		if err != nil {
+			return nil, err
+		}
+		if cond := subCondition(subExprs); cond != nil {
+			exprs = append(exprs, cond) // db.Where(sub)
+		}
+	}
+
+	for i, sub := range compiled.or {
+		subExprs, err := bindFilter(sub, filter.Or[i])
+		if err != nil {
+			return nil, err
+		}
+		if cond := subCondition(subExprs); cond != nil {
+			exprs = append(exprs, clause.Or(clause.And(cond))) // db.Or(sub)
+		}
+	}
+
+	if compiled.not != nil {
+		subExprs, err := bindFilter(*compiled.not, *filter.Not)
+		if err != nil {
+			return nil, err
+		}
+		if cond := subCondition(subExprs); cond != nil {
+			exprs = append(exprs, clause.Not(cond)) // db.Not(sub)
+		}
+	}
+
+	return exprs, nil
+}
+
+// subCondition is the condition gorm takes from a sub query passed to
+// Where / Or / Not (see gorm.Statement.BuildCondition).
+func subCondition(exprs []clause.Expression) clause.Expression {
+	if len(exprs) == 0 {
+		return nil
+	}
+
+	if len(exprs) == 1 {
+		if or, ok := exprs[0].(clause.OrConditions); ok && len(or.Exprs) == 1 {
+			exprs[0] = clause.AndConditions(or)
+		}
+	}
+
+	return clause.And(exprs...)
+}
@@ -0,0 +1,223 @@
+package fwork_server_gorm
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	fwork_server_orm "github.com/joabssilveira/GoQLite/core"
+	"gorm.io/gorm"
+)
+
+func intPtr(v int) *int { return &v }
+
+// TestQueryPlanMatchesApplyQuery compiles each payload and checks that the
+// plan builds the same SQL (and binds the same values) as ApplyQuery.
+func TestQueryPlanMatchesApplyQuery(t *testing.T) {
+	db := dryRunDB(t)
+
+	cases := map[string]fwork_server_orm.QueryPayload{
+		"empty": {},
+		"fields": {
+			Where: mustFilter(t, `{"name": {"$ilike": "jo%"}, "age": {"$gte": 18, "$lt": 65}}`),
+		},
+		"json name": {
+			Where:  mustFilter(t, `{"fullName": "Ana"}`),
+			Select: []string{"id", "fullName"},
+			Order:  []fwork_server_orm.Order{{Field: "fullName", Dir: "desc"}},
+		},
+		"relations": {
+			Where: mustFilter(t, `{"course.title": "Go", "course.group.name": {"$in": ["a", "b"]}}`),
+			Order: []fwork_server_orm.Order{{Field: "age"}, {Field: "id", Dir: "desc"}},
+			Limit: intPtr(10),
+		},
+		"jsonb": {
+			Where: mustFilter(t, `{"metadata.tags.0": "new", "course.metadata.level": {"$ne": "basic"}}`),
+		},
+		"logical": {
+			Where: mustFilter(t, `{
+				"$or": [{"age": {"$lt": 18}}, {"course.title": {"$null": true}}],
+				"$not": {"name": {"$in": ["x", "y"]}}
+			}`),
+			Offset: intPtr(20),
+			Limit:  intPtr(10),
+		},
+		"relation sort": {
+			Order: []fwork_server_orm.Order{{Field: "course.group.name"}, {Field: "metadata.level", Dir: "desc"}},
+		},
+		"relation select": {
+			Select: []string{"id", "course.title", "course.group.name"},
+		},
+		"with deleted": {
+			Where:       mustFilter(t, `{"course.title": "Go"}`),
+			WithDeleted: true,
+		},
+		"only deleted": {
+			Where:       mustFilter(t, `{"age": 30}`),
+			OnlyDeleted: true,
+		},
+	}
+
+	for name, payload := range cases {
+		t.Run(name, func(t *testing.T) {
+			want := db.ToSQL(func(tx *gorm.DB) *gorm.DB {
+				var students []testStudent
+				return ApplyQuery(NewGormQueryBuilder(tx.Model(new(testStudent))), payload).Db.Find(&students)
+			})
+
+			plan, err := CompileQuery[testStudent](db, payload)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := db.ToSQL(func(tx *gorm.DB) *gorm.DB {
+				query, err := plan.Apply(tx.Model(new(testStudent)), payload)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var students []testStudent
+				return query.Find(&students)
+			})
+
+			if got != want {
+				t.Errorf("plan SQL differs from ApplyQuery\nplan:  %s\napply: %s", got, want)
+			}
+		})
+	}
+}
+
+func TestApplyQueryRejectsUnknownFields(t *testing.T) {
+	db := dryRunDB(t)
+
+	for name, payload := range map[string]fwork_server_orm.QueryPayload{
+		"where":               {Where: mustFilter(t, `{"nope": 1}`)},
+		"sort":                {Order: []fwork_server_orm.Order{{Field: "age; DROP TABLE test_students"}}},
+		"select":              {Select: []string{"course.title FROM x; --"}},
+		"json key":            {Where: mustFilter(t, `{"metadata.a}' OR '1": 1}`)},
+		"plain field":         {Select: []string{"nope"}},
+		"write-only":          {Where: mustFilter(t, `{"password": "x"}`)},
+		"write-only sort":     {Order: []fwork_server_orm.Order{{Field: "password"}}},
+		"table select":        {Select: []string{"test_students.password"}},
+		"write-only relation": {Select: []string{"course.secret"}},
+		"json select":         {Select: []string{"metadata.a"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var students []testStudent
+			err := ApplyQuery(NewGormQueryBuilder(db.Model(new(testStudent))), payload).Db.Find(&students).Error
+
+			var e *fwork_server_orm.Error
+			if !errors.As(err, &e) || e.Kind != fwork_server_orm.ErrorBadQuery {
+				t.Fatalf("ApplyQuery error = %v, want a bad query error", err)
+			}
+
+			if _, err := CompileQuery[testStudent](db, payload); err == nil {
+				t.Fatal("CompileQuery accepted the payload")
+			}
+		})
+	}
+}
+
+func TestApplyQuerySelectsRelationColumns(t *testing.T) {
+	db := dryRunDB(t)
+
+	sql := db.ToSQL(func(tx *gorm.DB) *gorm.DB {
+		var students []testStudent
+		payload := fwork_server_orm.QueryPayload{Select: []string{"id", "course.title"}}
+		return ApplyQuery(NewGormQueryBuilder(tx.Model(new(testStudent))), payload).Db.Find(&students)
+	})
+
+	for _, want := range []string{`"course"."title"`, `LEFT JOIN "test_courses" "course"`} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("SQL has no %s: %s", want, sql)
+		}
+	}
+}
+
+func TestApplyQuerySortsByRelationPaths(t *testing.T) {
+	db := dryRunDB(t)
+
+	sql := db.ToSQL(func(tx *gorm.DB) *gorm.DB {
+		var students []testStudent
+		payload := fwork_server_orm.QueryPayload{
+			Order: []fwork_server_orm.Order{{Field: "course.title"}, {Field: "metadata.level", Dir: "desc"}},
+		}
+		return ApplyQuery(NewGormQueryBuilder(tx.Model(new(testStudent))), payload).Db.Find(&students)
+	})
+
+	for _, want := range []string{
+		`LEFT JOIN "test_courses" "course"`,
+		`ORDER BY "course"."title" ASC,"test_students"."metadata" #>> '{level}' DESC`,
+	} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("SQL has no %s: %s", want, sql)
+		}
+	}
+}
+
+func TestRelationJoinsSkipDeletedRows(t *testing.T) {
+	db := dryRunDB(t)
+
+	for name, tc := range map[string]struct {
+		payload fwork_server_orm.QueryPayload
+		want    []string
+		not     []string
+	}{
+		"filter": {
+			payload: fwork_server_orm.QueryPayload{Where: mustFilter(t, `{"course.group.name": "A"}`)},
+			want: []string{
+				`LEFT JOIN "test_courses" "course" ON "course"."id" = "test_students"."course_id" AND "course"."deleted_at" IS NULL`,
+				`LEFT JOIN "test_groups" "group" ON "group"."id" = "course"."group_id" AND "group"."deleted_at" IS NULL`,
+				`"test_students"."deleted_at" IS NULL`,
+			},
+		},
+		"sort": {
+			payload: fwork_server_orm.QueryPayload{Order: []fwork_server_orm.Order{{Field: "course.title"}}},
+			want:    []string{`AND "course"."deleted_at" IS NULL`},
+		},
+		"with deleted": {
+			payload: fwork_server_orm.QueryPayload{Where: mustFilter(t, `{"course.title": "A"}`), WithDeleted: true},
+			not:     []string{"IS NULL", "IS NOT NULL"},
+		},
+		"only deleted": {
+			payload: fwork_server_orm.QueryPayload{Where: mustFilter(t, `{"course.title": "A"}`), OnlyDeleted: true},
+			want:    []string{`"test_students"."deleted_at" IS NOT NULL`},
+			not:     []string{`"course"."deleted_at" IS NULL`, `"test_students"."deleted_at" IS NULL`},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			plan, err := CompileQuery[testStudent](db, tc.payload)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			for _, run := range []struct {
+				name  string
+				query func(tx *gorm.DB) *gorm.DB
+			}{
+				{"ApplyQuery", func(tx *gorm.DB) *gorm.DB {
+					return ApplyQuery(NewGormQueryBuilder(tx.Model(new(testStudent))), tc.payload).Db
+				}},
+				{"plan", func(tx *gorm.DB) *gorm.DB {
+					query, _ := plan.Apply(tx.Model(new(testStudent)), tc.payload)
+					return query
+				}},
+			} {
+				sql := db.ToSQL(func(tx *gorm.DB) *gorm.DB {
+					var students []testStudent
+					return run.query(tx).Find(&students)
+				})
+
+				for _, want := range tc.want {
+					if !strings.Contains(sql, want) {
+						t.Errorf("%s: SQL has no %s: %s", run.name, want, sql)
+					}
+				}
+				for _, not := range tc.not {
+					if strings.Contains(sql, not) {
+						t.Errorf("%s: SQL has %s: %s", run.name, not, sql)
+					}
+				}
+			}
+		})
+	}
+}
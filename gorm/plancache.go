@@ -0,0 +1,189 @@
+package fwork_server_gorm
+
+import (
+	"container/list"
+	"encoding/json"
+	"net/http"
+	"sync"
+
+	fwork_server_orm "github.com/joabssilveira/GoQLite/core"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+const DefaultPlanCacheSize = 1000
+
+// PlanCache keeps the compiled plans of the most recent query shapes (LRU).
+// It can be shared by several resources and is safe for concurrent use.
+//
+//	plans := NewPlanCache(500)
+//	users.Plans = plans
+//	r.HandleFunc("/_stats/plans", plans.StatsHandler()).Methods("GET")
+type PlanCache struct {
+	mu       sync.Mutex
+	capacity int
+	entries  map[planKey]*list.Element
+	lru      *list.List // front: mais recente
+
+	hits      uint64
+	misses    uint64
+	evictions uint64
+	failures  uint64
+}
+
+type planKey struct {
+	schema *schema.Schema
+	hash   string
+}
+
+type planEntry struct {
+	key  planKey
+	plan *QueryPlan
+	hits uint64
+}
+
+type PlanCacheStats struct {
+	Size      int    `json:"size"`
+	Capacity  int    `json:"capacity"`
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Evictions uint64 `json:"evictions"`
+	// Failures counts payloads that did not compile (unknown fields...).
+	Failures uint64 `json:"failures"`
+
+	Plans []PlanStats `json:"plans,omitempty"`
+}
+
+type PlanStats struct {
+	Model string `json:"model"`
+	Hash  string `json:"hash"`
+	Shape string `json:"shape"`
+	Where string `json:"where,omitempty"`
+	Hits  uint64 `json:"hits"`
+}
+
+// NewPlanCache creates a cache of up to capacity plans
+// (DefaultPlanCacheSize when capacity <= 0).
+func NewPlanCache(capacity int) *PlanCache {
+	if capacity <= 0 {
+		capacity = DefaultPlanCacheSize
+	}
+
+	return &PlanCache{
+		capacity: capacity,
+		entries:  make(map[planKey]*list.Element),
+		lru:      list.New(),
+	}
+}
+
+// Apply compiles payload (or reuses the plan of its shape) for the model of
+// db and binds its values. db must have the model set.
+func (c *PlanCache) Apply(db *gorm.DB, payload fwork_server_orm.QueryPayload) (*gorm.DB, error) {
+	s := schemaOf(db, db.Statement.Model)
+	if s == nil {
+		// sem schema não há o que compilar
+		return ApplyQuery(NewGormQueryBuilder(db), payload).Db, nil
+	}
+
+	plan, err := c.plan(s, payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return plan.apply(db, payload)
+}
+
+func (c *PlanCache) plan(s *schema.Schema, payload fwork_server_orm.QueryPayload) (*QueryPlan, error) {
+	shape := payload.Shape()
+	key := planKey{schema: s, hash: fwork_server_orm.ShapeHash(shape)}
+
+	c.mu.Lock()
+	if elem, ok := c.entries[key]; ok {
+		c.lru.MoveToFront(elem)
+		entry := elem.Value.(*planEntry)
+		entry.hits++
+		c.hits++
+		c.mu.Unlock()
+		return entry.plan, nil
+	}
+	c.misses++
+	c.mu.Unlock()
+
+	// compila fora do lock; duas compilações do mesmo shape geram o mesmo plano
+	plan, err := compileQuery(s, payload, shape)
+	if err != nil {
+		c.mu.Lock()
+		c.failures++
+		c.mu.Unlock()
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		return elem.Value.(*planEntry).plan, nil
+	}
+
+	c.entries[key] = c.lru.PushFront(&planEntry{key: key, plan: plan})
+
+	for c.lru.Len() > c.capacity {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		delete(c.entries, oldest.Value.(*planEntry).key)
+		c.evictions++
+	}
+
+	return plan, nil
+}
+
+// Stats returns the counters of the cache. withPlans also lists the cached
+// plans, most recent first.
+func (c *PlanCache) Stats(withPlans bool) PlanCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := PlanCacheStats{
+		Size:      c.lru.Len(),
+		Capacity:  c.capacity,
+		Hits:      c.hits,
+		Misses:    c.misses,
+		Evictions: c.evictions,
+		Failures:  c.failures,
+	}
+
+	if withPlans {
+		for elem := c.lru.Front(); elem != nil; elem = elem.Next() {
+			entry := elem.Value.(*planEntry)
+			stats.Plans = append(stats.Plans, PlanStats{
+				Model: entry.key.schema.Name,
+				Hash:  entry.plan.Hash,
+				Shape: entry.plan.Shape,
+				Where: entry.plan.Where,
+				Hits:  entry.hits,
+			})
+		}
+	}
+
+	return stats
+}
+
+// Reset drops every plan and zeroes the counters.
+func (c *PlanCache) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries = make(map[planKey]*list.Element)
+	c.lru.Init()
+	c.hits, c.misses, c.evictions, c.failures = 0, 0, 0, 0
+}
+
+// StatsHandler serves Stats as JSON (?plans=true lists the plans).
+func (c *PlanCache) StatsHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		stats := c.Stats(r.URL.Query().Get("plans") == "true")
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(stats)
+	}
+}
@@ -0,0 +1,162 @@
+package fwork_server_gorm
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	fwork_server_orm "github.com/joabssilveira/GoQLite/core"
+	"gorm.io/gorm"
+)
+
+// GormResource groups the generic handlers of a model with the options that
+// apply to that model only. The zero value of every option keeps the default
+// behavior, so a resource can be configured gradually:
+//
+//	users := NewGormResource[User](db, "id")
+//	users.Cache.ETag = ETagUpdatedAt
+//
+//	r.HandleFunc("/users", users.ListHandler()).Methods("GET")
+//	r.HandleFunc("/users/{id}", users.GetHandler()).Methods("GET")
+type GormResource[T any] struct {
+	Db      *gorm.DB
+	KeyName string
+
+	// Cache configures ETag / Last-Modified handling for GET handlers.
+	Cache CacheOptions
+
+	// AllowDeleted authorizes the withDeleted / onlyDeleted query options.
+	// Nil denies them.
+	AllowDeleted func(r *http.Request) bool
+
+	// AllowHardDelete / AllowRestore authorize HardDeleteHandler and
+	// RestoreHandler (403 otherwise). Nil uses AllowDeleted.
+	AllowHardDelete func(r *http.Request) bool
+	AllowRestore    func(r *http.Request) bool
+
+	// Scope restricts every row the resource can see (e.g. multi-tenant).
+	// It is merged with the user filter via MergeWhereWithAnd in list, get,
+	// update-where, delete-where and export jobs. Update, delete, hard
+	// delete and restore by key answer 404 for rows outside it; created,
+	// imported and updated rows must match it (403, rolled back).
+	Scope func(r *http.Request) fwork_server_orm.Filter
+
+	// Bulk configures the bulk handlers (transaction mode, batch size).
+	Bulk BulkOptions[T]
+
+	// Upsert enables ?upsert=true on the create and bulk create handlers.
+	Upsert *UpsertOptions
+
+	// Validator validates payloads before create / update (see Validator
+	// and TagValidator). Nil uses DefaultStructValidator.
+	Validator StructValidator
+
+	// ErrorResponder writes the error responses of the resource. Nil uses
+	// fwork_server_orm.DefaultErrorResponder (application/problem+json).
+	ErrorResponder fwork_server_orm.ErrorResponder
+
+	// Hooks run in every handler except update / delete by filter.
+	Hooks *Hooks[T]
+
+	// ListConsistency chooses how the list handler runs its count and data
+	// queries (ListSequential, ListSnapshot or ListConcurrent).
+	ListConsistency ListConsistency
+
+	// CountMode is used when the request has no count param. Default: exact.
+	CountMode fwork_server_orm.CountMode
+
+	// Plans caches the compiled list queries per query shape. Can be shared
+	// between resources.
+	Plans *PlanCache
+
+	// Export configures the file exports of the list handler
+	// (?format=csv|ndjson|xlsx or the matching Accept) and of the export jobs.
+	Export ExportOptions[T]
+
+	// Jobs enables the background export handlers (StartExportJobHandler,
+	// ExportJobHandler, ExportJobDownloadHandler). Can be shared between
+	// resources.
+	Jobs *ExportJobs
+
+	// JobOwner identifies the requester of export jobs (e.g. the user id).
+	// The status and download handlers answer 404 to other requesters and
+	// to requests with another Scope. Nil: only the Scope is checked.
+	JobOwner func(r *http.Request) string
+
+	// Import configures ImportHandler (CSV / NDJSON uploads).
+	Import ImportOptions[T]
+
+	// QueryTimeout limits each database call of the handlers. Expired
+	// queries answer 504 (503 when the client cancels the request).
+	QueryTimeout time.Duration
+}
+
+func NewGormResource[T any](db *gorm.DB, keyName string) *GormResource[T] {
+	return &GormResource[T]{
+		Db:      db,
+		KeyName: keyName,
+	}
+}
+
+// query runs fn with the request context, limited by QueryTimeout (see
+// withQueryTimeout).
+func (res *GormResource[T]) query(r *http.Request, fn func(ctx context.Context, db *gorm.DB) error) error {
+	return withQueryTimeout(r.Context(), res.Db, res.QueryTimeout, fn)
+}
+
+func (res *GormResource[T]) canSeeDeleted(r *http.Request) bool {
+	return res.AllowDeleted != nil && res.AllowDeleted(r)
+}
+
+func (res *GormResource[T]) canHardDelete(r *http.Request) bool {
+	if res.AllowHardDelete != nil {
+		return res.AllowHardDelete(r)
+	}
+	return res.canSeeDeleted(r)
+}
+
+func (res *GormResource[T]) canRestore(r *http.Request) bool {
+	if res.AllowRestore != nil {
+		return res.AllowRestore(r)
+	}
+	return res.canSeeDeleted(r)
+}
+
+func (res *GormResource[T]) scope(r *http.Request) fwork_server_orm.Filter {
+	if res.Scope == nil {
+		return fwork_server_orm.Filter{}
+	}
+	return res.Scope(r)
+}
+
+// jobOwner is the Owner / Scope an export job started by r gets.
+func (res *GormResource[T]) jobOwner(r *http.Request) (ExportJob, error) {
+	var owner ExportJob
+	if res.JobOwner != nil {
+		owner.Owner = res.JobOwner(r)
+	}
+
+	if scope := res.scope(r); !scope.IsEmpty() {
+		raw, err := json.Marshal(scope)
+		if err != nil {
+			return owner, err
+		}
+		owner.Scope = string(raw)
+	}
+
+	return owner, nil
+}
+
+// upsert resolves the ?upsert=true option of create requests.
+func (res *GormResource[T]) upsert(r *http.Request) (*UpsertOptions, error) {
+	if r.URL.Query().Get("upsert") != "true" {
+		return nil, nil
+	}
+
+	if res.Upsert == nil {
+		return nil, errUpsertDisabled
+	}
+
+	return res.Upsert, nil
+}
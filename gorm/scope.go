@@ -0,0 +1,218 @@
+package fwork_server_gorm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+
+	fwork_server_orm "github.com/joabssilveira/GoQLite/core"
+	"gorm.io/gorm"
+)
+
+var errOutOfScope = errors.New("record is outside the resource scope")
+
+// scopeHooks adds the Scope checks to hooks for the write handlers: the row
+// updated / deleted by key must be in the scope (404 otherwise), and the rows
+// created, upserted or updated must still be in it after the write (403, the
+// write is rolled back). unscoped also finds soft-deleted rows (hard delete).
+func (res *GormResource[T]) scopeHooks(r *http.Request, hooks *Hooks[T], unscoped bool) *Hooks[T] {
+	if res.Scope == nil {
+		return hooks
+	}
+
+	scope := res.Scope(r)
+	if scope.IsEmpty() {
+		return hooks
+	}
+
+	var h Hooks[T]
+	if hooks != nil {
+		h = *hooks
+	}
+
+	mustExist := func(tx *gorm.DB, id any) error {
+		ok, err := res.inScope(tx, scope, id, unscoped)
+		if err == nil && !ok {
+			err = gorm.ErrRecordNotFound
+		}
+		return err
+	}
+
+	mustStay := func(tx *gorm.DB, id any) error {
+		ok, err := res.inScope(tx, scope, id, unscoped)
+		if err == nil && !ok {
+			err = fwork_server_orm.NewError(fwork_server_orm.ErrorForbidden, errOutOfScope.Error(), errOutOfScope)
+		}
+		return err
+	}
+
+	// cópias: os slices de hooks são compartilhados entre requests
+	h.BeforeUpdate = append([]UpdateHook[T]{func(ctx context.Context, tx *gorm.DB, id any, item *T) error {
+		return mustExist(tx, id)
+	}}, h.BeforeUpdate...)
+
+	h.AfterUpdate = append(append([]UpdateHook[T]{}, h.AfterUpdate...), func(ctx context.Context, tx *gorm.DB, id any, item *T) error {
+		return mustStay(tx, id)
+	})
+
+	h.BeforeDelete = append([]DeleteHook{func(ctx context.Context, tx *gorm.DB, id any) error {
+		return mustExist(tx, id)
+	}}, h.BeforeDelete...)
+
+	h.AfterCreate = append(append([]CreateHook[T]{}, h.AfterCreate...), func(ctx context.Context, tx *gorm.DB, item *T) error {
+		id, ok := res.keyOf(tx, item)
+		if !ok {
+			return fwork_server_orm.NewError(fwork_server_orm.ErrorForbidden, errOutOfScope.Error(), errOutOfScope)
+		}
+		return mustStay(tx, id)
+	})
+
+	return &h
+}
+
+// upsertScopeHooks adds to hooks the check of the row an upsert would
+// overwrite: when a row with the same conflict key exists outside the Scope
+// the create answers 403 before the write. The AfterCreate check of
+// scopeHooks only sees the row after ON CONFLICT has taken it over.
+func (res *GormResource[T]) upsertScopeHooks(r *http.Request, hooks *Hooks[T], upsert *UpsertOptions) *Hooks[T] {
+	if upsert == nil || upsert.DoNothing || res.Scope == nil {
+		return hooks
+	}
+
+	scope := res.Scope(r)
+	if scope.IsEmpty() {
+		return hooks
+	}
+
+	var h Hooks[T]
+	if hooks != nil {
+		h = *hooks
+	}
+
+	// por último: os hooks do usuário podem mudar a chave
+	h.BeforeCreate = append(append([]CreateHook[T]{}, h.BeforeCreate...), func(ctx context.Context, tx *gorm.DB, item *T) error {
+		conflict, ok := upsertConflict(tx, item, upsert)
+		if !ok {
+			return nil
+		}
+
+		existing, err := countRows[T](tx, conflict, true)
+		if err != nil || existing == 0 {
+			return err
+		}
+
+		inScope, err := countRows[T](tx, fwork_server_orm.MergeWhereWithAnd(conflict, scope), true)
+		if err == nil && inScope == 0 {
+			err = fwork_server_orm.NewError(fwork_server_orm.ErrorForbidden, errOutOfScope.Error(), errOutOfScope)
+		}
+		return err
+	})
+
+	return &h
+}
+
+// upsertConflict is the filter of the row item conflicts with: the conflict
+// target of upsert, or the primary key. A zero primary key is generated by
+// the insert, so there is no conflict to check.
+func upsertConflict[T any](db *gorm.DB, item *T, upsert *UpsertOptions) (fwork_server_orm.Filter, bool) {
+	s := schemaOf(db, new(T))
+	if s == nil {
+		return fwork_server_orm.Filter{}, false
+	}
+
+	fields := s.PrimaryFields
+	if len(upsert.Columns) > 0 {
+		fields = nil
+		for _, name := range upsert.Columns {
+			if field := lookUpField(s, name); field != nil && field.DBName != "" {
+				fields = append(fields, field)
+			}
+		}
+	}
+	if len(fields) == 0 {
+		return fwork_server_orm.Filter{}, false
+	}
+
+	conflict := fwork_server_orm.Filter{Fields: map[string]fwork_server_orm.FieldExpr{}}
+	for _, field := range fields {
+		value, zero := field.ValueOf(dbContext(db), reflect.ValueOf(item).Elem())
+		if zero && field.PrimaryKey {
+			return fwork_server_orm.Filter{}, false
+		}
+		conflict.Fields[field.DBName] = fwork_server_orm.FieldExpr{Eq: value}
+	}
+
+	return conflict, true
+}
+
+// inScope reports whether the row with key id matches the scope filter.
+func (res *GormResource[T]) inScope(tx *gorm.DB, scope fwork_server_orm.Filter, id any, unscoped bool) (bool, error) {
+	count, err := countRows[T](tx, fwork_server_orm.MergeWhereWithAnd(keyFilter(res.keyName(tx), id), scope), unscoped)
+	return count > 0, err
+}
+
+// countRows counts the rows of T matching where; unscoped also counts the
+// soft-deleted ones.
+func countRows[T any](tx *gorm.DB, where fwork_server_orm.Filter, unscoped bool) (int64, error) {
+	builder := NewGormQueryBuilder(tx.Model(new(T)))
+	builder = ApplyQuery(builder, fwork_server_orm.QueryPayload{
+		Where:       where,
+		WithDeleted: unscoped,
+	})
+
+	var count int64
+	err := builder.Db.Count(&count).Error
+	return count, err
+}
+
+// keyName is KeyName or the primary key column (create handlers may have no
+// KeyName).
+func (res *GormResource[T]) keyName(db *gorm.DB) string {
+	if res.KeyName != "" {
+		return res.KeyName
+	}
+	if s := schemaOf(db, new(T)); s != nil && s.PrioritizedPrimaryField != nil {
+		return s.PrioritizedPrimaryField.DBName
+	}
+	return "id"
+}
+
+func (res *GormResource[T]) keyOf(db *gorm.DB, item *T) (any, bool) {
+	s := schemaOf(db, new(T))
+	if s == nil {
+		return nil, false
+	}
+
+	field := lookUpField(s, res.keyName(db))
+	if field == nil {
+		return nil, false
+	}
+
+	value, zero := field.ValueOf(dbContext(db), reflect.ValueOf(item).Elem())
+	return value, !zero
+}
+
+// inScopeTx runs fn in a transaction after checking that the row id is in
+// the Scope (for writes without hooks, e.g. restore).
+func (res *GormResource[T]) inScopeTx(r *http.Request, db *gorm.DB, id any, unscoped bool, fn func(tx *gorm.DB) error) error {
+	if res.Scope == nil {
+		return fn(db)
+	}
+
+	scope := res.Scope(r)
+	if scope.IsEmpty() {
+		return fn(db)
+	}
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		ok, err := res.inScope(tx, scope, id, unscoped)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			return gorm.ErrRecordNotFound
+		}
+		return fn(tx)
+	})
+}
@@ -0,0 +1,94 @@
+package fwork_server_gorm
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+
+	fwork_server_orm "github.com/joabssilveira/GoQLite/core"
+)
+
+type testTenantRow struct {
+	ID       uint   `json:"id"`
+	TenantID uint   `json:"tenantId"`
+	Name     string `json:"name"`
+}
+
+// tenantResource scopes the rows to the X-Tenant header.
+func tenantResource(t *testing.T) *GormResource[testTenantRow] {
+	db := sqliteDB(t, &testTenantRow{})
+	if err := db.Create(&[]testTenantRow{{ID: 1, TenantID: 1, Name: "one"}, {ID: 2, TenantID: 2, Name: "two"}}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	res := NewGormResource[testTenantRow](db, "id")
+	res.Upsert = &UpsertOptions{}
+	res.Scope = func(r *http.Request) fwork_server_orm.Filter {
+		tenant, _ := strconv.Atoi(r.Header.Get("X-Tenant"))
+		return fwork_server_orm.Filter{Fields: map[string]fwork_server_orm.FieldExpr{"tenant_id": {Eq: tenant}}}
+	}
+	return res
+}
+
+func TestUpsertRefusesRowsOutsideTheScope(t *testing.T) {
+	res := tenantResource(t)
+
+	for name, tc := range map[string]struct {
+		handler http.HandlerFunc
+		body    string
+	}{
+		"create": {res.CreateHandler(), `{"id": 1, "tenantId": 2, "name": "taken"}`},
+		"bulk":   {res.BulkCreateHandler(), `[{"id": 1, "tenantId": 2, "name": "taken"}]`},
+	} {
+		t.Run(name, func(t *testing.T) {
+			w := serve(tc.handler, "POST", "/rows?upsert=true", tc.body, "X-Tenant", "2")
+			if w.Code == http.StatusOK || w.Code == http.StatusCreated || w.Code == http.StatusMultiStatus {
+				t.Fatalf("status = %d, want the upsert refused: %s", w.Code, w.Body)
+			}
+
+			var row testTenantRow
+			res.Db.First(&row, 1)
+			if row.TenantID != 1 || row.Name != "one" {
+				t.Fatalf("row 1 = %+v, want it untouched", row)
+			}
+		})
+	}
+
+	w := serve(res.CreateHandler(), "POST", "/rows?upsert=true", `{"id": 1, "tenantId": 2, "name": "taken"}`, "X-Tenant", "2")
+	if w.Code != http.StatusForbidden {
+		t.Errorf("create status = %d, want 403", w.Code)
+	}
+
+	w = serve(res.CreateHandler(), "POST", "/rows?upsert=true", `{"id": 2, "tenantId": 2, "name": "mine"}`, "X-Tenant", "2")
+	if w.Code != http.StatusCreated {
+		t.Fatalf("same tenant upsert status = %d: %s", w.Code, w.Body)
+	}
+
+	var row testTenantRow
+	res.Db.First(&row, 2)
+	if row.Name != "mine" {
+		t.Errorf("row 2 = %+v, want it updated", row)
+	}
+}
+
+func TestUpdateWhereRefusesScopeColumns(t *testing.T) {
+	res := tenantResource(t)
+
+	for _, set := range []string{`{"tenantId": 2}`, `{"tenant_id": 2, "name": "moved"}`} {
+		w := serve(res.UpdateWhereHandler(), "PATCH", "/rows", `{"where": {"name": "one"}, "set": `+set+`}`, "X-Tenant", "1")
+		if w.Code != http.StatusForbidden {
+			t.Errorf("set %s: status = %d, want 403: %s", set, w.Code, w.Body)
+		}
+	}
+
+	var row testTenantRow
+	res.Db.First(&row, 1)
+	if row.TenantID != 1 || row.Name != "one" {
+		t.Fatalf("row 1 = %+v, want it untouched", row)
+	}
+
+	w := serve(res.UpdateWhereHandler(), "PATCH", "/rows", `{"where": {"name": "one"}, "set": {"name": "renamed"}}`, "X-Tenant", "1")
+	if w.Code != http.StatusOK {
+		t.Fatalf("status = %d: %s", w.Code, w.Body)
+	}
+}
@@ -0,0 +1,70 @@
+package fwork_server_gorm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	fwork_server_orm "github.com/joabssilveira/GoQLite/core"
+	"gorm.io/gorm"
+)
+
+// withQueryTimeout runs fn with ctx limited by timeout. On Postgres the
+// timeout is also set as statement_timeout, inside a transaction, so the
+// server stops the query even if the driver does not cancel it. Errors caused
+// by the context are returned as timeout / unavailable errors.
+func withQueryTimeout(
+	ctx context.Context,
+	db *gorm.DB,
+	timeout time.Duration,
+	fn func(ctx context.Context, db *gorm.DB) error,
+) error {
+
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	db = db.WithContext(ctx)
+
+	var err error
+	if timeout > 0 && isPostgres(db) {
+		err = db.Transaction(func(tx *gorm.DB) error {
+			// SET LOCAL vale só até o fim da transação
+			ms := timeout.Milliseconds()
+			if err := tx.Exec(fmt.Sprintf("SET LOCAL statement_timeout = %d", max(ms, 1))).Error; err != nil {
+				return err
+			}
+			return fn(ctx, tx)
+		})
+	} else {
+		err = fn(ctx, db)
+	}
+
+	return contextError(ctx, err)
+}
+
+func contextError(ctx context.Context, err error) error {
+	if err == nil || ctx.Err() == nil {
+		return err
+	}
+
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return fwork_server_orm.NewError(fwork_server_orm.ErrorTimeout, "query timed out", err)
+	}
+
+	return fwork_server_orm.NewError(fwork_server_orm.ErrorUnavailable, "request cancelled", err)
+}
+
+// isQueryTimeout reports deadline errors and statement timeouts
+// (SQLSTATE 57014 query_canceled).
+func isQueryTimeout(err error) bool {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return true
+	}
+
+	var state sqlStateError
+	return errors.As(err, &state) && state.SQLState() == "57014"
+}
@@ -0,0 +1,59 @@
+package fwork_server_gorm
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UpsertOptions turns a create into INSERT ... ON CONFLICT, through
+// clause.OnConflict, so each dialect renders its own syntax.
+type UpsertOptions struct {
+	// Columns is the conflict target (JSON names or columns). Empty: primary key.
+	Columns []string
+
+	// UpdateColumns are updated on conflict. Empty: every column.
+	UpdateColumns []string
+
+	// DoNothing keeps the existing row instead of updating it.
+	DoNothing bool
+}
+
+// withUpsert adds the ON CONFLICT clause to db, resolving field names through
+// the schema of T.
+func withUpsert[T any](db *gorm.DB, upsert *UpsertOptions) *gorm.DB {
+	if upsert == nil {
+		return db
+	}
+
+	stmt := &gorm.Statement{DB: db}
+	_ = stmt.Parse(new(T))
+
+	column := func(name string) string {
+		if f := lookUpField(stmt.Schema, name); f != nil && f.DBName != "" {
+			return f.DBName
+		}
+		return name
+	}
+
+	onConflict := clause.OnConflict{
+		DoNothing: upsert.DoNothing,
+	}
+
+	for _, name := range upsert.Columns {
+		onConflict.Columns = append(onConflict.Columns, clause.Column{Name: column(name)})
+	}
+
+	if !upsert.DoNothing {
+		if len(upsert.UpdateColumns) == 0 {
+			onConflict.UpdateAll = true
+		} else {
+			columns := make([]string, 0, len(upsert.UpdateColumns))
+			for _, name := range upsert.UpdateColumns {
+				columns = append(columns, column(name))
+			}
+			onConflict.DoUpdates = clause.AssignmentColumns(columns)
+		}
+	}
+
+	return db.Clauses(onConflict)
+}
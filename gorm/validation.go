@@ -0,0 +1,440 @@
+package fwork_server_gorm
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	fwork_server_orm "github.com/joabssilveira/GoQLite/core"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// Validator is implemented by models that validate themselves before
+// persistence (like PersistSanitizer). Returning a *ValidationError gives
+// field-level errors; any other error is reported as a single error.
+type Validator interface {
+	Validate() error
+}
+
+// StructValidator validates any struct, usually from struct tags.
+// TagValidator is the built-in one; adapters for other libraries
+// (e.g. go-playground/validator) only need this method.
+type StructValidator interface {
+	ValidateStruct(v any) error
+}
+
+// DefaultStructValidator is used when no StructValidator is given. Nil
+// disables tag validation.
+var DefaultStructValidator StructValidator
+
+// validateForPersist runs the struct validator and then the model Validator.
+func validateForPersist(v any, sv StructValidator) error {
+	if sv == nil {
+		sv = DefaultStructValidator
+	}
+
+	verr := &fwork_server_orm.ValidationError{}
+
+	if sv != nil {
+		mergeValidationError(verr, sv.ValidateStruct(v))
+	}
+
+	if m, ok := v.(Validator); ok {
+		mergeValidationError(verr, m.Validate())
+	}
+
+	return verr.OrNil()
+}
+
+// validateForUpdate validates the record as it will be after GormUpdate:
+// Updates only writes the non-zero fields of payload, so they are laid over
+// the stored row before validating (a partial payload does not fail
+// required / min). Nothing is validated when the row is not found (nothing is
+// written either).
+func validateForUpdate[T any](tx *gorm.DB, keyName string, id any, payload *T, sv StructValidator) error {
+	if sv == nil {
+		sv = DefaultStructValidator
+	}
+	if _, ok := any(payload).(Validator); !ok && sv == nil {
+		return nil
+	}
+
+	s := schemaOf(tx, payload)
+	if s == nil {
+		return validateForPersist(payload, sv)
+	}
+
+	var merged T
+	result := tx.Session(&gorm.Session{NewDB: true}).Model(new(T)).
+		Where(fmt.Sprintf("%s = ?", keyName), id).
+		Limit(1).
+		Find(&merged)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return nil
+	}
+
+	ctx := dbContext(tx)
+	src := reflect.ValueOf(payload).Elem()
+	dst := reflect.ValueOf(&merged).Elem()
+
+	for _, field := range s.Fields {
+		if value, zero := field.ValueOf(ctx, src); !zero {
+			if err := field.Set(ctx, dst, value); err != nil {
+				return err
+			}
+		}
+	}
+
+	return validateForPersist(&merged, sv)
+}
+
+func mergeValidationError(dst *fwork_server_orm.ValidationError, err error) {
+	if err == nil {
+		return
+	}
+
+	var verr *fwork_server_orm.ValidationError
+	if errors.As(err, &verr) {
+		dst.Errors = append(dst.Errors, verr.Errors...)
+		return
+	}
+
+	dst.Add("", fwork_server_orm.FieldErrorInvalid, err.Error())
+}
+
+// =========================
+// TagValidator
+// =========================
+
+// TagValidator validates the `validate` struct tag. Field errors use the JSON
+// name of the field. Supported rules:
+//
+//	required        non-zero value
+//	min=N / max=N   length for strings and slices, value for numbers
+//	len=N           exact length
+//	oneof=a b c     value in the list
+//	email           valid e-mail address
+//
+// Example: `json:"email" validate:"required,email,max=120"`
+type TagValidator struct {
+	TagName string // default: validate
+}
+
+func (tv TagValidator) ValidateStruct(v any) error {
+	tagName := tv.TagName
+	if tagName == "" {
+		tagName = "validate"
+	}
+
+	value := indirect(reflect.ValueOf(v))
+	if value.Kind() != reflect.Struct {
+		return nil
+	}
+
+	verr := &fwork_server_orm.ValidationError{}
+	validateStructFields(value, tagName, verr)
+	return verr.OrNil()
+}
+
+func validateStructFields(value reflect.Value, tagName string, verr *fwork_server_orm.ValidationError) {
+	typ := value.Type()
+
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		fieldValue := value.Field(i)
+
+		if field.Anonymous && indirect(fieldValue).Kind() == reflect.Struct {
+			validateStructFields(indirect(fieldValue), tagName, verr)
+			continue
+		}
+
+		tag := field.Tag.Get(tagName)
+		if tag == "" || tag == "-" {
+			continue
+		}
+
+		name := jsonName(field)
+		if name == "" {
+			name = field.Name
+		}
+
+		for _, rule := range strings.Split(tag, ",") {
+			rule = strings.TrimSpace(rule)
+			if rule == "" {
+				continue
+			}
+
+			ruleName, param, _ := strings.Cut(rule, "=")
+
+			if code, msg, ok := checkRule(fieldValue, ruleName, param); !ok {
+				verr.Add(name, code, msg)
+				break // um erro por campo
+			}
+		}
+	}
+}
+
+func checkRule(v reflect.Value, rule string, param string) (code string, message string, ok bool) {
+	// ponteiro nil: só "required" falha, as outras regras não se aplicam
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return fwork_server_orm.FieldErrorRequired, "is required", rule != "required"
+		}
+		v = v.Elem()
+	}
+
+	switch rule {
+	case "required":
+		return fwork_server_orm.FieldErrorRequired, "is required", !v.IsZero()
+
+	case "min", "max", "len":
+		n, err := strconv.ParseFloat(param, 64)
+		if err != nil {
+			return fwork_server_orm.FieldErrorInvalid, fmt.Sprintf("invalid %s rule: %s", rule, param), false
+		}
+
+		size, unit := measure(v)
+
+		switch rule {
+		case "min":
+			return fwork_server_orm.FieldErrorMin, fmt.Sprintf("must be at least %s%s", param, unit), size >= n
+		case "max":
+			return fwork_server_orm.FieldErrorMax, fmt.Sprintf("must be at most %s%s", param, unit), size <= n
+		default:
+			return fwork_server_orm.FieldErrorLen, fmt.Sprintf("must have exactly %s%s", param, unit), size == n
+		}
+
+	case "oneof":
+		current := fmt.Sprint(v.Interface())
+		for _, option := range strings.Fields(param) {
+			if current == option {
+				return "", "", true
+			}
+		}
+		return fwork_server_orm.FieldErrorOneOf, "must be one of: " + strings.Join(strings.Fields(param), ", "), false
+
+	case "email":
+		if v.Kind() != reflect.String || v.String() == "" {
+			return "", "", true
+		}
+		addr, err := mail.ParseAddress(v.String())
+		return fwork_server_orm.FieldErrorEmail, "must be a valid e-mail address", err == nil && addr.Address == v.String()
+	}
+
+	return fwork_server_orm.FieldErrorInvalid, "unknown validation rule: " + rule, false
+}
+
+// measure returns the length of strings / collections or the numeric value,
+// with the unit used in messages.
+func measure(v reflect.Value) (size float64, unit string) {
+	switch v.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(v.String())), " characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		return float64(v.Len()), " items"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), ""
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), ""
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), ""
+	}
+	return 0, ""
+}
+
+func jsonName(field reflect.StructField) string {
+	tag, ok := field.Tag.Lookup("json")
+	if !ok {
+		return field.Name
+	}
+
+	name := strings.Split(tag, ",")[0]
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+// =========================
+// DB errors
+// =========================
+
+// translateDBError turns unique / foreign key / not null / check violations
+// into a *ValidationError with the JSON name of the field. Other errors are
+// returned unchanged.
+func translateDBError[T any](db *gorm.DB, err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var verr *fwork_server_orm.ValidationError
+	if errors.As(err, &verr) {
+		return err
+	}
+
+	code, columns := classifyDBError(err)
+	if code == "" {
+		return err
+	}
+
+	stmt := &gorm.Statement{DB: db}
+	_ = stmt.Parse(new(T))
+
+	verr = &fwork_server_orm.ValidationError{Err: err}
+
+	message := map[string]string{
+		fwork_server_orm.FieldErrorUnique:     "already exists",
+		fwork_server_orm.FieldErrorForeignKey: "references a record that does not exist",
+		fwork_server_orm.FieldErrorNotNull:    "is required",
+		fwork_server_orm.FieldErrorCheck:      "is not valid",
+	}[code]
+
+	if len(columns) == 0 {
+		verr.Add("", code, message)
+		return verr
+	}
+
+	for _, column := range columns {
+		verr.Add(fieldNameForColumn(stmt.Schema, column), code, message)
+	}
+
+	return verr
+}
+
+func fieldNameForColumn(s *schema.Schema, column string) string {
+	if f := lookUpField(s, column); f != nil {
+		if name := jsonFieldName(f); name != "" {
+			return name
+		}
+	}
+	return column
+}
+
+// sqlStateError is implemented by pgconn.PgError (and other drivers).
+type sqlStateError interface {
+	SQLState() string
+}
+
+func classifyDBError(err error) (code string, columns []string) {
+	var state sqlStateError
+	if errors.As(err, &state) {
+		detail := driverErrorField(state, "Detail")
+
+		switch state.SQLState() {
+		case "23505":
+			return fwork_server_orm.FieldErrorUnique, columnsFromDetail(detail)
+		case "23503":
+			return fwork_server_orm.FieldErrorForeignKey, columnsFromDetail(detail)
+		case "23502":
+			return fwork_server_orm.FieldErrorNotNull, nonEmpty(driverErrorField(state, "ColumnName"))
+		case "23514":
+			return fwork_server_orm.FieldErrorCheck, nil
+		}
+	}
+
+	msg := err.Error()
+
+	switch {
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return fwork_server_orm.FieldErrorUnique, nil
+	case errors.Is(err, gorm.ErrForeignKeyViolated):
+		return fwork_server_orm.FieldErrorForeignKey, nil
+	case errors.Is(err, gorm.ErrCheckConstraintViolated):
+		return fwork_server_orm.FieldErrorCheck, nil
+
+	// SQLite
+	case strings.Contains(msg, "UNIQUE constraint failed:"):
+		return fwork_server_orm.FieldErrorUnique, columnsAfter(msg, "UNIQUE constraint failed:")
+	case strings.Contains(msg, "NOT NULL constraint failed:"):
+		return fwork_server_orm.FieldErrorNotNull, columnsAfter(msg, "NOT NULL constraint failed:")
+	case strings.Contains(msg, "FOREIGN KEY constraint failed"):
+		return fwork_server_orm.FieldErrorForeignKey, nil
+	case strings.Contains(msg, "CHECK constraint failed"):
+		return fwork_server_orm.FieldErrorCheck, nil
+
+	// MySQL
+	case strings.Contains(msg, "Duplicate entry"):
+		return fwork_server_orm.FieldErrorUnique, nil
+	case strings.Contains(msg, "a foreign key constraint fails"):
+		return fwork_server_orm.FieldErrorForeignKey, nil
+	}
+
+	return "", nil
+}
+
+// driverErrorField reads a string field (e.g. pgconn.PgError.Detail) without
+// importing the driver.
+func driverErrorField(err any, name string) string {
+	v := indirect(reflect.ValueOf(err))
+	if v.Kind() != reflect.Struct {
+		return ""
+	}
+
+	f := v.FieldByName(name)
+	if !f.IsValid() || f.Kind() != reflect.String {
+		return ""
+	}
+	return f.String()
+}
+
+// columnsFromDetail parses Postgres details like "Key (a, b)=(1, 2) already exists."
+func columnsFromDetail(detail string) []string {
+	start := strings.Index(detail, "Key (")
+	if start < 0 {
+		return nil
+	}
+
+	rest := detail[start+len("Key ("):]
+	end := strings.Index(rest, ")=")
+	if end < 0 {
+		return nil
+	}
+
+	var columns []string
+	for _, c := range strings.Split(rest[:end], ",") {
+		columns = append(columns, strings.Trim(strings.TrimSpace(c), `"`))
+	}
+	return columns
+}
+
+// columnsAfter parses SQLite messages like "UNIQUE constraint failed: users.email, users.realm".
+func columnsAfter(msg string, prefix string) []string {
+	rest := msg[strings.Index(msg, prefix)+len(prefix):]
+	if i := strings.Index(rest, " ("); i >= 0 {
+		rest = rest[:i]
+	}
+
+	var columns []string
+	for _, c := range strings.Split(rest, ",") {
+		c = strings.TrimSpace(c)
+		if i := strings.LastIndex(c, "."); i >= 0 {
+			c = c[i+1:]
+		}
+		if c != "" {
+			columns = append(columns, c)
+		}
+	}
+	return columns
+}
+
+func nonEmpty(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return []string{s}
+}
@@ -0,0 +1,240 @@
+package fwork_server_gorm
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+
+	fwork_server_orm "github.com/joabssilveira/GoQLite/core"
+	"gorm.io/gorm"
+)
+
+type testValidatedRow struct {
+	ID    uint     `json:"id"`
+	Name  string   `json:"name" validate:"required,min=2,max=5"`
+	Email string   `json:"email" gorm:"uniqueIndex" validate:"email"`
+	Role  string   `json:"role" validate:"oneof=admin user"`
+	Code  string   `json:"code" validate:"len=3"`
+	Tags  []string `json:"tags" gorm:"serializer:json" validate:"max=2"`
+	Age   *int     `json:"age" validate:"min=18"`
+	Note  string   `validate:"max=3"`
+}
+
+func TestTagValidator(t *testing.T) {
+	valid := func() testValidatedRow {
+		return testValidatedRow{Name: "Ana", Email: "ana@example.com", Role: "user", Code: "abc"}
+	}
+	age := func(n int) *int { return &n }
+
+	for name, tc := range map[string]struct {
+		change func(row *testValidatedRow)
+		want   []fwork_server_orm.FieldError
+	}{
+		"valid":          {func(row *testValidatedRow) {}, nil},
+		"nil pointer":    {func(row *testValidatedRow) { row.Age = nil }, nil},
+		"empty email":    {func(row *testValidatedRow) { row.Email = "" }, nil},
+		"required":       {func(row *testValidatedRow) { row.Name = "" }, []fwork_server_orm.FieldError{{Field: "name", Code: "required"}}},
+		"min":            {func(row *testValidatedRow) { row.Name = "A" }, []fwork_server_orm.FieldError{{Field: "name", Code: "min"}}},
+		"max runes":      {func(row *testValidatedRow) { row.Name = "Ação!" }, nil},
+		"max":            {func(row *testValidatedRow) { row.Name = "Ananias" }, []fwork_server_orm.FieldError{{Field: "name", Code: "max"}}},
+		"email":          {func(row *testValidatedRow) { row.Email = "ana" }, []fwork_server_orm.FieldError{{Field: "email", Code: "email"}}},
+		"email name":     {func(row *testValidatedRow) { row.Email = "Ana <ana@example.com>" }, []fwork_server_orm.FieldError{{Field: "email", Code: "email"}}},
+		"oneof":          {func(row *testValidatedRow) { row.Role = "root" }, []fwork_server_orm.FieldError{{Field: "role", Code: "oneof"}}},
+		"len":            {func(row *testValidatedRow) { row.Code = "ab" }, []fwork_server_orm.FieldError{{Field: "code", Code: "len"}}},
+		"slice max":      {func(row *testValidatedRow) { row.Tags = []string{"a", "b", "c"} }, []fwork_server_orm.FieldError{{Field: "tags", Code: "max"}}},
+		"pointer number": {func(row *testValidatedRow) { row.Age = age(17) }, []fwork_server_orm.FieldError{{Field: "age", Code: "min"}}},
+		"no json tag":    {func(row *testValidatedRow) { row.Note = "long" }, []fwork_server_orm.FieldError{{Field: "Note", Code: "max"}}},
+		"several": {
+			func(row *testValidatedRow) { row.Name = ""; row.Role = "" },
+			[]fwork_server_orm.FieldError{{Field: "name", Code: "required"}, {Field: "role", Code: "oneof"}},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			row := valid()
+			tc.change(&row)
+
+			err := TagValidator{}.ValidateStruct(&row)
+			if tc.want == nil {
+				if err != nil {
+					t.Errorf("ValidateStruct = %v, want nil", err)
+				}
+				return
+			}
+
+			var verr *fwork_server_orm.ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("ValidateStruct = %v, want a *ValidationError", err)
+			}
+			if got := fieldCodes(verr.Errors); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("errors = %v, want %v", got, tc.want)
+			}
+		})
+	}
+}
+
+// fieldCodes drops the messages, so the tests only compare field and code.
+func fieldCodes(errs []fwork_server_orm.FieldError) []fwork_server_orm.FieldError {
+	codes := make([]fwork_server_orm.FieldError, len(errs))
+	for i, e := range errs {
+		codes[i] = fwork_server_orm.FieldError{Field: e.Field, Code: e.Code}
+	}
+	return codes
+}
+
+// testPgError mimics pgconn.PgError (SQLState plus Detail / ColumnName).
+type testPgError struct {
+	Code       string
+	Detail     string
+	ColumnName string
+}
+
+func (e *testPgError) Error() string    { return "pg error " + e.Code }
+func (e *testPgError) SQLState() string { return e.Code }
+
+func TestClassifyDBError(t *testing.T) {
+	for name, tc := range map[string]struct {
+		err     error
+		code    string
+		columns []string
+	}{
+		"pg unique":      {&testPgError{Code: "23505", Detail: `Key (email, "realm")=(a, b) already exists.`}, "unique", []string{"email", "realm"}},
+		"pg wrapped":     {fmt.Errorf("create: %w", &testPgError{Code: "23505", Detail: "Key (email)=(a) already exists."}), "unique", []string{"email"}},
+		"pg foreign key": {&testPgError{Code: "23503", Detail: "Key (course_id)=(9) is not present in table courses."}, "foreign_key", []string{"course_id"}},
+		"pg not null":    {&testPgError{Code: "23502", ColumnName: "name"}, "not_null", []string{"name"}},
+		"pg check":       {&testPgError{Code: "23514"}, "check", nil},
+		"pg other":       {&testPgError{Code: "42P01"}, "", nil},
+		"sqlite unique":  {errors.New("constraint failed: UNIQUE constraint failed: users.email, users.realm (2067)"), "unique", []string{"email", "realm"}},
+		"sqlite null":    {errors.New("NOT NULL constraint failed: users.name"), "not_null", []string{"name"}},
+		"sqlite fk":      {errors.New("FOREIGN KEY constraint failed"), "foreign_key", nil},
+		"sqlite check":   {errors.New("CHECK constraint failed: age"), "check", nil},
+		"mysql unique":   {errors.New("Error 1062: Duplicate entry 'a' for key 'email'"), "unique", nil},
+		"mysql fk":       {errors.New("Error 1452: Cannot add or update a child row: a foreign key constraint fails"), "foreign_key", nil},
+		"gorm duplicate": {gorm.ErrDuplicatedKey, "unique", nil},
+		"gorm fk":        {gorm.ErrForeignKeyViolated, "foreign_key", nil},
+		"gorm check":     {gorm.ErrCheckConstraintViolated, "check", nil},
+		"other":          {errors.New("connection refused"), "", nil},
+	} {
+		t.Run(name, func(t *testing.T) {
+			code, columns := classifyDBError(tc.err)
+			if code != tc.code || !reflect.DeepEqual(columns, tc.columns) {
+				t.Errorf("classifyDBError = %q %v, want %q %v", code, columns, tc.code, tc.columns)
+			}
+		})
+	}
+}
+
+func TestTranslateDBError(t *testing.T) {
+	db := dryRunDB(t)
+
+	for name, tc := range map[string]struct {
+		err  error
+		want []fwork_server_orm.FieldError // nil: err unchanged
+	}{
+		"column to json name": {
+			errors.New("UNIQUE constraint failed: test_validated_rows.email"),
+			[]fwork_server_orm.FieldError{{Field: "email", Code: "unique"}},
+		},
+		"unknown column": {
+			&testPgError{Code: "23502", ColumnName: "legacy_col"},
+			[]fwork_server_orm.FieldError{{Field: "legacy_col", Code: "not_null"}},
+		},
+		"no column": {
+			errors.New("FOREIGN KEY constraint failed"),
+			[]fwork_server_orm.FieldError{{Code: "foreign_key"}},
+		},
+		"not a constraint": {errors.New("connection refused"), nil},
+		"validation error": {(&fwork_server_orm.ValidationError{}).Add("name", "required", "is required"), nil},
+	} {
+		t.Run(name, func(t *testing.T) {
+			err := translateDBError[testValidatedRow](db, tc.err)
+
+			if tc.want == nil {
+				if err != tc.err {
+					t.Errorf("translateDBError = %v, want the error unchanged", err)
+				}
+				return
+			}
+
+			var verr *fwork_server_orm.ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("translateDBError = %v, want a *ValidationError", err)
+			}
+			if got := fieldCodes(verr.Errors); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("errors = %v, want %v", got, tc.want)
+			}
+			if !errors.Is(err, tc.err) {
+				t.Errorf("the driver error is not kept as the cause")
+			}
+		})
+	}
+
+	unique := translateDBError[testValidatedRow](db, errors.New("UNIQUE constraint failed: test_validated_rows.email"))
+	if kind := HttpError(unique).Kind; kind != fwork_server_orm.ErrorConflict {
+		t.Errorf("unique violation kind = %s, want %s", kind, fwork_server_orm.ErrorConflict)
+	}
+}
+
+func TestUpdateValidatesTheMergedRecord(t *testing.T) {
+	ctx := context.Background()
+	db := sqliteDB(t, &testValidatedRow{})
+
+	stored := testValidatedRow{ID: 1, Name: "Ana", Email: "ana@example.com", Role: "user", Code: "abc"}
+	if err := db.Create(&stored).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	opts := UpdateOptions[testValidatedRow]{Validator: TagValidator{}}
+
+	for name, tc := range map[string]struct {
+		payload testValidatedRow
+		want    []fwork_server_orm.FieldError
+	}{
+		// name / role / code vêm da linha gravada
+		"partial":       {testValidatedRow{Email: "bia@example.com"}, nil},
+		"partial error": {testValidatedRow{Role: "root"}, []fwork_server_orm.FieldError{{Field: "role", Code: "oneof"}}},
+		"field error":   {testValidatedRow{Name: "A"}, []fwork_server_orm.FieldError{{Field: "name", Code: "min"}}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			_, err := GormUpdate(ctx, tc.payload, 1, db, "id", opts)
+
+			if tc.want == nil {
+				if err != nil {
+					t.Errorf("GormUpdate = %v, want nil", err)
+				}
+				return
+			}
+
+			var verr *fwork_server_orm.ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("GormUpdate = %v, want a *ValidationError", err)
+			}
+			if got := fieldCodes(verr.Errors); !reflect.DeepEqual(got, tc.want) {
+				t.Errorf("errors = %v, want %v", got, tc.want)
+			}
+		})
+	}
+
+	var row testValidatedRow
+	db.First(&row, 1)
+	if row.Name != "Ana" || row.Role != "user" || row.Email != "bia@example.com" {
+		t.Errorf("row = %+v, want only the valid partial update applied", row)
+	}
+
+	// sem linha não há o que validar (nem o que gravar)
+	if _, err := GormUpdate(ctx, testValidatedRow{Role: "user"}, 99, db, "id", opts); err != nil {
+		t.Errorf("GormUpdate of a missing key = %v, want nil", err)
+	}
+
+	// violação de unique vira erro de campo
+	if err := db.Create(&testValidatedRow{ID: 2, Name: "Bia", Email: "other@example.com", Role: "user", Code: "xyz"}).Error; err != nil {
+		t.Fatal(err)
+	}
+	_, err := GormUpdate(ctx, testValidatedRow{Email: "other@example.com"}, 1, db, "id", opts)
+
+	var verr *fwork_server_orm.ValidationError
+	if !errors.As(err, &verr) || !reflect.DeepEqual(fieldCodes(verr.Errors), []fwork_server_orm.FieldError{{Field: "email", Code: "unique"}}) {
+		t.Errorf("duplicate email: err = %v, want a unique error on email", err)
+	}
+}
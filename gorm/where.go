@@ -0,0 +1,194 @@
+package fwork_server_gorm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+
+	fwork_server_orm "github.com/joabssilveira/GoQLite/core"
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+var ErrEmptyFilter = errors.New("refusing to run without a filter")
+
+type WhereOptions struct {
+	// DryRun only counts the rows that would be affected.
+	DryRun bool
+}
+
+// GormUpdateWhere applies changes (keyed by JSON name, field name or column)
+// to every row matched by filter AND additionalWhere. An empty filter is
+// refused, even if additionalWhere is not empty. The columns of
+// additionalWhere (the Scope of the handlers) cannot be changed, so rows
+// don't move out of it: 403.
+func GormUpdateWhere[T any](
+	ctx context.Context,
+	db *gorm.DB,
+	filter fwork_server_orm.Filter,
+	additionalWhere fwork_server_orm.Filter,
+	changes map[string]any,
+	opts ...WhereOptions,
+) (int64, error) {
+
+	db = db.WithContext(ctx)
+
+	scope, sch, err := whereScope[T](db, filter, additionalWhere)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(changes) == 0 {
+		return 0, badQuery("no changes to apply")
+	}
+
+	scoped := filterColumns(sch, additionalWhere)
+
+	columns := make(map[string]any, len(changes))
+	for name, value := range changes {
+		field := lookUpField(sch, name)
+		if field == nil || field.DBName == "" || !field.Updatable || field.PrimaryKey {
+			return 0, badQuery("field cannot be updated: %s", name)
+		}
+		if scoped[field.DBName] {
+			return 0, fwork_server_orm.NewError(fwork_server_orm.ErrorForbidden, "field is part of the resource scope: "+name, errOutOfScope)
+		}
+
+		typed, err := coerceFieldValue(field, value)
+		if err != nil {
+			return 0, badQuery("invalid value for %s: %w", name, err)
+		}
+
+		columns[field.DBName] = typed
+	}
+
+	if len(opts) > 0 && opts[0].DryRun {
+		return countScope[T](db, scope)
+	}
+
+	result := scope.apply(db.Model(new(T))).Updates(columns)
+	return result.RowsAffected, result.Error
+}
+
+// GormDeleteWhere deletes every row matched by filter AND additionalWhere.
+// Models with gorm.DeletedAt are soft deleted. An empty filter is refused.
+func GormDeleteWhere[T any](
+	ctx context.Context,
+	db *gorm.DB,
+	filter fwork_server_orm.Filter,
+	additionalWhere fwork_server_orm.Filter,
+	opts ...WhereOptions,
+) (int64, error) {
+
+	db = db.WithContext(ctx)
+
+	scope, _, err := whereScope[T](db, filter, additionalWhere)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(opts) > 0 && opts[0].DryRun {
+		return countScope[T](db, scope)
+	}
+
+	result := scope.apply(db).Delete(new(T))
+	return result.RowsAffected, result.Error
+}
+
+// filterColumns returns the columns of the model used by filter (relation
+// paths are columns of other tables).
+func filterColumns(s *schema.Schema, filter fwork_server_orm.Filter) map[string]bool {
+	columns := map[string]bool{}
+
+	var walk func(filter fwork_server_orm.Filter)
+	walk = func(filter fwork_server_orm.Filter) {
+		for name := range filter.Fields {
+			// caminho JSONB: a coluna do documento (relações não têm coluna)
+			name, _, _ = strings.Cut(name, ".")
+			if field := lookUpField(s, name); field != nil && field.DBName != "" {
+				columns[field.DBName] = true
+			}
+		}
+		for _, sub := range filter.And {
+			walk(sub)
+		}
+		for _, sub := range filter.Or {
+			walk(sub)
+		}
+		if filter.Not != nil {
+			walk(*filter.Not)
+		}
+	}
+	walk(filter)
+
+	return columns
+}
+
+// whereScope builds a "pk IN (SELECT pk ... WHERE filter)" condition, so
+// relation filters (joins) also work for UPDATE / DELETE.
+func whereScope[T any](
+	db *gorm.DB,
+	filter fwork_server_orm.Filter,
+	additionalWhere fwork_server_orm.Filter,
+) (*whereSubquery, *schema.Schema, error) {
+
+	if filter.IsEmpty() {
+		return nil, nil, ErrEmptyFilter
+	}
+
+	builder := NewGormQueryBuilder(db.Model(new(T)))
+	if builder.Schema == nil || builder.Schema.PrioritizedPrimaryField == nil {
+		return nil, nil, gorm.ErrPrimaryKeyRequired
+	}
+
+	pk := quoteIdent(builder.Schema.PrioritizedPrimaryField.DBName)
+
+	builder = ApplyQuery(builder, fwork_server_orm.QueryPayload{
+		Where: fwork_server_orm.MergeWhereWithAnd(filter, additionalWhere),
+	})
+	// um subquery com erro perderia o WHERE
+	if builder.Db.Error != nil {
+		return nil, nil, builder.Db.Error
+	}
+
+	sub := builder.Db.Select(quoteTable(builder.Schema.Table) + "." + pk)
+
+	return &whereSubquery{Column: pk, Query: sub}, builder.Schema, nil
+}
+
+type whereSubquery struct {
+	Column string
+	Query  *gorm.DB
+}
+
+func (s *whereSubquery) apply(db *gorm.DB) *gorm.DB {
+	return db.Where(s.Column+" IN (?)", s.Query)
+}
+
+func countScope[T any](db *gorm.DB, scope *whereSubquery) (int64, error) {
+	var total int64
+	err := scope.apply(db.Model(new(T))).Count(&total).Error
+	return total, err
+}
+
+// coerceFieldValue converts a decoded JSON value to the Go type of the field,
+// so custom types (JSONB[T], time.Time, enums...) reach the driver correctly.
+func coerceFieldValue(field *schema.Field, value any) (any, error) {
+	if value == nil {
+		return nil, nil
+	}
+
+	raw, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	typed := reflect.New(field.FieldType)
+	if err := json.Unmarshal(raw, typed.Interface()); err != nil {
+		return nil, err
+	}
+
+	return typed.Elem().Interface(), nil
+}
@@ -0,0 +1,97 @@
+package fwork_server_gorm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+
+	fwork_server_orm "github.com/joabssilveira/GoQLite/core"
+)
+
+func TestWhereRefusesEmptyFilters(t *testing.T) {
+	ctx := context.Background()
+	res := tenantResource(t)
+
+	// o Scope sozinho não conta como filtro
+	scope := mustFilter(t, `{"tenant_id": 1}`)
+
+	for name, filter := range map[string]fwork_server_orm.Filter{
+		"empty":          {},
+		"empty and":      mustFilter(t, `{"$and": [{}]}`),
+		"empty or / not": mustFilter(t, `{"$or": [{}, {"$not": {}}]}`),
+		"no operator":    {Fields: map[string]fwork_server_orm.FieldExpr{"name": {}}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			if _, err := GormUpdateWhere[testTenantRow](ctx, res.Db, filter, scope, map[string]any{"name": "x"}); !errors.Is(err, ErrEmptyFilter) {
+				t.Errorf("GormUpdateWhere err = %v, want ErrEmptyFilter", err)
+			}
+			if _, err := GormDeleteWhere[testTenantRow](ctx, res.Db, filter, scope); !errors.Is(err, ErrEmptyFilter) {
+				t.Errorf("GormDeleteWhere err = %v, want ErrEmptyFilter", err)
+			}
+		})
+	}
+
+	for name, handler := range map[string]http.HandlerFunc{
+		"update": res.UpdateWhereHandler(),
+		"delete": res.DeleteWhereHandler(),
+	} {
+		for _, body := range []string{`{"set": {"name": "x"}}`, `{"where": {}, "set": {"name": "x"}}`} {
+			if w := serve(handler, "POST", "/rows/where", body, "X-Tenant", "1"); w.Code != http.StatusBadRequest {
+				t.Errorf("%s %s: status = %d, want 400", name, body, w.Code)
+			}
+		}
+	}
+
+	var count int64
+	res.Db.Model(new(testTenantRow)).Where("name IN ?", []string{"one", "two"}).Count(&count)
+	if count != 2 {
+		t.Errorf("%d rows untouched, want 2", count)
+	}
+}
+
+func TestWhereDryRunCounts(t *testing.T) {
+	res := tenantResource(t)
+	if err := res.Db.Create(&[]testTenantRow{{ID: 3, TenantID: 1, Name: "three"}, {ID: 4, TenantID: 1, Name: "four"}}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	for name, tc := range map[string]struct {
+		handler http.HandlerFunc
+		target  string
+		body    string
+		want    int64
+	}{
+		// o Scope (tenant 1) também limita a contagem
+		"update":       {res.UpdateWhereHandler(), "/rows/where", `{"where": {"id": {"$gte": 1}}, "set": {"name": "x"}, "dryRun": true}`, 3},
+		"update query": {res.UpdateWhereHandler(), "/rows/where?dryRun=true", `{"where": {"name": "three"}, "set": {"name": "x"}}`, 1},
+		"delete":       {res.DeleteWhereHandler(), "/rows/where", `{"where": {"id": {"$in": [1, 2, 4]}}, "dryRun": true}`, 2},
+		"delete none":  {res.DeleteWhereHandler(), "/rows/where?dryRun=true", `{"where": {"name": "two"}}`, 0},
+	} {
+		t.Run(name, func(t *testing.T) {
+			w := serve(tc.handler, "POST", tc.target, tc.body, "X-Tenant", "1")
+			if w.Code != http.StatusOK {
+				t.Fatalf("status = %d: %s", w.Code, w.Body)
+			}
+
+			var result fwork_server_orm.WriteResult
+			if err := json.Unmarshal(w.Body.Bytes(), &result); err != nil {
+				t.Fatal(err)
+			}
+			if !result.DryRun || result.Affected != tc.want {
+				t.Errorf("result = %+v, want a dry run of %d rows", result, tc.want)
+			}
+		})
+	}
+
+	var rows []testTenantRow
+	res.Db.Order("id").Find(&rows)
+	names := make([]string, len(rows))
+	for i, row := range rows {
+		names[i] = row.Name
+	}
+	if len(rows) != 4 || names[0] != "one" || names[2] != "three" {
+		t.Errorf("rows after the dry runs = %v, want them untouched", names)
+	}
+}
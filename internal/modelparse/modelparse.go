@@ -0,0 +1,382 @@
+// Package modelparse reads the model structs of a Go package from its
+// source (go/ast, no type checking), for the code generators of cmd/.
+package modelparse
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm/schema"
+)
+
+type FieldKind int
+
+const (
+	// Scalar is a column (numbers, strings, time.Time, custom types...).
+	Scalar FieldKind = iota
+	// Relation points to another struct of the package (GORM relation).
+	Relation
+	// JSON is a document column: JSONB[T], type:json / jsonb, serializer:json,
+	// maps. Target is set when the document is a struct of the package.
+	JSON
+	// Object is a struct stored in the columns of the model (gorm:"embedded")
+	// and nested in the JSON.
+	Object
+)
+
+type Package struct {
+	Name    string
+	Structs map[string]*Struct
+	Types   map[string]string // outros tipos nomeados do pacote: nome -> tipo base
+	Order   []string          // structs na ordem do código
+}
+
+type Struct struct {
+	Name   string
+	Doc    string
+	Fields []*Field
+}
+
+type Field struct {
+	GoName   string
+	JSONName string // "" when ignored by encoding/json
+	Column   bool   // false for gorm:"-"
+	Kind     FieldKind
+
+	// Type is the Go type as written ("*time.Time", "JSONB[Meta]").
+	Type string
+	// Elem is Type without pointer / slice / JSONB wrappers ("time.Time",
+	// "Meta").
+	Elem      string
+	Pointer   bool
+	Many      bool // slice / array
+	OmitEmpty bool
+
+	// Target is the struct of Relation / Object fields and of JSON fields
+	// holding a struct of the package ("" otherwise).
+	Target string
+}
+
+// Load parses the non-test files of the package in dir. Files named in skip
+// (e.g. the generator output) are ignored.
+func Load(dir string, skip ...string) (*Package, error) {
+	fset := token.NewFileSet()
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	pkg := &Package{Structs: map[string]*Struct{}, Types: map[string]string{}}
+
+	var files []*ast.File
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		if contains(skip, name) {
+			continue
+		}
+
+		file, err := parser.ParseFile(fset, dir+string(os.PathSeparator)+name, nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+
+		if pkg.Name == "" {
+			pkg.Name = file.Name.Name
+		} else if pkg.Name != file.Name.Name {
+			continue // pacote main de ferramentas, etc.
+		}
+		files = append(files, file)
+	}
+
+	if pkg.Name == "" {
+		return nil, fmt.Errorf("no Go files in %s", dir)
+	}
+
+	// primeiro os nomes, depois os campos (referências em qualquer ordem)
+	specs := map[string]*ast.StructType{}
+	docs := map[string]string{}
+
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+
+			for _, spec := range gen.Specs {
+				ts := spec.(*ast.TypeSpec)
+				if st, ok := ts.Type.(*ast.StructType); ok && ts.TypeParams == nil {
+					specs[ts.Name.Name] = st
+					pkg.Order = append(pkg.Order, ts.Name.Name)
+
+					doc := ts.Doc
+					if doc == nil {
+						doc = gen.Doc
+					}
+					docs[ts.Name.Name] = strings.TrimSpace(doc.Text())
+					continue
+				}
+				pkg.Types[ts.Name.Name] = types.ExprString(ts.Type)
+			}
+		}
+	}
+
+	for _, name := range pkg.Order {
+		pkg.Structs[name] = &Struct{Name: name, Doc: docs[name]}
+	}
+
+	for _, name := range pkg.Order {
+		pkg.Structs[name].Fields = pkg.fields(specs[name], specs, map[string]bool{name: true})
+	}
+
+	return pkg, nil
+}
+
+// fields flattens the embedded structs, like encoding/json and GORM do.
+func (p *Package) fields(st *ast.StructType, specs map[string]*ast.StructType, seen map[string]bool) []*Field {
+	var fields []*Field
+
+	for _, f := range st.Fields.List {
+		tag := reflect.StructTag("")
+		if f.Tag != nil {
+			tag = reflect.StructTag(strings.Trim(f.Tag.Value, "`"))
+		}
+
+		// embutido (anônimo)
+		if len(f.Names) == 0 {
+			name := embeddedName(f.Type)
+			if _, named := tag.Lookup("json"); !named {
+				if spec, ok := specs[name]; ok && !seen[name] {
+					seen[name] = true
+					fields = append(fields, p.fields(spec, specs, seen)...)
+					delete(seen, name)
+					continue
+				}
+				if types.ExprString(f.Type) == "gorm.Model" {
+					fields = append(fields, gormModelFields()...)
+					continue
+				}
+			}
+
+			if !ast.IsExported(name) {
+				continue
+			}
+			fields = append(fields, p.field(name, f.Type, tag))
+			continue
+		}
+
+		for _, ident := range f.Names {
+			if !ident.IsExported() {
+				continue
+			}
+			fields = append(fields, p.field(ident.Name, f.Type, tag))
+		}
+	}
+
+	return fields
+}
+
+func (p *Package) field(name string, expr ast.Expr, tag reflect.StructTag) *Field {
+	field := &Field{
+		GoName:   name,
+		JSONName: name,
+		Column:   true,
+		Type:     types.ExprString(expr),
+	}
+
+	if json, ok := tag.Lookup("json"); ok {
+		parts := strings.Split(json, ",")
+		switch parts[0] {
+		case "-":
+			field.JSONName = ""
+			if len(parts) > 1 {
+				field.JSONName = "-" // json:"-,"
+			}
+		case "":
+		default:
+			field.JSONName = parts[0]
+		}
+		field.OmitEmpty = contains(parts[1:], "omitempty")
+	}
+
+	settings := schema.ParseTagSetting(tag.Get("gorm"), ";")
+	if _, ok := settings["-"]; ok {
+		field.Column = false
+	}
+
+	// desembrulha *, [] e JSONB[...]
+	elem := expr
+	jsonb := false
+unwrap:
+	for {
+		switch e := elem.(type) {
+		case *ast.StarExpr:
+			field.Pointer = true
+			elem = e.X
+		case *ast.ArrayType:
+			if types.ExprString(e.Elt) == "byte" {
+				break unwrap
+			}
+			field.Many = true
+			elem = e.Elt
+		case *ast.IndexExpr:
+			if embeddedName(e.X) != "JSONB" {
+				break unwrap
+			}
+			jsonb = true
+			elem = e.Index
+		default:
+			break unwrap
+		}
+	}
+	field.Elem = types.ExprString(elem)
+
+	dataType := strings.ToLower(settings["TYPE"])
+	_, serializer := settings["SERIALIZER"]
+	_, embedded := settings["EMBEDDED"]
+
+	_, local := p.Structs[field.Elem]
+
+	switch {
+	case jsonb || strings.HasPrefix(dataType, "json") || serializer:
+		field.Kind = JSON
+	case embedded && local:
+		field.Kind = Object
+	case local:
+		field.Kind = Relation
+	case isMap(elem):
+		field.Kind = JSON
+	default:
+		field.Kind = Scalar
+	}
+
+	if field.Kind != Scalar && local {
+		field.Target = field.Elem
+	}
+
+	return field
+}
+
+func gormModelFields() []*Field {
+	return []*Field{
+		{GoName: "ID", JSONName: "ID", Column: true, Type: "uint", Elem: "uint"},
+		{GoName: "CreatedAt", JSONName: "CreatedAt", Column: true, Type: "time.Time", Elem: "time.Time"},
+		{GoName: "UpdatedAt", JSONName: "UpdatedAt", Column: true, Type: "time.Time", Elem: "time.Time"},
+		{GoName: "DeletedAt", JSONName: "DeletedAt", Column: true, Type: "gorm.DeletedAt", Elem: "gorm.DeletedAt", Pointer: true},
+	}
+}
+
+func embeddedName(expr ast.Expr) string {
+	switch e := expr.(type) {
+	case *ast.Ident:
+		return e.Name
+	case *ast.StarExpr:
+		return embeddedName(e.X)
+	case *ast.SelectorExpr:
+		return e.Sel.Name
+	case *ast.IndexExpr:
+		return embeddedName(e.X)
+	}
+	return ""
+}
+
+func isMap(expr ast.Expr) bool {
+	_, ok := expr.(*ast.MapType)
+	return ok
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// Names returns the names of the structs: the given ones (in order, checked)
+// or every exported struct of the package.
+func (p *Package) Names(only []string) ([]string, error) {
+	if len(only) == 0 {
+		var names []string
+		for _, name := range p.Order {
+			if ast.IsExported(name) {
+				names = append(names, name)
+			}
+		}
+		return names, nil
+	}
+
+	for _, name := range only {
+		if _, ok := p.Structs[name]; !ok {
+			return nil, fmt.Errorf("struct %s not found in package %s", name, p.Name)
+		}
+	}
+	return only, nil
+}
+
+// RelationPath is the name of a relation in filter / nested paths: the field
+// name in snake case, as long as it converts back (core SnakeToCamel).
+func RelationPath(goName string) string {
+	snake := schema.NamingStrategy{}.ColumnName("", goName)
+
+	// core.SnakeToCamel (sem importar o core: o gerador roda nele)
+	parts := strings.Split(snake, "_")
+	for i := range parts {
+		if len(parts[i]) > 0 {
+			parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+		}
+	}
+
+	if strings.Join(parts, "") == goName {
+		return snake
+	}
+	return goName
+}
+
+// SortedStructs returns the structs of names followed by the ones reachable
+// from them through relations, objects and JSON documents (sorted by name).
+func (p *Package) SortedStructs(names []string) []*Struct {
+	seen := map[string]bool{}
+
+	var roots []*Struct
+	for _, name := range names {
+		if s, ok := p.Structs[name]; ok && !seen[name] {
+			seen[name] = true
+			roots = append(roots, s)
+		}
+	}
+
+	var extra []string
+	var visit func(s *Struct)
+	visit = func(s *Struct) {
+		for _, f := range s.Fields {
+			if f.Target != "" && !seen[f.Target] {
+				seen[f.Target] = true
+				extra = append(extra, f.Target)
+				visit(p.Structs[f.Target])
+			}
+		}
+	}
+	for _, s := range roots {
+		visit(s)
+	}
+
+	sort.Strings(extra)
+	result := roots
+	for _, name := range extra {
+		result = append(result, p.Structs[name])
+	}
+	return result
+}